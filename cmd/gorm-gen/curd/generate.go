@@ -43,6 +43,17 @@ func main() {
 		model.Dataset{},
 		model.AccountDataset{},
 		model.UserDataset{},
+		model.SpaceUsage{},
+		model.TrashItem{},
+		model.DatasetVersion{},
+		model.DatasetVersionFile{},
+		model.DatasetManifest{},
+		model.DatasetChecksum{},
+		model.ShareLink{},
+		model.APIKey{},
+		model.RevokedToken{},
+		model.FileJob{},
+		model.FileIndex{},
 	)
 
 	// 执行并生成代码
@@ -3,10 +3,12 @@ package main
 
 import (
 	"fmt"
+	"time"
 
 	"webdav/dao/model"
 
 	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/datatypes"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -58,6 +60,287 @@ func main() {
 				return tx.Migrator().DropTable("dataset", "userdataset", "queuedataset")
 			},
 		},
+		{
+			// add storage quota to `users` and `accounts`, create `space_usages` table
+			ID: "202506031530",
+			Migrate: func(tx *gorm.DB) error {
+				type User struct {
+					StorageQuota int64 `gorm:"type:bigint;default:-1;comment:用户空间的存储配额 (字节)"`
+				}
+				type Account struct {
+					StorageQuota int64 `gorm:"type:bigint;default:-1;comment:账户空间的存储配额 (字节)"`
+				}
+				type SpaceUsage struct {
+					gorm.Model
+					Space string `gorm:"uniqueIndex;type:varchar(512);not null;comment:空间实际路径"`
+					Bytes int64  `gorm:"type:bigint;not null;default:0;comment:已用字节数"`
+					Files int64  `gorm:"type:bigint;not null;default:0;comment:文件数"`
+				}
+				if err := tx.Migrator().AddColumn(&User{}, "StorageQuota"); err != nil {
+					return err
+				}
+				if err := tx.Migrator().AddColumn(&Account{}, "StorageQuota"); err != nil {
+					return err
+				}
+				return tx.Migrator().CreateTable(&SpaceUsage{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				type User struct{}
+				type Account struct{}
+				if err := tx.Migrator().DropColumn(&User{}, "storage_quota"); err != nil {
+					return err
+				}
+				if err := tx.Migrator().DropColumn(&Account{}, "storage_quota"); err != nil {
+					return err
+				}
+				return tx.Migrator().DropTable("space_usages")
+			},
+		},
+		{
+			// create `trash_items` table
+			ID: "202506101020",
+			Migrate: func(tx *gorm.DB) error {
+				type TrashItem struct {
+					gorm.Model
+					Space     string `gorm:"index;type:varchar(512);not null;comment:所在空间实际路径"`
+					Path      string `gorm:"type:varchar(1024);not null;comment:原虚拟路径"`
+					RealPath  string `gorm:"type:varchar(1024);not null;comment:原实际路径"`
+					TrashPath string `gorm:"type:varchar(1024);not null;comment:回收站中的实际路径"`
+					IsDir     bool   `gorm:"not null;default:false;comment:是否为目录"`
+					Size      int64  `gorm:"type:bigint;not null;default:0;comment:字节数"`
+					UserID    uint   `gorm:"index;comment:删除者"`
+				}
+				return tx.Migrator().CreateTable(&TrashItem{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("trash_items")
+			},
+		},
+		{
+			// create `dataset_versions` and `dataset_version_files` table
+			ID: "202506171410",
+			Migrate: func(tx *gorm.DB) error {
+				type DatasetVersion struct {
+					gorm.Model
+					DatasetID uint   `gorm:"uniqueIndex:idx_dataset_version;not null;comment:数据集ID"`
+					Version   uint   `gorm:"uniqueIndex:idx_dataset_version;not null;comment:版本号"`
+					Describe  string `gorm:"type:text;comment:版本描述"`
+					Path      string `gorm:"type:varchar(512);not null;comment:快照实际路径"`
+					Status    string `gorm:"type:varchar(32);not null;comment:快照状态 (creating, ready, failed)"`
+					Message   string `gorm:"type:text;comment:快照失败原因"`
+					Files     int64  `gorm:"type:bigint;not null;default:0;comment:文件数"`
+					Bytes     int64  `gorm:"type:bigint;not null;default:0;comment:字节数"`
+					UserID    uint   `gorm:"comment:创建者"`
+				}
+				type DatasetVersionFile struct {
+					ID        uint   `gorm:"primaryKey"`
+					VersionID uint   `gorm:"index;not null;comment:快照ID"`
+					Path      string `gorm:"type:varchar(1024);not null;comment:相对路径"`
+					Size      int64  `gorm:"type:bigint;not null;comment:字节数"`
+					SHA256    string `gorm:"type:char(64);not null;comment:SHA-256"`
+				}
+				return tx.Migrator().CreateTable(&DatasetVersion{}, &DatasetVersionFile{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("dataset_versions", "dataset_version_files")
+			},
+		},
+		{
+			// create `dataset_manifests` and `dataset_checksums` table
+			ID: "202506241630",
+			Migrate: func(tx *gorm.DB) error {
+				type VerifyReport struct {
+					Missing   []string `json:"missing"`
+					Extra     []string `json:"extra"`
+					Corrupted []string `json:"corrupted"`
+				}
+				type DatasetManifest struct {
+					gorm.Model
+					DatasetID    uint                             `gorm:"uniqueIndex;not null;comment:数据集ID"`
+					Status       string                           `gorm:"type:varchar(32);not null;comment:清单生成状态"`
+					Message      string                           `gorm:"type:text;comment:清单生成失败原因"`
+					Files        int64                            `gorm:"type:bigint;not null;default:0;comment:文件数"`
+					Bytes        int64                            `gorm:"type:bigint;not null;default:0;comment:字节数"`
+					HashedAt     *time.Time                       `gorm:"comment:清单生成时间"`
+					VerifyStatus string                           `gorm:"type:varchar(32);comment:校验状态，为空表示从未校验"`
+					VerifyError  string                           `gorm:"type:text;comment:校验失败原因"`
+					VerifiedAt   *time.Time                       `gorm:"comment:校验完成时间"`
+					Report       datatypes.JSONType[VerifyReport] `gorm:"comment:校验结果"`
+				}
+				type DatasetChecksum struct {
+					ID        uint   `gorm:"primaryKey"`
+					DatasetID uint   `gorm:"index;not null;comment:数据集ID"`
+					Path      string `gorm:"type:varchar(1024);not null;comment:相对路径"`
+					Size      int64  `gorm:"type:bigint;not null;comment:字节数"`
+					SHA256    string `gorm:"type:char(64);not null;comment:SHA-256"`
+				}
+				return tx.Migrator().CreateTable(&DatasetManifest{}, &DatasetChecksum{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("dataset_manifests", "dataset_checksums")
+			},
+		},
+		{
+			// create `share_links` table
+			ID: "202507011050",
+			Migrate: func(tx *gorm.DB) error {
+				type ShareLink struct {
+					gorm.Model
+					Token        string     `gorm:"type:varchar(64);uniqueIndex;not null;comment:链接令牌"`
+					UserID       uint       `gorm:"index;not null;comment:创建者"`
+					Path         string     `gorm:"type:varchar(512);not null;comment:创建时的虚拟路径"`
+					RealPath     string     `gorm:"type:varchar(512);not null;comment:分享的实际路径"`
+					IsDir        bool       `gorm:"not null;comment:是否为目录"`
+					Mode         string     `gorm:"type:varchar(32);not null;comment:分享模式 (readonly, uploadonly)"`
+					PasswordHash string     `gorm:"type:varchar(128);comment:访问密码的 bcrypt 哈希，为空表示无需密码"`
+					ExpiresAt    *time.Time `gorm:"comment:过期时间，为空表示永不过期"`
+					MaxDownloads int64      `gorm:"type:bigint;not null;default:0;comment:最大下载次数，0 表示不限制"`
+					Downloads    int64      `gorm:"type:bigint;not null;default:0;comment:已下载次数"`
+				}
+				return tx.Migrator().CreateTable(&ShareLink{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("share_links")
+			},
+		},
+		{
+			// create `api_keys` table
+			ID: "202507081420",
+			Migrate: func(tx *gorm.DB) error {
+				type APIKey struct {
+					gorm.Model
+					UserID     uint                         `gorm:"index;not null;comment:所属用户"`
+					AccountID  uint                         `gorm:"not null;default:0;comment:创建时所在的账户，决定 account/ 指向的空间"`
+					Name       string                       `gorm:"type:varchar(128);not null;comment:令牌名称"`
+					Prefix     string                       `gorm:"type:varchar(16);not null;comment:令牌开头的若干字符，用于辨认"`
+					KeyHash    string                       `gorm:"type:char(64);uniqueIndex;not null;comment:令牌的 SHA-256"`
+					Scope      string                       `gorm:"type:varchar(8);not null;comment:权限范围 (ro, rw)"`
+					Roots      datatypes.JSONType[[]string] `gorm:"comment:允许访问的虚拟路径，为空且未指定数据集时不限制"`
+					DatasetIDs datatypes.JSONType[[]uint]   `gorm:"comment:允许访问的数据集"`
+					ExpiresAt  *time.Time                   `gorm:"comment:过期时间，为空表示永不过期"`
+					LastUsedAt *time.Time                   `gorm:"comment:最近一次使用的时间"`
+				}
+				return tx.Migrator().CreateTable(&APIKey{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("api_keys")
+			},
+		},
+		{
+			// create `revoked_tokens` table
+			ID: "202507151020",
+			Migrate: func(tx *gorm.DB) error {
+				type RevokedToken struct {
+					ID        uint      `gorm:"primarykey"`
+					CreatedAt time.Time `gorm:"comment:撤销时间"`
+					JTI       string    `gorm:"type:varchar(64);index;not null;default:'';comment:令牌 ID"`
+					UserID    uint      `gorm:"index;not null;comment:令牌所属用户"`
+					ExpiresAt time.Time `gorm:"index;not null;comment:令牌最晚的过期时间，之后记录可以清理"`
+				}
+				return tx.Migrator().CreateTable(&RevokedToken{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("revoked_tokens")
+			},
+		},
+		{
+			// create `file_jobs` table
+			ID: "202507221500",
+			Migrate: func(tx *gorm.DB) error {
+				type FileJobParams struct {
+					Src       string   `json:"src,omitempty"`
+					Dst       string   `json:"dst,omitempty"`
+					RealSrc   string   `json:"realSrc,omitempty"`
+					RealDst   string   `json:"realDst,omitempty"`
+					DatasetID uint     `json:"datasetID,omitempty"`
+					Format    string   `json:"format,omitempty"`
+					Include   []string `json:"include,omitempty"`
+					Exclude   []string `json:"exclude,omitempty"`
+					Name      string   `json:"name,omitempty"`
+				}
+				type FileJob struct {
+					gorm.Model
+					UserID     uint                              `gorm:"index;not null;comment:创建任务的用户，系统任务为 0"`
+					Type       string                            `gorm:"type:varchar(32);not null;comment:任务类型"`
+					Status     string                            `gorm:"type:varchar(16);index;not null;comment:任务状态 (pending, running, succeeded, failed, canceled)"`
+					Params     datatypes.JSONType[FileJobParams] `gorm:"comment:任务参数"`
+					Result     string                            `gorm:"type:varchar(1024);comment:任务结果，例如打包文件的实际路径"`
+					Message    string                            `gorm:"type:text;comment:失败原因"`
+					Attempts   int                               `gorm:"not null;default:0;comment:已执行的次数"`
+					RunAfter   time.Time                         `gorm:"index;comment:重试前等待到此时间"`
+					TotalBytes int64                             `gorm:"comment:需要处理的字节数"`
+					TotalFiles int64                             `gorm:"comment:需要处理的文件数"`
+					DoneBytes  int64                             `gorm:"comment:已处理的字节数"`
+					DoneFiles  int64                             `gorm:"comment:已处理的文件数"`
+					StartedAt  *time.Time                        `gorm:"comment:最近一次开始执行的时间"`
+					FinishedAt *time.Time                        `gorm:"comment:结束时间"`
+				}
+				return tx.Migrator().CreateTable(&FileJob{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("file_jobs")
+			},
+		},
+		{
+			// create `file_indices` table
+			ID: "202507291000",
+			Migrate: func(tx *gorm.DB) error {
+				type FileIndex struct {
+					ID        uint      `gorm:"primarykey"`
+					UpdatedAt time.Time `gorm:"comment:索引更新时间"`
+					Space     string    `gorm:"type:varchar(512);index;not null;comment:所在空间的实际路径"`
+					Path      string    `gorm:"type:text;uniqueIndex;not null;comment:文件的实际路径"`
+					Name      string    `gorm:"type:varchar(1024);index;not null;comment:文件名"`
+					IsDir     bool      `gorm:"not null;default:false;comment:是否为目录"`
+					Size      int64     `gorm:"not null;default:0;comment:文件大小"`
+					ModTime   time.Time `gorm:"index;comment:修改时间"`
+				}
+				return tx.Migrator().CreateTable(&FileIndex{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("file_indices")
+			},
+		},
+		{
+			// add `account_id` to `share_links`
+			ID: "202508051000",
+			Migrate: func(tx *gorm.DB) error {
+				type ShareLink struct {
+					AccountID uint `gorm:"not null;default:0;comment:创建时所在的账户，决定 account/ 指向的空间"`
+				}
+				return tx.Migrator().AddColumn(&ShareLink{}, "AccountID")
+			},
+			Rollback: func(tx *gorm.DB) error {
+				type ShareLink struct{}
+				return tx.Migrator().DropColumn(&ShareLink{}, "account_id")
+			},
+		},
+		{
+			// add `owner`, `heartbeat_at` and `cancel_requested` to `file_jobs`
+			ID: "202508121000",
+			Migrate: func(tx *gorm.DB) error {
+				type FileJob struct {
+					Owner           string     `gorm:"type:varchar(128);comment:执行任务的实例"`
+					HeartbeatAt     *time.Time `gorm:"index;comment:执行中的任务最近一次报告进度的时间，长时间未更新说明实例已经退出"`
+					CancelRequested bool       `gorm:"not null;default:false;comment:已请求取消，执行任务的实例轮询到后中断任务"`
+				}
+				for _, column := range []string{"Owner", "HeartbeatAt", "CancelRequested"} {
+					if err := tx.Migrator().AddColumn(&FileJob{}, column); err != nil {
+						return err
+					}
+				}
+				return tx.Migrator().CreateIndex(&FileJob{}, "HeartbeatAt")
+			},
+			Rollback: func(tx *gorm.DB) error {
+				type FileJob struct{}
+				for _, column := range []string{"owner", "heartbeat_at", "cancel_requested"} {
+					if err := tx.Migrator().DropColumn(&FileJob{}, column); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
 	})
 
 	m.InitSchema(func(tx *gorm.DB) error {
@@ -68,6 +351,17 @@ func main() {
 			&model.Dataset{},
 			&model.AccountDataset{},
 			&model.UserDataset{},
+			&model.SpaceUsage{},
+			&model.TrashItem{},
+			&model.DatasetVersion{},
+			&model.DatasetVersionFile{},
+			&model.DatasetManifest{},
+			&model.DatasetChecksum{},
+			&model.ShareLink{},
+			&model.APIKey{},
+			&model.RevokedToken{},
+			&model.FileJob{},
+			&model.FileIndex{},
 		)
 		if err != nil {
 			return err
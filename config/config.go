@@ -27,6 +27,40 @@ type Config struct {
 		AccessTokenSecret  string `yaml:"accessTokenSecret"`
 		RefreshTokenSecret string `yaml:"refreshTokenSecret"`
 	} `yaml:"auth"`
+
+	Upload struct {
+		ExpireHours int   `yaml:"expireHours"` // 未完成的上传在暂存区保留的小时数
+		MaxSize     int64 `yaml:"maxSize"`     // 单个上传的最大字节数，0 表示不限制
+	} `yaml:"upload"`
+
+	Quota struct {
+		ScanIntervalMinutes int `yaml:"scanIntervalMinutes"` // 重新统计各空间用量的间隔
+	} `yaml:"quota"`
+
+	Trash struct {
+		RetentionDays int `yaml:"retentionDays"` // 回收站中的文件保留的天数
+	} `yaml:"trash"`
+
+	DatasetVersion struct {
+		Hardlink bool `yaml:"hardlink"` // 快照使用硬链接，不占用额外空间，但原文件被原地修改时快照也会随之改变
+	} `yaml:"datasetVersion"`
+
+	Share struct {
+		DefaultExpireHours int `yaml:"defaultExpireHours"` // 未指定过期时间的分享链接的有效小时数，0 表示永不过期
+	} `yaml:"share"`
+
+	Job struct {
+		Workers       int `yaml:"workers"`       // 同时执行的后台任务数
+		RetentionDays int `yaml:"retentionDays"` // 结束的任务及其结果保留的天数
+	} `yaml:"job"`
+
+	Index struct {
+		ScanIntervalMinutes int `yaml:"scanIntervalMinutes"` // 重新扫描各空间、更新文件索引的间隔
+	} `yaml:"index"`
+
+	Account struct {
+		ExpireGraceHours int `yaml:"expireGraceHours"` // 账户过期后空间保持只读的小时数，之后不可访问
+	} `yaml:"account"`
 }
 
 var (
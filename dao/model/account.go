@@ -36,6 +36,8 @@ type Account struct {
 	ExpiredAt *time.Time                     `gorm:"comment:账户过期时间"`
 	Quota     datatypes.JSONType[QueueQuota] `gorm:"comment:账户对应队列的资源配额"`
 
+	StorageQuota int64 `gorm:"type:bigint;default:-1;comment:账户空间的存储配额 (字节)"`
+
 	UserAccounts    []UserAccount
 	AccountDatasets []AccountDataset
 }
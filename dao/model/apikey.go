@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+type APIKeyScope string
+
+const (
+	APIKeyReadOnly  APIKeyScope = "ro"
+	APIKeyReadWrite APIKeyScope = "rw"
+)
+
+const APIKeyPrefix = "csk_"
+
+// APIKey 个人访问令牌，用于 davfs2、rclone 等无法刷新 JWT 的客户端，只保存令牌的哈希
+type APIKey struct {
+	gorm.Model
+	UserID     uint                         `gorm:"index;not null;comment:所属用户"`
+	AccountID  uint                         `gorm:"not null;default:0;comment:创建时所在的账户，决定 account/ 指向的空间"`
+	Name       string                       `gorm:"type:varchar(128);not null;comment:令牌名称"`
+	Prefix     string                       `gorm:"type:varchar(16);not null;comment:令牌开头的若干字符，用于辨认"`
+	KeyHash    string                       `gorm:"type:char(64);uniqueIndex;not null;comment:令牌的 SHA-256"`
+	Scope      APIKeyScope                  `gorm:"type:varchar(8);not null;comment:权限范围 (ro, rw)"`
+	Roots      datatypes.JSONType[[]string] `gorm:"comment:允许访问的虚拟路径，为空且未指定数据集时不限制"`
+	DatasetIDs datatypes.JSONType[[]uint]   `gorm:"comment:允许访问的数据集"`
+	ExpiresAt  *time.Time                   `gorm:"comment:过期时间，为空表示永不过期"`
+	LastUsedAt *time.Time                   `gorm:"comment:最近一次使用的时间"`
+}
@@ -84,8 +84,37 @@ const (
 	NotAllowed
 	ReadOnly
 	ReadWrite
+	AppendOnly // 可以读取和新建，不能覆盖、删除、移动已有的文件或修改其属性
 )
 
+// 空间访问模式对应的文件权限，未知的访问模式视为不允许访问
+func (m AccessMode) FilePermission() FilePermission {
+	switch m {
+	case AccessModeRO:
+		return ReadOnly
+	case AccessModeRW:
+		return ReadWrite
+	case AccessModeAO:
+		return AppendOnly
+	default:
+		return NotAllowed
+	}
+}
+
+func (p FilePermission) CanRead() bool {
+	return p == ReadOnly || p == ReadWrite || p == AppendOnly
+}
+
+// 能否新建文件和目录
+func (p FilePermission) CanCreate() bool {
+	return p == ReadWrite || p == AppendOnly
+}
+
+// 能否覆盖、删除、移动已有的文件
+func (p FilePermission) CanModify() bool {
+	return p == ReadWrite
+}
+
 type TokenResp struct {
 	Code int `json:"code"`
 	Data struct {
@@ -105,6 +134,7 @@ const (
 )
 
 const DefaultFolderPerm = 0755
+const DefaultFilePerm = 0644
 const RWXFolderPerm = 0777
 const UserPath = "user"
 const PublicPath = "public"
@@ -114,3 +144,7 @@ const AdminPublicPath = "admin-public"
 const AdminAccountPath = "admin-account"
 const ModelPrefix = "crater-model"
 const DatasetPrefix = "crater-dataset"
+const UploadPrefix = "crater-upload"
+const TrashDir = ".crater-trash"
+const DatasetVersionPrefix = "crater-dataset-version"
+const ArchivePrefix = "crater-archive"
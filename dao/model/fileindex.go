@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// FileIndex 各空间中文件的索引，后台定期扫描并增量更新，用于按文件名搜索
+type FileIndex struct {
+	ID        uint      `gorm:"primarykey"`
+	UpdatedAt time.Time `gorm:"comment:索引更新时间"`
+	Space     string    `gorm:"type:varchar(512);index;not null;comment:所在空间的实际路径"`
+	Path      string    `gorm:"type:text;uniqueIndex;not null;comment:文件的实际路径"`
+	Name      string    `gorm:"type:varchar(1024);index;not null;comment:文件名"`
+	IsDir     bool      `gorm:"not null;default:false;comment:是否为目录"`
+	Size      int64     `gorm:"not null;default:0;comment:文件大小"`
+	ModTime   time.Time `gorm:"index;comment:修改时间"`
+}
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// FileJobType 文件任务的类型，与集群作业 (JobStatus) 无关
+type FileJobType string
+
+const (
+	FileJobMove        FileJobType = "move"
+	FileJobDatasetMove FileJobType = "dataset-move"
+	FileJobCopy        FileJobType = "copy"
+	FileJobArchive     FileJobType = "archive"
+	FileJobHash        FileJobType = "hash"
+	FileJobVerify      FileJobType = "verify"
+	FileJobVersion     FileJobType = "version"
+)
+
+type FileJobStatus string
+
+const (
+	FileJobPending   FileJobStatus = "pending"
+	FileJobRunning   FileJobStatus = "running"
+	FileJobSucceeded FileJobStatus = "succeeded"
+	FileJobFailed    FileJobStatus = "failed"
+	FileJobCanceled  FileJobStatus = "canceled"
+)
+
+// FileJobParams 任务参数，各类任务只使用与自己相关的字段
+type FileJobParams struct {
+	Src       string   `json:"src,omitempty"` // 虚拟路径，用于展示
+	Dst       string   `json:"dst,omitempty"`
+	RealSrc   string   `json:"realSrc,omitempty"`
+	RealDst   string   `json:"realDst,omitempty"`
+	DatasetID uint     `json:"datasetID,omitempty"`
+	VersionID uint     `json:"versionID,omitempty"`
+	Format    string   `json:"format,omitempty"`
+	Include   []string `json:"include,omitempty"`
+	Exclude   []string `json:"exclude,omitempty"`
+	Name      string   `json:"name,omitempty"`
+}
+
+// FileJob 在后台执行的文件任务，例如移动、复制、打包和计算哈希
+type FileJob struct {
+	gorm.Model
+	UserID     uint                              `gorm:"index;not null;comment:创建任务的用户，系统任务为 0"`
+	Type       FileJobType                       `gorm:"type:varchar(32);not null;comment:任务类型"`
+	Status     FileJobStatus                     `gorm:"type:varchar(16);index;not null;comment:任务状态 (pending, running, succeeded, failed, canceled)"`
+	Params     datatypes.JSONType[FileJobParams] `gorm:"comment:任务参数"`
+	Result     string                            `gorm:"type:varchar(1024);comment:任务结果，例如打包文件的实际路径"`
+	Message    string                            `gorm:"type:text;comment:失败原因"`
+	Attempts   int                               `gorm:"not null;default:0;comment:已执行的次数"`
+	RunAfter   time.Time                         `gorm:"index;comment:重试前等待到此时间"`
+	TotalBytes int64                             `gorm:"comment:需要处理的字节数"`
+	TotalFiles int64                             `gorm:"comment:需要处理的文件数"`
+	DoneBytes  int64                             `gorm:"comment:已处理的字节数"`
+	DoneFiles  int64                             `gorm:"comment:已处理的文件数"`
+	StartedAt  *time.Time                        `gorm:"comment:最近一次开始执行的时间"`
+	FinishedAt *time.Time                        `gorm:"comment:结束时间"`
+
+	Owner           string     `gorm:"type:varchar(128);comment:执行任务的实例"`
+	HeartbeatAt     *time.Time `gorm:"index;comment:执行中的任务最近一次报告进度的时间，长时间未更新说明实例已经退出"`
+	CancelRequested bool       `gorm:"not null;default:false;comment:已请求取消，执行任务的实例轮询到后中断任务"`
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+type ManifestStatus string
+
+const (
+	ManifestPending   ManifestStatus = "pending"
+	ManifestRunning   ManifestStatus = "running"
+	ManifestSucceeded ManifestStatus = "succeeded"
+	ManifestFailed    ManifestStatus = "failed"
+)
+
+// VerifyReport 校验结果，路径均相对于数据集根目录
+type VerifyReport struct {
+	Missing   []string `json:"missing"`
+	Extra     []string `json:"extra"`
+	Corrupted []string `json:"corrupted"`
+}
+
+// DatasetManifest 数据集 SHA-256 清单的生成状态和最近一次校验结果
+type DatasetManifest struct {
+	gorm.Model
+	DatasetID    uint                             `gorm:"uniqueIndex;not null;comment:数据集ID"`
+	Status       ManifestStatus                   `gorm:"type:varchar(32);not null;comment:清单生成状态"`
+	Message      string                           `gorm:"type:text;comment:清单生成失败原因"`
+	Files        int64                            `gorm:"type:bigint;not null;default:0;comment:文件数"`
+	Bytes        int64                            `gorm:"type:bigint;not null;default:0;comment:字节数"`
+	HashedAt     *time.Time                       `gorm:"comment:清单生成时间"`
+	VerifyStatus ManifestStatus                   `gorm:"type:varchar(32);comment:校验状态，为空表示从未校验"`
+	VerifyError  string                           `gorm:"type:text;comment:校验失败原因"`
+	VerifiedAt   *time.Time                       `gorm:"comment:校验完成时间"`
+	Report       datatypes.JSONType[VerifyReport] `gorm:"comment:校验结果"`
+}
+
+// DatasetChecksum 数据集中单个文件的 SHA-256，行数与文件数相同，因此不使用软删除
+type DatasetChecksum struct {
+	ID        uint   `gorm:"primaryKey"`
+	DatasetID uint   `gorm:"index;not null;comment:数据集ID"`
+	Path      string `gorm:"type:varchar(1024);not null;comment:相对路径"`
+	Size      int64  `gorm:"type:bigint;not null;comment:字节数"`
+	SHA256    string `gorm:"type:char(64);not null;comment:SHA-256"`
+}
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// RevokedToken 被撤销的 JWT。JTI 为空时表示撤销该用户在 CreatedAt 之前签发的所有令牌
+type RevokedToken struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"comment:撤销时间"`
+	JTI       string    `gorm:"type:varchar(64);index;not null;default:'';comment:令牌 ID"`
+	UserID    uint      `gorm:"index;not null;comment:令牌所属用户"`
+	ExpiresAt time.Time `gorm:"index;not null;comment:令牌最晚的过期时间，之后记录可以清理"`
+}
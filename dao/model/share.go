@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ShareMode string
+
+const (
+	ShareReadOnly   ShareMode = "readonly"   // 只能列出和下载
+	ShareUploadOnly ShareMode = "uploadonly" // 只能向分享的目录上传新文件
+)
+
+// ShareLink 文件分享链接，持有令牌的人无需登录即可访问，删除即撤销
+type ShareLink struct {
+	gorm.Model
+	Token        string     `gorm:"type:varchar(64);uniqueIndex;not null;comment:链接令牌"`
+	UserID       uint       `gorm:"index;not null;comment:创建者"`
+	AccountID    uint       `gorm:"not null;default:0;comment:创建时所在的账户，决定 account/ 指向的空间"`
+	Path         string     `gorm:"type:varchar(512);not null;comment:创建时的虚拟路径"`
+	RealPath     string     `gorm:"type:varchar(512);not null;comment:分享的实际路径"`
+	IsDir        bool       `gorm:"not null;comment:是否为目录"`
+	Mode         ShareMode  `gorm:"type:varchar(32);not null;comment:分享模式 (readonly, uploadonly)"`
+	PasswordHash string     `gorm:"type:varchar(128);comment:访问密码的 bcrypt 哈希，为空表示无需密码"`
+	ExpiresAt    *time.Time `gorm:"comment:过期时间，为空表示永不过期"`
+	MaxDownloads int64      `gorm:"type:bigint;not null;default:0;comment:最大下载次数，0 表示不限制"`
+	Downloads    int64      `gorm:"type:bigint;not null;default:0;comment:已下载次数"`
+}
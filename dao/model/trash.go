@@ -0,0 +1,17 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+// TrashItem 记录回收站中的文件，文件本身被移动到所在空间的 TrashDir 目录下
+type TrashItem struct {
+	gorm.Model
+	Space     string `gorm:"index;type:varchar(512);not null;comment:所在空间实际路径"`
+	Path      string `gorm:"type:varchar(1024);not null;comment:原虚拟路径"`
+	RealPath  string `gorm:"type:varchar(1024);not null;comment:原实际路径"`
+	TrashPath string `gorm:"type:varchar(1024);not null;comment:回收站中的实际路径"`
+	IsDir     bool   `gorm:"not null;default:false;comment:是否为目录"`
+	Size      int64  `gorm:"type:bigint;not null;default:0;comment:字节数"`
+	UserID    uint   `gorm:"index;comment:删除者"`
+}
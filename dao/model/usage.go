@@ -0,0 +1,13 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+// SpaceUsage 记录每个空间的磁盘用量，后台定期扫描校正，经本服务的写操作增量更新
+type SpaceUsage struct {
+	gorm.Model
+	Space string `gorm:"uniqueIndex;type:varchar(512);not null;comment:空间实际路径"`
+	Bytes int64  `gorm:"type:bigint;not null;default:0;comment:已用字节数"`
+	Files int64  `gorm:"type:bigint;not null;default:0;comment:文件数"`
+}
@@ -6,8 +6,9 @@ import (
 )
 
 const (
-	InvalidUserID      = 0
-	ImageQuotaInfinity = -1
+	InvalidUserID        = 0
+	ImageQuotaInfinity   = -1
+	StorageQuotaInfinity = -1
 )
 
 // UserAttributeForScan is used for scan.
@@ -52,6 +53,8 @@ type User struct {
 	Space      string  `gorm:"uniqueIndex;type:varchar(256);not null;comment:用户空间绝对路径"`
 	ImageQuota int64   `gorm:"type:bigint;default:-1;comment:用户在镜像仓库的配额"`
 
+	StorageQuota int64 `gorm:"type:bigint;default:-1;comment:用户空间的存储配额 (字节)"`
+
 	Attributes   datatypes.JSONType[UserAttribute] `gorm:"comment:用户的额外属性 (昵称、邮箱、电话、头像等)"`
 	UserAccounts []UserAccount
 	UserDatasets []UserDataset
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+type VersionStatus string
+
+const (
+	VersionCreating VersionStatus = "creating"
+	VersionReady    VersionStatus = "ready"
+	VersionFailed   VersionStatus = "failed"
+)
+
+// DatasetVersion 数据集在某一时刻的只读快照
+type DatasetVersion struct {
+	gorm.Model
+	DatasetID uint          `gorm:"uniqueIndex:idx_dataset_version;not null;comment:数据集ID"`
+	Version   uint          `gorm:"uniqueIndex:idx_dataset_version;not null;comment:版本号"`
+	Describe  string        `gorm:"type:text;comment:版本描述"`
+	Path      string        `gorm:"type:varchar(512);not null;comment:快照实际路径"`
+	Status    VersionStatus `gorm:"type:varchar(32);not null;comment:快照状态 (creating, ready, failed)"`
+	Message   string        `gorm:"type:text;comment:快照失败原因"`
+	Files     int64         `gorm:"type:bigint;not null;default:0;comment:文件数"`
+	Bytes     int64         `gorm:"type:bigint;not null;default:0;comment:字节数"`
+	UserID    uint          `gorm:"comment:创建者"`
+}
+
+// DatasetVersionFile 快照的文件清单，行数与文件数相同，因此不使用软删除
+type DatasetVersionFile struct {
+	ID        uint   `gorm:"primaryKey"`
+	VersionID uint   `gorm:"index;not null;comment:快照ID"`
+	Path      string `gorm:"type:varchar(1024);not null;comment:相对路径"`
+	Size      int64  `gorm:"type:bigint;not null;comment:字节数"`
+	SHA256    string `gorm:"type:char(64);not null;comment:SHA-256"`
+}
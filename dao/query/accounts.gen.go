@@ -36,6 +36,7 @@ func newAccount(db *gorm.DB, opts ...gen.DOOption) account {
 	_account.Space = field.NewString(tableName, "space")
 	_account.ExpiredAt = field.NewTime(tableName, "expired_at")
 	_account.Quota = field.NewField(tableName, "quota")
+	_account.StorageQuota = field.NewInt64(tableName, "storage_quota")
 	_account.UserAccounts = accountHasManyUserAccounts{
 		db: db.Session(&gorm.Session{}),
 
@@ -66,6 +67,7 @@ type account struct {
 	Space        field.String
 	ExpiredAt    field.Time
 	Quota        field.Field
+	StorageQuota field.Int64
 	UserAccounts accountHasManyUserAccounts
 
 	AccountDatasets accountHasManyAccountDatasets
@@ -94,6 +96,7 @@ func (a *account) updateTableName(table string) *account {
 	a.Space = field.NewString(table, "space")
 	a.ExpiredAt = field.NewTime(table, "expired_at")
 	a.Quota = field.NewField(table, "quota")
+	a.StorageQuota = field.NewInt64(table, "storage_quota")
 
 	a.fillFieldMap()
 
@@ -118,7 +121,7 @@ func (a *account) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
 }
 
 func (a *account) fillFieldMap() {
-	a.fieldMap = make(map[string]field.Expr, 11)
+	a.fieldMap = make(map[string]field.Expr, 12)
 	a.fieldMap["id"] = a.ID
 	a.fieldMap["created_at"] = a.CreatedAt
 	a.fieldMap["updated_at"] = a.UpdatedAt
@@ -128,6 +131,7 @@ func (a *account) fillFieldMap() {
 	a.fieldMap["space"] = a.Space
 	a.fieldMap["expired_at"] = a.ExpiredAt
 	a.fieldMap["quota"] = a.Quota
+	a.fieldMap["storage_quota"] = a.StorageQuota
 
 }
 
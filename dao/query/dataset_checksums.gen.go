@@ -0,0 +1,408 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package query
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"gorm.io/gen"
+	"gorm.io/gen/field"
+
+	"gorm.io/plugin/dbresolver"
+
+	"webdav/dao/model"
+)
+
+func newDatasetChecksum(db *gorm.DB, opts ...gen.DOOption) datasetChecksum {
+	_datasetChecksum := datasetChecksum{}
+
+	_datasetChecksum.datasetChecksumDo.UseDB(db, opts...)
+	_datasetChecksum.datasetChecksumDo.UseModel(&model.DatasetChecksum{})
+
+	tableName := _datasetChecksum.datasetChecksumDo.TableName()
+	_datasetChecksum.ALL = field.NewAsterisk(tableName)
+	_datasetChecksum.ID = field.NewUint(tableName, "id")
+	_datasetChecksum.DatasetID = field.NewUint(tableName, "dataset_id")
+	_datasetChecksum.Path = field.NewString(tableName, "path")
+	_datasetChecksum.Size = field.NewInt64(tableName, "size")
+	_datasetChecksum.SHA256 = field.NewString(tableName, "sha256")
+
+	_datasetChecksum.fillFieldMap()
+
+	return _datasetChecksum
+}
+
+type datasetChecksum struct {
+	datasetChecksumDo datasetChecksumDo
+
+	ALL       field.Asterisk
+	ID        field.Uint
+	DatasetID field.Uint
+	Path      field.String
+	Size      field.Int64
+	SHA256    field.String
+
+	fieldMap map[string]field.Expr
+}
+
+func (d datasetChecksum) Table(newTableName string) *datasetChecksum {
+	d.datasetChecksumDo.UseTable(newTableName)
+	return d.updateTableName(newTableName)
+}
+
+func (d datasetChecksum) As(alias string) *datasetChecksum {
+	d.datasetChecksumDo.DO = *(d.datasetChecksumDo.As(alias).(*gen.DO))
+	return d.updateTableName(alias)
+}
+
+func (d *datasetChecksum) updateTableName(table string) *datasetChecksum {
+	d.ALL = field.NewAsterisk(table)
+	d.ID = field.NewUint(table, "id")
+	d.DatasetID = field.NewUint(table, "dataset_id")
+	d.Path = field.NewString(table, "path")
+	d.Size = field.NewInt64(table, "size")
+	d.SHA256 = field.NewString(table, "sha256")
+
+	d.fillFieldMap()
+
+	return d
+}
+
+func (d *datasetChecksum) WithContext(ctx context.Context) IDatasetChecksumDo {
+	return d.datasetChecksumDo.WithContext(ctx)
+}
+
+func (d datasetChecksum) TableName() string { return d.datasetChecksumDo.TableName() }
+
+func (d datasetChecksum) Alias() string { return d.datasetChecksumDo.Alias() }
+
+func (d datasetChecksum) Columns(cols ...field.Expr) gen.Columns {
+	return d.datasetChecksumDo.Columns(cols...)
+}
+
+func (d *datasetChecksum) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
+	_f, ok := d.fieldMap[fieldName]
+	if !ok || _f == nil {
+		return nil, false
+	}
+	_oe, ok := _f.(field.OrderExpr)
+	return _oe, ok
+}
+
+func (d *datasetChecksum) fillFieldMap() {
+	d.fieldMap = make(map[string]field.Expr, 5)
+	d.fieldMap["id"] = d.ID
+	d.fieldMap["dataset_id"] = d.DatasetID
+	d.fieldMap["path"] = d.Path
+	d.fieldMap["size"] = d.Size
+	d.fieldMap["sha256"] = d.SHA256
+}
+
+func (d datasetChecksum) clone(db *gorm.DB) datasetChecksum {
+	d.datasetChecksumDo.ReplaceConnPool(db.Statement.ConnPool)
+	return d
+}
+
+func (d datasetChecksum) replaceDB(db *gorm.DB) datasetChecksum {
+	d.datasetChecksumDo.ReplaceDB(db)
+	return d
+}
+
+type datasetChecksumDo struct{ gen.DO }
+
+type IDatasetChecksumDo interface {
+	gen.SubQuery
+	Debug() IDatasetChecksumDo
+	WithContext(ctx context.Context) IDatasetChecksumDo
+	WithResult(fc func(tx gen.Dao)) gen.ResultInfo
+	ReplaceDB(db *gorm.DB)
+	ReadDB() IDatasetChecksumDo
+	WriteDB() IDatasetChecksumDo
+	As(alias string) gen.Dao
+	Session(config *gorm.Session) IDatasetChecksumDo
+	Columns(cols ...field.Expr) gen.Columns
+	Clauses(conds ...clause.Expression) IDatasetChecksumDo
+	Not(conds ...gen.Condition) IDatasetChecksumDo
+	Or(conds ...gen.Condition) IDatasetChecksumDo
+	Select(conds ...field.Expr) IDatasetChecksumDo
+	Where(conds ...gen.Condition) IDatasetChecksumDo
+	Order(conds ...field.Expr) IDatasetChecksumDo
+	Distinct(cols ...field.Expr) IDatasetChecksumDo
+	Omit(cols ...field.Expr) IDatasetChecksumDo
+	Join(table schema.Tabler, on ...field.Expr) IDatasetChecksumDo
+	LeftJoin(table schema.Tabler, on ...field.Expr) IDatasetChecksumDo
+	RightJoin(table schema.Tabler, on ...field.Expr) IDatasetChecksumDo
+	Group(cols ...field.Expr) IDatasetChecksumDo
+	Having(conds ...gen.Condition) IDatasetChecksumDo
+	Limit(limit int) IDatasetChecksumDo
+	Offset(offset int) IDatasetChecksumDo
+	Count() (count int64, err error)
+	Scopes(funcs ...func(gen.Dao) gen.Dao) IDatasetChecksumDo
+	Unscoped() IDatasetChecksumDo
+	Create(values ...*model.DatasetChecksum) error
+	CreateInBatches(values []*model.DatasetChecksum, batchSize int) error
+	Save(values ...*model.DatasetChecksum) error
+	First() (*model.DatasetChecksum, error)
+	Take() (*model.DatasetChecksum, error)
+	Last() (*model.DatasetChecksum, error)
+	Find() ([]*model.DatasetChecksum, error)
+	FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.DatasetChecksum, err error)
+	FindInBatches(result *[]*model.DatasetChecksum, batchSize int, fc func(tx gen.Dao, batch int) error) error
+	Pluck(column field.Expr, dest interface{}) error
+	Delete(...*model.DatasetChecksum) (info gen.ResultInfo, err error)
+	Update(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	Updates(value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumn(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumnSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	UpdateColumns(value interface{}) (info gen.ResultInfo, err error)
+	UpdateFrom(q gen.SubQuery) gen.Dao
+	Attrs(attrs ...field.AssignExpr) IDatasetChecksumDo
+	Assign(attrs ...field.AssignExpr) IDatasetChecksumDo
+	Joins(fields ...field.RelationField) IDatasetChecksumDo
+	Preload(fields ...field.RelationField) IDatasetChecksumDo
+	FirstOrInit() (*model.DatasetChecksum, error)
+	FirstOrCreate() (*model.DatasetChecksum, error)
+	FindByPage(offset int, limit int) (result []*model.DatasetChecksum, count int64, err error)
+	ScanByPage(result interface{}, offset int, limit int) (count int64, err error)
+	Scan(result interface{}) (err error)
+	Returning(value interface{}, columns ...string) IDatasetChecksumDo
+	UnderlyingDB() *gorm.DB
+	schema.Tabler
+}
+
+func (d datasetChecksumDo) Debug() IDatasetChecksumDo {
+	return d.withDO(d.DO.Debug())
+}
+
+func (d datasetChecksumDo) WithContext(ctx context.Context) IDatasetChecksumDo {
+	return d.withDO(d.DO.WithContext(ctx))
+}
+
+func (d datasetChecksumDo) ReadDB() IDatasetChecksumDo {
+	return d.Clauses(dbresolver.Read)
+}
+
+func (d datasetChecksumDo) WriteDB() IDatasetChecksumDo {
+	return d.Clauses(dbresolver.Write)
+}
+
+func (d datasetChecksumDo) Session(config *gorm.Session) IDatasetChecksumDo {
+	return d.withDO(d.DO.Session(config))
+}
+
+func (d datasetChecksumDo) Clauses(conds ...clause.Expression) IDatasetChecksumDo {
+	return d.withDO(d.DO.Clauses(conds...))
+}
+
+func (d datasetChecksumDo) Returning(value interface{}, columns ...string) IDatasetChecksumDo {
+	return d.withDO(d.DO.Returning(value, columns...))
+}
+
+func (d datasetChecksumDo) Not(conds ...gen.Condition) IDatasetChecksumDo {
+	return d.withDO(d.DO.Not(conds...))
+}
+
+func (d datasetChecksumDo) Or(conds ...gen.Condition) IDatasetChecksumDo {
+	return d.withDO(d.DO.Or(conds...))
+}
+
+func (d datasetChecksumDo) Select(conds ...field.Expr) IDatasetChecksumDo {
+	return d.withDO(d.DO.Select(conds...))
+}
+
+func (d datasetChecksumDo) Where(conds ...gen.Condition) IDatasetChecksumDo {
+	return d.withDO(d.DO.Where(conds...))
+}
+
+func (d datasetChecksumDo) Order(conds ...field.Expr) IDatasetChecksumDo {
+	return d.withDO(d.DO.Order(conds...))
+}
+
+func (d datasetChecksumDo) Distinct(cols ...field.Expr) IDatasetChecksumDo {
+	return d.withDO(d.DO.Distinct(cols...))
+}
+
+func (d datasetChecksumDo) Omit(cols ...field.Expr) IDatasetChecksumDo {
+	return d.withDO(d.DO.Omit(cols...))
+}
+
+func (d datasetChecksumDo) Join(table schema.Tabler, on ...field.Expr) IDatasetChecksumDo {
+	return d.withDO(d.DO.Join(table, on...))
+}
+
+func (d datasetChecksumDo) LeftJoin(table schema.Tabler, on ...field.Expr) IDatasetChecksumDo {
+	return d.withDO(d.DO.LeftJoin(table, on...))
+}
+
+func (d datasetChecksumDo) RightJoin(table schema.Tabler, on ...field.Expr) IDatasetChecksumDo {
+	return d.withDO(d.DO.RightJoin(table, on...))
+}
+
+func (d datasetChecksumDo) Group(cols ...field.Expr) IDatasetChecksumDo {
+	return d.withDO(d.DO.Group(cols...))
+}
+
+func (d datasetChecksumDo) Having(conds ...gen.Condition) IDatasetChecksumDo {
+	return d.withDO(d.DO.Having(conds...))
+}
+
+func (d datasetChecksumDo) Limit(limit int) IDatasetChecksumDo {
+	return d.withDO(d.DO.Limit(limit))
+}
+
+func (d datasetChecksumDo) Offset(offset int) IDatasetChecksumDo {
+	return d.withDO(d.DO.Offset(offset))
+}
+
+func (d datasetChecksumDo) Scopes(funcs ...func(gen.Dao) gen.Dao) IDatasetChecksumDo {
+	return d.withDO(d.DO.Scopes(funcs...))
+}
+
+func (d datasetChecksumDo) Unscoped() IDatasetChecksumDo {
+	return d.withDO(d.DO.Unscoped())
+}
+
+func (d datasetChecksumDo) Create(values ...*model.DatasetChecksum) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return d.DO.Create(values)
+}
+
+func (d datasetChecksumDo) CreateInBatches(values []*model.DatasetChecksum, batchSize int) error {
+	return d.DO.CreateInBatches(values, batchSize)
+}
+
+// Save : !!! underlying implementation is different with GORM
+// The method is equivalent to executing the statement: db.Clauses(clause.OnConflict{UpdateAll: true}).Create(values)
+func (d datasetChecksumDo) Save(values ...*model.DatasetChecksum) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return d.DO.Save(values)
+}
+
+func (d datasetChecksumDo) First() (*model.DatasetChecksum, error) {
+	if result, err := d.DO.First(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DatasetChecksum), nil
+	}
+}
+
+func (d datasetChecksumDo) Take() (*model.DatasetChecksum, error) {
+	if result, err := d.DO.Take(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DatasetChecksum), nil
+	}
+}
+
+func (d datasetChecksumDo) Last() (*model.DatasetChecksum, error) {
+	if result, err := d.DO.Last(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DatasetChecksum), nil
+	}
+}
+
+func (d datasetChecksumDo) Find() ([]*model.DatasetChecksum, error) {
+	result, err := d.DO.Find()
+	return result.([]*model.DatasetChecksum), err
+}
+
+func (d datasetChecksumDo) FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.DatasetChecksum, err error) {
+	buf := make([]*model.DatasetChecksum, 0, batchSize)
+	err = d.DO.FindInBatches(&buf, batchSize, func(tx gen.Dao, batch int) error {
+		defer func() { results = append(results, buf...) }()
+		return fc(tx, batch)
+	})
+	return results, err
+}
+
+func (d datasetChecksumDo) FindInBatches(result *[]*model.DatasetChecksum, batchSize int, fc func(tx gen.Dao, batch int) error) error {
+	return d.DO.FindInBatches(result, batchSize, fc)
+}
+
+func (d datasetChecksumDo) Attrs(attrs ...field.AssignExpr) IDatasetChecksumDo {
+	return d.withDO(d.DO.Attrs(attrs...))
+}
+
+func (d datasetChecksumDo) Assign(attrs ...field.AssignExpr) IDatasetChecksumDo {
+	return d.withDO(d.DO.Assign(attrs...))
+}
+
+func (d datasetChecksumDo) Joins(fields ...field.RelationField) IDatasetChecksumDo {
+	for _, _f := range fields {
+		d = *d.withDO(d.DO.Joins(_f))
+	}
+	return &d
+}
+
+func (d datasetChecksumDo) Preload(fields ...field.RelationField) IDatasetChecksumDo {
+	for _, _f := range fields {
+		d = *d.withDO(d.DO.Preload(_f))
+	}
+	return &d
+}
+
+func (d datasetChecksumDo) FirstOrInit() (*model.DatasetChecksum, error) {
+	if result, err := d.DO.FirstOrInit(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DatasetChecksum), nil
+	}
+}
+
+func (d datasetChecksumDo) FirstOrCreate() (*model.DatasetChecksum, error) {
+	if result, err := d.DO.FirstOrCreate(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DatasetChecksum), nil
+	}
+}
+
+func (d datasetChecksumDo) FindByPage(offset int, limit int) (result []*model.DatasetChecksum, count int64, err error) {
+	result, err = d.Offset(offset).Limit(limit).Find()
+	if err != nil {
+		return
+	}
+
+	if size := len(result); 0 < limit && 0 < size && size < limit {
+		count = int64(size + offset)
+		return
+	}
+
+	count, err = d.Offset(-1).Limit(-1).Count()
+	return
+}
+
+func (d datasetChecksumDo) ScanByPage(result interface{}, offset int, limit int) (count int64, err error) {
+	count, err = d.Count()
+	if err != nil {
+		return
+	}
+
+	err = d.Offset(offset).Limit(limit).Scan(result)
+	return
+}
+
+func (d datasetChecksumDo) Scan(result interface{}) (err error) {
+	return d.DO.Scan(result)
+}
+
+func (d datasetChecksumDo) Delete(models ...*model.DatasetChecksum) (result gen.ResultInfo, err error) {
+	return d.DO.Delete(models)
+}
+
+func (d *datasetChecksumDo) withDO(do gen.Dao) *datasetChecksumDo {
+	d.DO = *do.(*gen.DO)
+	return d
+}
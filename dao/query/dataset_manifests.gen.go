@@ -0,0 +1,444 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package query
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"gorm.io/gen"
+	"gorm.io/gen/field"
+
+	"gorm.io/plugin/dbresolver"
+
+	"webdav/dao/model"
+)
+
+func newDatasetManifest(db *gorm.DB, opts ...gen.DOOption) datasetManifest {
+	_datasetManifest := datasetManifest{}
+
+	_datasetManifest.datasetManifestDo.UseDB(db, opts...)
+	_datasetManifest.datasetManifestDo.UseModel(&model.DatasetManifest{})
+
+	tableName := _datasetManifest.datasetManifestDo.TableName()
+	_datasetManifest.ALL = field.NewAsterisk(tableName)
+	_datasetManifest.ID = field.NewUint(tableName, "id")
+	_datasetManifest.CreatedAt = field.NewTime(tableName, "created_at")
+	_datasetManifest.UpdatedAt = field.NewTime(tableName, "updated_at")
+	_datasetManifest.DeletedAt = field.NewField(tableName, "deleted_at")
+	_datasetManifest.DatasetID = field.NewUint(tableName, "dataset_id")
+	_datasetManifest.Status = field.NewString(tableName, "status")
+	_datasetManifest.Message = field.NewString(tableName, "message")
+	_datasetManifest.Files = field.NewInt64(tableName, "files")
+	_datasetManifest.Bytes = field.NewInt64(tableName, "bytes")
+	_datasetManifest.HashedAt = field.NewTime(tableName, "hashed_at")
+	_datasetManifest.VerifyStatus = field.NewString(tableName, "verify_status")
+	_datasetManifest.VerifyError = field.NewString(tableName, "verify_error")
+	_datasetManifest.VerifiedAt = field.NewTime(tableName, "verified_at")
+	_datasetManifest.Report = field.NewField(tableName, "report")
+
+	_datasetManifest.fillFieldMap()
+
+	return _datasetManifest
+}
+
+type datasetManifest struct {
+	datasetManifestDo datasetManifestDo
+
+	ALL          field.Asterisk
+	ID           field.Uint
+	CreatedAt    field.Time
+	UpdatedAt    field.Time
+	DeletedAt    field.Field
+	DatasetID    field.Uint
+	Status       field.String
+	Message      field.String
+	Files        field.Int64
+	Bytes        field.Int64
+	HashedAt     field.Time
+	VerifyStatus field.String
+	VerifyError  field.String
+	VerifiedAt   field.Time
+	Report       field.Field
+
+	fieldMap map[string]field.Expr
+}
+
+func (d datasetManifest) Table(newTableName string) *datasetManifest {
+	d.datasetManifestDo.UseTable(newTableName)
+	return d.updateTableName(newTableName)
+}
+
+func (d datasetManifest) As(alias string) *datasetManifest {
+	d.datasetManifestDo.DO = *(d.datasetManifestDo.As(alias).(*gen.DO))
+	return d.updateTableName(alias)
+}
+
+func (d *datasetManifest) updateTableName(table string) *datasetManifest {
+	d.ALL = field.NewAsterisk(table)
+	d.ID = field.NewUint(table, "id")
+	d.CreatedAt = field.NewTime(table, "created_at")
+	d.UpdatedAt = field.NewTime(table, "updated_at")
+	d.DeletedAt = field.NewField(table, "deleted_at")
+	d.DatasetID = field.NewUint(table, "dataset_id")
+	d.Status = field.NewString(table, "status")
+	d.Message = field.NewString(table, "message")
+	d.Files = field.NewInt64(table, "files")
+	d.Bytes = field.NewInt64(table, "bytes")
+	d.HashedAt = field.NewTime(table, "hashed_at")
+	d.VerifyStatus = field.NewString(table, "verify_status")
+	d.VerifyError = field.NewString(table, "verify_error")
+	d.VerifiedAt = field.NewTime(table, "verified_at")
+	d.Report = field.NewField(table, "report")
+
+	d.fillFieldMap()
+
+	return d
+}
+
+func (d *datasetManifest) WithContext(ctx context.Context) IDatasetManifestDo {
+	return d.datasetManifestDo.WithContext(ctx)
+}
+
+func (d datasetManifest) TableName() string { return d.datasetManifestDo.TableName() }
+
+func (d datasetManifest) Alias() string { return d.datasetManifestDo.Alias() }
+
+func (d datasetManifest) Columns(cols ...field.Expr) gen.Columns {
+	return d.datasetManifestDo.Columns(cols...)
+}
+
+func (d *datasetManifest) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
+	_f, ok := d.fieldMap[fieldName]
+	if !ok || _f == nil {
+		return nil, false
+	}
+	_oe, ok := _f.(field.OrderExpr)
+	return _oe, ok
+}
+
+func (d *datasetManifest) fillFieldMap() {
+	d.fieldMap = make(map[string]field.Expr, 14)
+	d.fieldMap["id"] = d.ID
+	d.fieldMap["created_at"] = d.CreatedAt
+	d.fieldMap["updated_at"] = d.UpdatedAt
+	d.fieldMap["deleted_at"] = d.DeletedAt
+	d.fieldMap["dataset_id"] = d.DatasetID
+	d.fieldMap["status"] = d.Status
+	d.fieldMap["message"] = d.Message
+	d.fieldMap["files"] = d.Files
+	d.fieldMap["bytes"] = d.Bytes
+	d.fieldMap["hashed_at"] = d.HashedAt
+	d.fieldMap["verify_status"] = d.VerifyStatus
+	d.fieldMap["verify_error"] = d.VerifyError
+	d.fieldMap["verified_at"] = d.VerifiedAt
+	d.fieldMap["report"] = d.Report
+}
+
+func (d datasetManifest) clone(db *gorm.DB) datasetManifest {
+	d.datasetManifestDo.ReplaceConnPool(db.Statement.ConnPool)
+	return d
+}
+
+func (d datasetManifest) replaceDB(db *gorm.DB) datasetManifest {
+	d.datasetManifestDo.ReplaceDB(db)
+	return d
+}
+
+type datasetManifestDo struct{ gen.DO }
+
+type IDatasetManifestDo interface {
+	gen.SubQuery
+	Debug() IDatasetManifestDo
+	WithContext(ctx context.Context) IDatasetManifestDo
+	WithResult(fc func(tx gen.Dao)) gen.ResultInfo
+	ReplaceDB(db *gorm.DB)
+	ReadDB() IDatasetManifestDo
+	WriteDB() IDatasetManifestDo
+	As(alias string) gen.Dao
+	Session(config *gorm.Session) IDatasetManifestDo
+	Columns(cols ...field.Expr) gen.Columns
+	Clauses(conds ...clause.Expression) IDatasetManifestDo
+	Not(conds ...gen.Condition) IDatasetManifestDo
+	Or(conds ...gen.Condition) IDatasetManifestDo
+	Select(conds ...field.Expr) IDatasetManifestDo
+	Where(conds ...gen.Condition) IDatasetManifestDo
+	Order(conds ...field.Expr) IDatasetManifestDo
+	Distinct(cols ...field.Expr) IDatasetManifestDo
+	Omit(cols ...field.Expr) IDatasetManifestDo
+	Join(table schema.Tabler, on ...field.Expr) IDatasetManifestDo
+	LeftJoin(table schema.Tabler, on ...field.Expr) IDatasetManifestDo
+	RightJoin(table schema.Tabler, on ...field.Expr) IDatasetManifestDo
+	Group(cols ...field.Expr) IDatasetManifestDo
+	Having(conds ...gen.Condition) IDatasetManifestDo
+	Limit(limit int) IDatasetManifestDo
+	Offset(offset int) IDatasetManifestDo
+	Count() (count int64, err error)
+	Scopes(funcs ...func(gen.Dao) gen.Dao) IDatasetManifestDo
+	Unscoped() IDatasetManifestDo
+	Create(values ...*model.DatasetManifest) error
+	CreateInBatches(values []*model.DatasetManifest, batchSize int) error
+	Save(values ...*model.DatasetManifest) error
+	First() (*model.DatasetManifest, error)
+	Take() (*model.DatasetManifest, error)
+	Last() (*model.DatasetManifest, error)
+	Find() ([]*model.DatasetManifest, error)
+	FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.DatasetManifest, err error)
+	FindInBatches(result *[]*model.DatasetManifest, batchSize int, fc func(tx gen.Dao, batch int) error) error
+	Pluck(column field.Expr, dest interface{}) error
+	Delete(...*model.DatasetManifest) (info gen.ResultInfo, err error)
+	Update(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	Updates(value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumn(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumnSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	UpdateColumns(value interface{}) (info gen.ResultInfo, err error)
+	UpdateFrom(q gen.SubQuery) gen.Dao
+	Attrs(attrs ...field.AssignExpr) IDatasetManifestDo
+	Assign(attrs ...field.AssignExpr) IDatasetManifestDo
+	Joins(fields ...field.RelationField) IDatasetManifestDo
+	Preload(fields ...field.RelationField) IDatasetManifestDo
+	FirstOrInit() (*model.DatasetManifest, error)
+	FirstOrCreate() (*model.DatasetManifest, error)
+	FindByPage(offset int, limit int) (result []*model.DatasetManifest, count int64, err error)
+	ScanByPage(result interface{}, offset int, limit int) (count int64, err error)
+	Scan(result interface{}) (err error)
+	Returning(value interface{}, columns ...string) IDatasetManifestDo
+	UnderlyingDB() *gorm.DB
+	schema.Tabler
+}
+
+func (d datasetManifestDo) Debug() IDatasetManifestDo {
+	return d.withDO(d.DO.Debug())
+}
+
+func (d datasetManifestDo) WithContext(ctx context.Context) IDatasetManifestDo {
+	return d.withDO(d.DO.WithContext(ctx))
+}
+
+func (d datasetManifestDo) ReadDB() IDatasetManifestDo {
+	return d.Clauses(dbresolver.Read)
+}
+
+func (d datasetManifestDo) WriteDB() IDatasetManifestDo {
+	return d.Clauses(dbresolver.Write)
+}
+
+func (d datasetManifestDo) Session(config *gorm.Session) IDatasetManifestDo {
+	return d.withDO(d.DO.Session(config))
+}
+
+func (d datasetManifestDo) Clauses(conds ...clause.Expression) IDatasetManifestDo {
+	return d.withDO(d.DO.Clauses(conds...))
+}
+
+func (d datasetManifestDo) Returning(value interface{}, columns ...string) IDatasetManifestDo {
+	return d.withDO(d.DO.Returning(value, columns...))
+}
+
+func (d datasetManifestDo) Not(conds ...gen.Condition) IDatasetManifestDo {
+	return d.withDO(d.DO.Not(conds...))
+}
+
+func (d datasetManifestDo) Or(conds ...gen.Condition) IDatasetManifestDo {
+	return d.withDO(d.DO.Or(conds...))
+}
+
+func (d datasetManifestDo) Select(conds ...field.Expr) IDatasetManifestDo {
+	return d.withDO(d.DO.Select(conds...))
+}
+
+func (d datasetManifestDo) Where(conds ...gen.Condition) IDatasetManifestDo {
+	return d.withDO(d.DO.Where(conds...))
+}
+
+func (d datasetManifestDo) Order(conds ...field.Expr) IDatasetManifestDo {
+	return d.withDO(d.DO.Order(conds...))
+}
+
+func (d datasetManifestDo) Distinct(cols ...field.Expr) IDatasetManifestDo {
+	return d.withDO(d.DO.Distinct(cols...))
+}
+
+func (d datasetManifestDo) Omit(cols ...field.Expr) IDatasetManifestDo {
+	return d.withDO(d.DO.Omit(cols...))
+}
+
+func (d datasetManifestDo) Join(table schema.Tabler, on ...field.Expr) IDatasetManifestDo {
+	return d.withDO(d.DO.Join(table, on...))
+}
+
+func (d datasetManifestDo) LeftJoin(table schema.Tabler, on ...field.Expr) IDatasetManifestDo {
+	return d.withDO(d.DO.LeftJoin(table, on...))
+}
+
+func (d datasetManifestDo) RightJoin(table schema.Tabler, on ...field.Expr) IDatasetManifestDo {
+	return d.withDO(d.DO.RightJoin(table, on...))
+}
+
+func (d datasetManifestDo) Group(cols ...field.Expr) IDatasetManifestDo {
+	return d.withDO(d.DO.Group(cols...))
+}
+
+func (d datasetManifestDo) Having(conds ...gen.Condition) IDatasetManifestDo {
+	return d.withDO(d.DO.Having(conds...))
+}
+
+func (d datasetManifestDo) Limit(limit int) IDatasetManifestDo {
+	return d.withDO(d.DO.Limit(limit))
+}
+
+func (d datasetManifestDo) Offset(offset int) IDatasetManifestDo {
+	return d.withDO(d.DO.Offset(offset))
+}
+
+func (d datasetManifestDo) Scopes(funcs ...func(gen.Dao) gen.Dao) IDatasetManifestDo {
+	return d.withDO(d.DO.Scopes(funcs...))
+}
+
+func (d datasetManifestDo) Unscoped() IDatasetManifestDo {
+	return d.withDO(d.DO.Unscoped())
+}
+
+func (d datasetManifestDo) Create(values ...*model.DatasetManifest) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return d.DO.Create(values)
+}
+
+func (d datasetManifestDo) CreateInBatches(values []*model.DatasetManifest, batchSize int) error {
+	return d.DO.CreateInBatches(values, batchSize)
+}
+
+// Save : !!! underlying implementation is different with GORM
+// The method is equivalent to executing the statement: db.Clauses(clause.OnConflict{UpdateAll: true}).Create(values)
+func (d datasetManifestDo) Save(values ...*model.DatasetManifest) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return d.DO.Save(values)
+}
+
+func (d datasetManifestDo) First() (*model.DatasetManifest, error) {
+	if result, err := d.DO.First(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DatasetManifest), nil
+	}
+}
+
+func (d datasetManifestDo) Take() (*model.DatasetManifest, error) {
+	if result, err := d.DO.Take(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DatasetManifest), nil
+	}
+}
+
+func (d datasetManifestDo) Last() (*model.DatasetManifest, error) {
+	if result, err := d.DO.Last(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DatasetManifest), nil
+	}
+}
+
+func (d datasetManifestDo) Find() ([]*model.DatasetManifest, error) {
+	result, err := d.DO.Find()
+	return result.([]*model.DatasetManifest), err
+}
+
+func (d datasetManifestDo) FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.DatasetManifest, err error) {
+	buf := make([]*model.DatasetManifest, 0, batchSize)
+	err = d.DO.FindInBatches(&buf, batchSize, func(tx gen.Dao, batch int) error {
+		defer func() { results = append(results, buf...) }()
+		return fc(tx, batch)
+	})
+	return results, err
+}
+
+func (d datasetManifestDo) FindInBatches(result *[]*model.DatasetManifest, batchSize int, fc func(tx gen.Dao, batch int) error) error {
+	return d.DO.FindInBatches(result, batchSize, fc)
+}
+
+func (d datasetManifestDo) Attrs(attrs ...field.AssignExpr) IDatasetManifestDo {
+	return d.withDO(d.DO.Attrs(attrs...))
+}
+
+func (d datasetManifestDo) Assign(attrs ...field.AssignExpr) IDatasetManifestDo {
+	return d.withDO(d.DO.Assign(attrs...))
+}
+
+func (d datasetManifestDo) Joins(fields ...field.RelationField) IDatasetManifestDo {
+	for _, _f := range fields {
+		d = *d.withDO(d.DO.Joins(_f))
+	}
+	return &d
+}
+
+func (d datasetManifestDo) Preload(fields ...field.RelationField) IDatasetManifestDo {
+	for _, _f := range fields {
+		d = *d.withDO(d.DO.Preload(_f))
+	}
+	return &d
+}
+
+func (d datasetManifestDo) FirstOrInit() (*model.DatasetManifest, error) {
+	if result, err := d.DO.FirstOrInit(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DatasetManifest), nil
+	}
+}
+
+func (d datasetManifestDo) FirstOrCreate() (*model.DatasetManifest, error) {
+	if result, err := d.DO.FirstOrCreate(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DatasetManifest), nil
+	}
+}
+
+func (d datasetManifestDo) FindByPage(offset int, limit int) (result []*model.DatasetManifest, count int64, err error) {
+	result, err = d.Offset(offset).Limit(limit).Find()
+	if err != nil {
+		return
+	}
+
+	if size := len(result); 0 < limit && 0 < size && size < limit {
+		count = int64(size + offset)
+		return
+	}
+
+	count, err = d.Offset(-1).Limit(-1).Count()
+	return
+}
+
+func (d datasetManifestDo) ScanByPage(result interface{}, offset int, limit int) (count int64, err error) {
+	count, err = d.Count()
+	if err != nil {
+		return
+	}
+
+	err = d.Offset(offset).Limit(limit).Scan(result)
+	return
+}
+
+func (d datasetManifestDo) Scan(result interface{}) (err error) {
+	return d.DO.Scan(result)
+}
+
+func (d datasetManifestDo) Delete(models ...*model.DatasetManifest) (result gen.ResultInfo, err error) {
+	return d.DO.Delete(models)
+}
+
+func (d *datasetManifestDo) withDO(do gen.Dao) *datasetManifestDo {
+	d.DO = *do.(*gen.DO)
+	return d
+}
@@ -0,0 +1,408 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package query
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"gorm.io/gen"
+	"gorm.io/gen/field"
+
+	"gorm.io/plugin/dbresolver"
+
+	"webdav/dao/model"
+)
+
+func newDatasetVersionFile(db *gorm.DB, opts ...gen.DOOption) datasetVersionFile {
+	_datasetVersionFile := datasetVersionFile{}
+
+	_datasetVersionFile.datasetVersionFileDo.UseDB(db, opts...)
+	_datasetVersionFile.datasetVersionFileDo.UseModel(&model.DatasetVersionFile{})
+
+	tableName := _datasetVersionFile.datasetVersionFileDo.TableName()
+	_datasetVersionFile.ALL = field.NewAsterisk(tableName)
+	_datasetVersionFile.ID = field.NewUint(tableName, "id")
+	_datasetVersionFile.VersionID = field.NewUint(tableName, "version_id")
+	_datasetVersionFile.Path = field.NewString(tableName, "path")
+	_datasetVersionFile.Size = field.NewInt64(tableName, "size")
+	_datasetVersionFile.SHA256 = field.NewString(tableName, "sha256")
+
+	_datasetVersionFile.fillFieldMap()
+
+	return _datasetVersionFile
+}
+
+type datasetVersionFile struct {
+	datasetVersionFileDo datasetVersionFileDo
+
+	ALL       field.Asterisk
+	ID        field.Uint
+	VersionID field.Uint
+	Path      field.String
+	Size      field.Int64
+	SHA256    field.String
+
+	fieldMap map[string]field.Expr
+}
+
+func (d datasetVersionFile) Table(newTableName string) *datasetVersionFile {
+	d.datasetVersionFileDo.UseTable(newTableName)
+	return d.updateTableName(newTableName)
+}
+
+func (d datasetVersionFile) As(alias string) *datasetVersionFile {
+	d.datasetVersionFileDo.DO = *(d.datasetVersionFileDo.As(alias).(*gen.DO))
+	return d.updateTableName(alias)
+}
+
+func (d *datasetVersionFile) updateTableName(table string) *datasetVersionFile {
+	d.ALL = field.NewAsterisk(table)
+	d.ID = field.NewUint(table, "id")
+	d.VersionID = field.NewUint(table, "version_id")
+	d.Path = field.NewString(table, "path")
+	d.Size = field.NewInt64(table, "size")
+	d.SHA256 = field.NewString(table, "sha256")
+
+	d.fillFieldMap()
+
+	return d
+}
+
+func (d *datasetVersionFile) WithContext(ctx context.Context) IDatasetVersionFileDo {
+	return d.datasetVersionFileDo.WithContext(ctx)
+}
+
+func (d datasetVersionFile) TableName() string { return d.datasetVersionFileDo.TableName() }
+
+func (d datasetVersionFile) Alias() string { return d.datasetVersionFileDo.Alias() }
+
+func (d datasetVersionFile) Columns(cols ...field.Expr) gen.Columns {
+	return d.datasetVersionFileDo.Columns(cols...)
+}
+
+func (d *datasetVersionFile) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
+	_f, ok := d.fieldMap[fieldName]
+	if !ok || _f == nil {
+		return nil, false
+	}
+	_oe, ok := _f.(field.OrderExpr)
+	return _oe, ok
+}
+
+func (d *datasetVersionFile) fillFieldMap() {
+	d.fieldMap = make(map[string]field.Expr, 5)
+	d.fieldMap["id"] = d.ID
+	d.fieldMap["version_id"] = d.VersionID
+	d.fieldMap["path"] = d.Path
+	d.fieldMap["size"] = d.Size
+	d.fieldMap["sha256"] = d.SHA256
+}
+
+func (d datasetVersionFile) clone(db *gorm.DB) datasetVersionFile {
+	d.datasetVersionFileDo.ReplaceConnPool(db.Statement.ConnPool)
+	return d
+}
+
+func (d datasetVersionFile) replaceDB(db *gorm.DB) datasetVersionFile {
+	d.datasetVersionFileDo.ReplaceDB(db)
+	return d
+}
+
+type datasetVersionFileDo struct{ gen.DO }
+
+type IDatasetVersionFileDo interface {
+	gen.SubQuery
+	Debug() IDatasetVersionFileDo
+	WithContext(ctx context.Context) IDatasetVersionFileDo
+	WithResult(fc func(tx gen.Dao)) gen.ResultInfo
+	ReplaceDB(db *gorm.DB)
+	ReadDB() IDatasetVersionFileDo
+	WriteDB() IDatasetVersionFileDo
+	As(alias string) gen.Dao
+	Session(config *gorm.Session) IDatasetVersionFileDo
+	Columns(cols ...field.Expr) gen.Columns
+	Clauses(conds ...clause.Expression) IDatasetVersionFileDo
+	Not(conds ...gen.Condition) IDatasetVersionFileDo
+	Or(conds ...gen.Condition) IDatasetVersionFileDo
+	Select(conds ...field.Expr) IDatasetVersionFileDo
+	Where(conds ...gen.Condition) IDatasetVersionFileDo
+	Order(conds ...field.Expr) IDatasetVersionFileDo
+	Distinct(cols ...field.Expr) IDatasetVersionFileDo
+	Omit(cols ...field.Expr) IDatasetVersionFileDo
+	Join(table schema.Tabler, on ...field.Expr) IDatasetVersionFileDo
+	LeftJoin(table schema.Tabler, on ...field.Expr) IDatasetVersionFileDo
+	RightJoin(table schema.Tabler, on ...field.Expr) IDatasetVersionFileDo
+	Group(cols ...field.Expr) IDatasetVersionFileDo
+	Having(conds ...gen.Condition) IDatasetVersionFileDo
+	Limit(limit int) IDatasetVersionFileDo
+	Offset(offset int) IDatasetVersionFileDo
+	Count() (count int64, err error)
+	Scopes(funcs ...func(gen.Dao) gen.Dao) IDatasetVersionFileDo
+	Unscoped() IDatasetVersionFileDo
+	Create(values ...*model.DatasetVersionFile) error
+	CreateInBatches(values []*model.DatasetVersionFile, batchSize int) error
+	Save(values ...*model.DatasetVersionFile) error
+	First() (*model.DatasetVersionFile, error)
+	Take() (*model.DatasetVersionFile, error)
+	Last() (*model.DatasetVersionFile, error)
+	Find() ([]*model.DatasetVersionFile, error)
+	FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.DatasetVersionFile, err error)
+	FindInBatches(result *[]*model.DatasetVersionFile, batchSize int, fc func(tx gen.Dao, batch int) error) error
+	Pluck(column field.Expr, dest interface{}) error
+	Delete(...*model.DatasetVersionFile) (info gen.ResultInfo, err error)
+	Update(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	Updates(value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumn(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumnSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	UpdateColumns(value interface{}) (info gen.ResultInfo, err error)
+	UpdateFrom(q gen.SubQuery) gen.Dao
+	Attrs(attrs ...field.AssignExpr) IDatasetVersionFileDo
+	Assign(attrs ...field.AssignExpr) IDatasetVersionFileDo
+	Joins(fields ...field.RelationField) IDatasetVersionFileDo
+	Preload(fields ...field.RelationField) IDatasetVersionFileDo
+	FirstOrInit() (*model.DatasetVersionFile, error)
+	FirstOrCreate() (*model.DatasetVersionFile, error)
+	FindByPage(offset int, limit int) (result []*model.DatasetVersionFile, count int64, err error)
+	ScanByPage(result interface{}, offset int, limit int) (count int64, err error)
+	Scan(result interface{}) (err error)
+	Returning(value interface{}, columns ...string) IDatasetVersionFileDo
+	UnderlyingDB() *gorm.DB
+	schema.Tabler
+}
+
+func (d datasetVersionFileDo) Debug() IDatasetVersionFileDo {
+	return d.withDO(d.DO.Debug())
+}
+
+func (d datasetVersionFileDo) WithContext(ctx context.Context) IDatasetVersionFileDo {
+	return d.withDO(d.DO.WithContext(ctx))
+}
+
+func (d datasetVersionFileDo) ReadDB() IDatasetVersionFileDo {
+	return d.Clauses(dbresolver.Read)
+}
+
+func (d datasetVersionFileDo) WriteDB() IDatasetVersionFileDo {
+	return d.Clauses(dbresolver.Write)
+}
+
+func (d datasetVersionFileDo) Session(config *gorm.Session) IDatasetVersionFileDo {
+	return d.withDO(d.DO.Session(config))
+}
+
+func (d datasetVersionFileDo) Clauses(conds ...clause.Expression) IDatasetVersionFileDo {
+	return d.withDO(d.DO.Clauses(conds...))
+}
+
+func (d datasetVersionFileDo) Returning(value interface{}, columns ...string) IDatasetVersionFileDo {
+	return d.withDO(d.DO.Returning(value, columns...))
+}
+
+func (d datasetVersionFileDo) Not(conds ...gen.Condition) IDatasetVersionFileDo {
+	return d.withDO(d.DO.Not(conds...))
+}
+
+func (d datasetVersionFileDo) Or(conds ...gen.Condition) IDatasetVersionFileDo {
+	return d.withDO(d.DO.Or(conds...))
+}
+
+func (d datasetVersionFileDo) Select(conds ...field.Expr) IDatasetVersionFileDo {
+	return d.withDO(d.DO.Select(conds...))
+}
+
+func (d datasetVersionFileDo) Where(conds ...gen.Condition) IDatasetVersionFileDo {
+	return d.withDO(d.DO.Where(conds...))
+}
+
+func (d datasetVersionFileDo) Order(conds ...field.Expr) IDatasetVersionFileDo {
+	return d.withDO(d.DO.Order(conds...))
+}
+
+func (d datasetVersionFileDo) Distinct(cols ...field.Expr) IDatasetVersionFileDo {
+	return d.withDO(d.DO.Distinct(cols...))
+}
+
+func (d datasetVersionFileDo) Omit(cols ...field.Expr) IDatasetVersionFileDo {
+	return d.withDO(d.DO.Omit(cols...))
+}
+
+func (d datasetVersionFileDo) Join(table schema.Tabler, on ...field.Expr) IDatasetVersionFileDo {
+	return d.withDO(d.DO.Join(table, on...))
+}
+
+func (d datasetVersionFileDo) LeftJoin(table schema.Tabler, on ...field.Expr) IDatasetVersionFileDo {
+	return d.withDO(d.DO.LeftJoin(table, on...))
+}
+
+func (d datasetVersionFileDo) RightJoin(table schema.Tabler, on ...field.Expr) IDatasetVersionFileDo {
+	return d.withDO(d.DO.RightJoin(table, on...))
+}
+
+func (d datasetVersionFileDo) Group(cols ...field.Expr) IDatasetVersionFileDo {
+	return d.withDO(d.DO.Group(cols...))
+}
+
+func (d datasetVersionFileDo) Having(conds ...gen.Condition) IDatasetVersionFileDo {
+	return d.withDO(d.DO.Having(conds...))
+}
+
+func (d datasetVersionFileDo) Limit(limit int) IDatasetVersionFileDo {
+	return d.withDO(d.DO.Limit(limit))
+}
+
+func (d datasetVersionFileDo) Offset(offset int) IDatasetVersionFileDo {
+	return d.withDO(d.DO.Offset(offset))
+}
+
+func (d datasetVersionFileDo) Scopes(funcs ...func(gen.Dao) gen.Dao) IDatasetVersionFileDo {
+	return d.withDO(d.DO.Scopes(funcs...))
+}
+
+func (d datasetVersionFileDo) Unscoped() IDatasetVersionFileDo {
+	return d.withDO(d.DO.Unscoped())
+}
+
+func (d datasetVersionFileDo) Create(values ...*model.DatasetVersionFile) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return d.DO.Create(values)
+}
+
+func (d datasetVersionFileDo) CreateInBatches(values []*model.DatasetVersionFile, batchSize int) error {
+	return d.DO.CreateInBatches(values, batchSize)
+}
+
+// Save : !!! underlying implementation is different with GORM
+// The method is equivalent to executing the statement: db.Clauses(clause.OnConflict{UpdateAll: true}).Create(values)
+func (d datasetVersionFileDo) Save(values ...*model.DatasetVersionFile) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return d.DO.Save(values)
+}
+
+func (d datasetVersionFileDo) First() (*model.DatasetVersionFile, error) {
+	if result, err := d.DO.First(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DatasetVersionFile), nil
+	}
+}
+
+func (d datasetVersionFileDo) Take() (*model.DatasetVersionFile, error) {
+	if result, err := d.DO.Take(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DatasetVersionFile), nil
+	}
+}
+
+func (d datasetVersionFileDo) Last() (*model.DatasetVersionFile, error) {
+	if result, err := d.DO.Last(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DatasetVersionFile), nil
+	}
+}
+
+func (d datasetVersionFileDo) Find() ([]*model.DatasetVersionFile, error) {
+	result, err := d.DO.Find()
+	return result.([]*model.DatasetVersionFile), err
+}
+
+func (d datasetVersionFileDo) FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.DatasetVersionFile, err error) {
+	buf := make([]*model.DatasetVersionFile, 0, batchSize)
+	err = d.DO.FindInBatches(&buf, batchSize, func(tx gen.Dao, batch int) error {
+		defer func() { results = append(results, buf...) }()
+		return fc(tx, batch)
+	})
+	return results, err
+}
+
+func (d datasetVersionFileDo) FindInBatches(result *[]*model.DatasetVersionFile, batchSize int, fc func(tx gen.Dao, batch int) error) error {
+	return d.DO.FindInBatches(result, batchSize, fc)
+}
+
+func (d datasetVersionFileDo) Attrs(attrs ...field.AssignExpr) IDatasetVersionFileDo {
+	return d.withDO(d.DO.Attrs(attrs...))
+}
+
+func (d datasetVersionFileDo) Assign(attrs ...field.AssignExpr) IDatasetVersionFileDo {
+	return d.withDO(d.DO.Assign(attrs...))
+}
+
+func (d datasetVersionFileDo) Joins(fields ...field.RelationField) IDatasetVersionFileDo {
+	for _, _f := range fields {
+		d = *d.withDO(d.DO.Joins(_f))
+	}
+	return &d
+}
+
+func (d datasetVersionFileDo) Preload(fields ...field.RelationField) IDatasetVersionFileDo {
+	for _, _f := range fields {
+		d = *d.withDO(d.DO.Preload(_f))
+	}
+	return &d
+}
+
+func (d datasetVersionFileDo) FirstOrInit() (*model.DatasetVersionFile, error) {
+	if result, err := d.DO.FirstOrInit(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DatasetVersionFile), nil
+	}
+}
+
+func (d datasetVersionFileDo) FirstOrCreate() (*model.DatasetVersionFile, error) {
+	if result, err := d.DO.FirstOrCreate(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DatasetVersionFile), nil
+	}
+}
+
+func (d datasetVersionFileDo) FindByPage(offset int, limit int) (result []*model.DatasetVersionFile, count int64, err error) {
+	result, err = d.Offset(offset).Limit(limit).Find()
+	if err != nil {
+		return
+	}
+
+	if size := len(result); 0 < limit && 0 < size && size < limit {
+		count = int64(size + offset)
+		return
+	}
+
+	count, err = d.Offset(-1).Limit(-1).Count()
+	return
+}
+
+func (d datasetVersionFileDo) ScanByPage(result interface{}, offset int, limit int) (count int64, err error) {
+	count, err = d.Count()
+	if err != nil {
+		return
+	}
+
+	err = d.Offset(offset).Limit(limit).Scan(result)
+	return
+}
+
+func (d datasetVersionFileDo) Scan(result interface{}) (err error) {
+	return d.DO.Scan(result)
+}
+
+func (d datasetVersionFileDo) Delete(models ...*model.DatasetVersionFile) (result gen.ResultInfo, err error) {
+	return d.DO.Delete(models)
+}
+
+func (d *datasetVersionFileDo) withDO(do gen.Dao) *datasetVersionFileDo {
+	d.DO = *do.(*gen.DO)
+	return d
+}
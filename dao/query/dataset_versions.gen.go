@@ -0,0 +1,440 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package query
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"gorm.io/gen"
+	"gorm.io/gen/field"
+
+	"gorm.io/plugin/dbresolver"
+
+	"webdav/dao/model"
+)
+
+func newDatasetVersion(db *gorm.DB, opts ...gen.DOOption) datasetVersion {
+	_datasetVersion := datasetVersion{}
+
+	_datasetVersion.datasetVersionDo.UseDB(db, opts...)
+	_datasetVersion.datasetVersionDo.UseModel(&model.DatasetVersion{})
+
+	tableName := _datasetVersion.datasetVersionDo.TableName()
+	_datasetVersion.ALL = field.NewAsterisk(tableName)
+	_datasetVersion.ID = field.NewUint(tableName, "id")
+	_datasetVersion.CreatedAt = field.NewTime(tableName, "created_at")
+	_datasetVersion.UpdatedAt = field.NewTime(tableName, "updated_at")
+	_datasetVersion.DeletedAt = field.NewField(tableName, "deleted_at")
+	_datasetVersion.DatasetID = field.NewUint(tableName, "dataset_id")
+	_datasetVersion.Version = field.NewUint(tableName, "version")
+	_datasetVersion.Describe = field.NewString(tableName, "describe")
+	_datasetVersion.Path = field.NewString(tableName, "path")
+	_datasetVersion.Status = field.NewString(tableName, "status")
+	_datasetVersion.Message = field.NewString(tableName, "message")
+	_datasetVersion.Files = field.NewInt64(tableName, "files")
+	_datasetVersion.Bytes = field.NewInt64(tableName, "bytes")
+	_datasetVersion.UserID = field.NewUint(tableName, "user_id")
+
+	_datasetVersion.fillFieldMap()
+
+	return _datasetVersion
+}
+
+type datasetVersion struct {
+	datasetVersionDo datasetVersionDo
+
+	ALL       field.Asterisk
+	ID        field.Uint
+	CreatedAt field.Time
+	UpdatedAt field.Time
+	DeletedAt field.Field
+	DatasetID field.Uint
+	Version   field.Uint
+	Describe  field.String
+	Path      field.String
+	Status    field.String
+	Message   field.String
+	Files     field.Int64
+	Bytes     field.Int64
+	UserID    field.Uint
+
+	fieldMap map[string]field.Expr
+}
+
+func (d datasetVersion) Table(newTableName string) *datasetVersion {
+	d.datasetVersionDo.UseTable(newTableName)
+	return d.updateTableName(newTableName)
+}
+
+func (d datasetVersion) As(alias string) *datasetVersion {
+	d.datasetVersionDo.DO = *(d.datasetVersionDo.As(alias).(*gen.DO))
+	return d.updateTableName(alias)
+}
+
+func (d *datasetVersion) updateTableName(table string) *datasetVersion {
+	d.ALL = field.NewAsterisk(table)
+	d.ID = field.NewUint(table, "id")
+	d.CreatedAt = field.NewTime(table, "created_at")
+	d.UpdatedAt = field.NewTime(table, "updated_at")
+	d.DeletedAt = field.NewField(table, "deleted_at")
+	d.DatasetID = field.NewUint(table, "dataset_id")
+	d.Version = field.NewUint(table, "version")
+	d.Describe = field.NewString(table, "describe")
+	d.Path = field.NewString(table, "path")
+	d.Status = field.NewString(table, "status")
+	d.Message = field.NewString(table, "message")
+	d.Files = field.NewInt64(table, "files")
+	d.Bytes = field.NewInt64(table, "bytes")
+	d.UserID = field.NewUint(table, "user_id")
+
+	d.fillFieldMap()
+
+	return d
+}
+
+func (d *datasetVersion) WithContext(ctx context.Context) IDatasetVersionDo {
+	return d.datasetVersionDo.WithContext(ctx)
+}
+
+func (d datasetVersion) TableName() string { return d.datasetVersionDo.TableName() }
+
+func (d datasetVersion) Alias() string { return d.datasetVersionDo.Alias() }
+
+func (d datasetVersion) Columns(cols ...field.Expr) gen.Columns {
+	return d.datasetVersionDo.Columns(cols...)
+}
+
+func (d *datasetVersion) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
+	_f, ok := d.fieldMap[fieldName]
+	if !ok || _f == nil {
+		return nil, false
+	}
+	_oe, ok := _f.(field.OrderExpr)
+	return _oe, ok
+}
+
+func (d *datasetVersion) fillFieldMap() {
+	d.fieldMap = make(map[string]field.Expr, 13)
+	d.fieldMap["id"] = d.ID
+	d.fieldMap["created_at"] = d.CreatedAt
+	d.fieldMap["updated_at"] = d.UpdatedAt
+	d.fieldMap["deleted_at"] = d.DeletedAt
+	d.fieldMap["dataset_id"] = d.DatasetID
+	d.fieldMap["version"] = d.Version
+	d.fieldMap["describe"] = d.Describe
+	d.fieldMap["path"] = d.Path
+	d.fieldMap["status"] = d.Status
+	d.fieldMap["message"] = d.Message
+	d.fieldMap["files"] = d.Files
+	d.fieldMap["bytes"] = d.Bytes
+	d.fieldMap["user_id"] = d.UserID
+}
+
+func (d datasetVersion) clone(db *gorm.DB) datasetVersion {
+	d.datasetVersionDo.ReplaceConnPool(db.Statement.ConnPool)
+	return d
+}
+
+func (d datasetVersion) replaceDB(db *gorm.DB) datasetVersion {
+	d.datasetVersionDo.ReplaceDB(db)
+	return d
+}
+
+type datasetVersionDo struct{ gen.DO }
+
+type IDatasetVersionDo interface {
+	gen.SubQuery
+	Debug() IDatasetVersionDo
+	WithContext(ctx context.Context) IDatasetVersionDo
+	WithResult(fc func(tx gen.Dao)) gen.ResultInfo
+	ReplaceDB(db *gorm.DB)
+	ReadDB() IDatasetVersionDo
+	WriteDB() IDatasetVersionDo
+	As(alias string) gen.Dao
+	Session(config *gorm.Session) IDatasetVersionDo
+	Columns(cols ...field.Expr) gen.Columns
+	Clauses(conds ...clause.Expression) IDatasetVersionDo
+	Not(conds ...gen.Condition) IDatasetVersionDo
+	Or(conds ...gen.Condition) IDatasetVersionDo
+	Select(conds ...field.Expr) IDatasetVersionDo
+	Where(conds ...gen.Condition) IDatasetVersionDo
+	Order(conds ...field.Expr) IDatasetVersionDo
+	Distinct(cols ...field.Expr) IDatasetVersionDo
+	Omit(cols ...field.Expr) IDatasetVersionDo
+	Join(table schema.Tabler, on ...field.Expr) IDatasetVersionDo
+	LeftJoin(table schema.Tabler, on ...field.Expr) IDatasetVersionDo
+	RightJoin(table schema.Tabler, on ...field.Expr) IDatasetVersionDo
+	Group(cols ...field.Expr) IDatasetVersionDo
+	Having(conds ...gen.Condition) IDatasetVersionDo
+	Limit(limit int) IDatasetVersionDo
+	Offset(offset int) IDatasetVersionDo
+	Count() (count int64, err error)
+	Scopes(funcs ...func(gen.Dao) gen.Dao) IDatasetVersionDo
+	Unscoped() IDatasetVersionDo
+	Create(values ...*model.DatasetVersion) error
+	CreateInBatches(values []*model.DatasetVersion, batchSize int) error
+	Save(values ...*model.DatasetVersion) error
+	First() (*model.DatasetVersion, error)
+	Take() (*model.DatasetVersion, error)
+	Last() (*model.DatasetVersion, error)
+	Find() ([]*model.DatasetVersion, error)
+	FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.DatasetVersion, err error)
+	FindInBatches(result *[]*model.DatasetVersion, batchSize int, fc func(tx gen.Dao, batch int) error) error
+	Pluck(column field.Expr, dest interface{}) error
+	Delete(...*model.DatasetVersion) (info gen.ResultInfo, err error)
+	Update(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	Updates(value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumn(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumnSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	UpdateColumns(value interface{}) (info gen.ResultInfo, err error)
+	UpdateFrom(q gen.SubQuery) gen.Dao
+	Attrs(attrs ...field.AssignExpr) IDatasetVersionDo
+	Assign(attrs ...field.AssignExpr) IDatasetVersionDo
+	Joins(fields ...field.RelationField) IDatasetVersionDo
+	Preload(fields ...field.RelationField) IDatasetVersionDo
+	FirstOrInit() (*model.DatasetVersion, error)
+	FirstOrCreate() (*model.DatasetVersion, error)
+	FindByPage(offset int, limit int) (result []*model.DatasetVersion, count int64, err error)
+	ScanByPage(result interface{}, offset int, limit int) (count int64, err error)
+	Scan(result interface{}) (err error)
+	Returning(value interface{}, columns ...string) IDatasetVersionDo
+	UnderlyingDB() *gorm.DB
+	schema.Tabler
+}
+
+func (d datasetVersionDo) Debug() IDatasetVersionDo {
+	return d.withDO(d.DO.Debug())
+}
+
+func (d datasetVersionDo) WithContext(ctx context.Context) IDatasetVersionDo {
+	return d.withDO(d.DO.WithContext(ctx))
+}
+
+func (d datasetVersionDo) ReadDB() IDatasetVersionDo {
+	return d.Clauses(dbresolver.Read)
+}
+
+func (d datasetVersionDo) WriteDB() IDatasetVersionDo {
+	return d.Clauses(dbresolver.Write)
+}
+
+func (d datasetVersionDo) Session(config *gorm.Session) IDatasetVersionDo {
+	return d.withDO(d.DO.Session(config))
+}
+
+func (d datasetVersionDo) Clauses(conds ...clause.Expression) IDatasetVersionDo {
+	return d.withDO(d.DO.Clauses(conds...))
+}
+
+func (d datasetVersionDo) Returning(value interface{}, columns ...string) IDatasetVersionDo {
+	return d.withDO(d.DO.Returning(value, columns...))
+}
+
+func (d datasetVersionDo) Not(conds ...gen.Condition) IDatasetVersionDo {
+	return d.withDO(d.DO.Not(conds...))
+}
+
+func (d datasetVersionDo) Or(conds ...gen.Condition) IDatasetVersionDo {
+	return d.withDO(d.DO.Or(conds...))
+}
+
+func (d datasetVersionDo) Select(conds ...field.Expr) IDatasetVersionDo {
+	return d.withDO(d.DO.Select(conds...))
+}
+
+func (d datasetVersionDo) Where(conds ...gen.Condition) IDatasetVersionDo {
+	return d.withDO(d.DO.Where(conds...))
+}
+
+func (d datasetVersionDo) Order(conds ...field.Expr) IDatasetVersionDo {
+	return d.withDO(d.DO.Order(conds...))
+}
+
+func (d datasetVersionDo) Distinct(cols ...field.Expr) IDatasetVersionDo {
+	return d.withDO(d.DO.Distinct(cols...))
+}
+
+func (d datasetVersionDo) Omit(cols ...field.Expr) IDatasetVersionDo {
+	return d.withDO(d.DO.Omit(cols...))
+}
+
+func (d datasetVersionDo) Join(table schema.Tabler, on ...field.Expr) IDatasetVersionDo {
+	return d.withDO(d.DO.Join(table, on...))
+}
+
+func (d datasetVersionDo) LeftJoin(table schema.Tabler, on ...field.Expr) IDatasetVersionDo {
+	return d.withDO(d.DO.LeftJoin(table, on...))
+}
+
+func (d datasetVersionDo) RightJoin(table schema.Tabler, on ...field.Expr) IDatasetVersionDo {
+	return d.withDO(d.DO.RightJoin(table, on...))
+}
+
+func (d datasetVersionDo) Group(cols ...field.Expr) IDatasetVersionDo {
+	return d.withDO(d.DO.Group(cols...))
+}
+
+func (d datasetVersionDo) Having(conds ...gen.Condition) IDatasetVersionDo {
+	return d.withDO(d.DO.Having(conds...))
+}
+
+func (d datasetVersionDo) Limit(limit int) IDatasetVersionDo {
+	return d.withDO(d.DO.Limit(limit))
+}
+
+func (d datasetVersionDo) Offset(offset int) IDatasetVersionDo {
+	return d.withDO(d.DO.Offset(offset))
+}
+
+func (d datasetVersionDo) Scopes(funcs ...func(gen.Dao) gen.Dao) IDatasetVersionDo {
+	return d.withDO(d.DO.Scopes(funcs...))
+}
+
+func (d datasetVersionDo) Unscoped() IDatasetVersionDo {
+	return d.withDO(d.DO.Unscoped())
+}
+
+func (d datasetVersionDo) Create(values ...*model.DatasetVersion) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return d.DO.Create(values)
+}
+
+func (d datasetVersionDo) CreateInBatches(values []*model.DatasetVersion, batchSize int) error {
+	return d.DO.CreateInBatches(values, batchSize)
+}
+
+// Save : !!! underlying implementation is different with GORM
+// The method is equivalent to executing the statement: db.Clauses(clause.OnConflict{UpdateAll: true}).Create(values)
+func (d datasetVersionDo) Save(values ...*model.DatasetVersion) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return d.DO.Save(values)
+}
+
+func (d datasetVersionDo) First() (*model.DatasetVersion, error) {
+	if result, err := d.DO.First(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DatasetVersion), nil
+	}
+}
+
+func (d datasetVersionDo) Take() (*model.DatasetVersion, error) {
+	if result, err := d.DO.Take(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DatasetVersion), nil
+	}
+}
+
+func (d datasetVersionDo) Last() (*model.DatasetVersion, error) {
+	if result, err := d.DO.Last(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DatasetVersion), nil
+	}
+}
+
+func (d datasetVersionDo) Find() ([]*model.DatasetVersion, error) {
+	result, err := d.DO.Find()
+	return result.([]*model.DatasetVersion), err
+}
+
+func (d datasetVersionDo) FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.DatasetVersion, err error) {
+	buf := make([]*model.DatasetVersion, 0, batchSize)
+	err = d.DO.FindInBatches(&buf, batchSize, func(tx gen.Dao, batch int) error {
+		defer func() { results = append(results, buf...) }()
+		return fc(tx, batch)
+	})
+	return results, err
+}
+
+func (d datasetVersionDo) FindInBatches(result *[]*model.DatasetVersion, batchSize int, fc func(tx gen.Dao, batch int) error) error {
+	return d.DO.FindInBatches(result, batchSize, fc)
+}
+
+func (d datasetVersionDo) Attrs(attrs ...field.AssignExpr) IDatasetVersionDo {
+	return d.withDO(d.DO.Attrs(attrs...))
+}
+
+func (d datasetVersionDo) Assign(attrs ...field.AssignExpr) IDatasetVersionDo {
+	return d.withDO(d.DO.Assign(attrs...))
+}
+
+func (d datasetVersionDo) Joins(fields ...field.RelationField) IDatasetVersionDo {
+	for _, _f := range fields {
+		d = *d.withDO(d.DO.Joins(_f))
+	}
+	return &d
+}
+
+func (d datasetVersionDo) Preload(fields ...field.RelationField) IDatasetVersionDo {
+	for _, _f := range fields {
+		d = *d.withDO(d.DO.Preload(_f))
+	}
+	return &d
+}
+
+func (d datasetVersionDo) FirstOrInit() (*model.DatasetVersion, error) {
+	if result, err := d.DO.FirstOrInit(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DatasetVersion), nil
+	}
+}
+
+func (d datasetVersionDo) FirstOrCreate() (*model.DatasetVersion, error) {
+	if result, err := d.DO.FirstOrCreate(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.DatasetVersion), nil
+	}
+}
+
+func (d datasetVersionDo) FindByPage(offset int, limit int) (result []*model.DatasetVersion, count int64, err error) {
+	result, err = d.Offset(offset).Limit(limit).Find()
+	if err != nil {
+		return
+	}
+
+	if size := len(result); 0 < limit && 0 < size && size < limit {
+		count = int64(size + offset)
+		return
+	}
+
+	count, err = d.Offset(-1).Limit(-1).Count()
+	return
+}
+
+func (d datasetVersionDo) ScanByPage(result interface{}, offset int, limit int) (count int64, err error) {
+	count, err = d.Count()
+	if err != nil {
+		return
+	}
+
+	err = d.Offset(offset).Limit(limit).Scan(result)
+	return
+}
+
+func (d datasetVersionDo) Scan(result interface{}) (err error) {
+	return d.DO.Scan(result)
+}
+
+func (d datasetVersionDo) Delete(models ...*model.DatasetVersion) (result gen.ResultInfo, err error) {
+	return d.DO.Delete(models)
+}
+
+func (d *datasetVersionDo) withDO(do gen.Dao) *datasetVersionDo {
+	d.DO = *do.(*gen.DO)
+	return d
+}
@@ -0,0 +1,468 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package query
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"gorm.io/gen"
+	"gorm.io/gen/field"
+
+	"gorm.io/plugin/dbresolver"
+
+	"webdav/dao/model"
+)
+
+func newFileJob(db *gorm.DB, opts ...gen.DOOption) fileJob {
+	_fileJob := fileJob{}
+
+	_fileJob.fileJobDo.UseDB(db, opts...)
+	_fileJob.fileJobDo.UseModel(&model.FileJob{})
+
+	tableName := _fileJob.fileJobDo.TableName()
+	_fileJob.ALL = field.NewAsterisk(tableName)
+	_fileJob.ID = field.NewUint(tableName, "id")
+	_fileJob.CreatedAt = field.NewTime(tableName, "created_at")
+	_fileJob.UpdatedAt = field.NewTime(tableName, "updated_at")
+	_fileJob.DeletedAt = field.NewField(tableName, "deleted_at")
+	_fileJob.UserID = field.NewUint(tableName, "user_id")
+	_fileJob.Type = field.NewString(tableName, "type")
+	_fileJob.Status = field.NewString(tableName, "status")
+	_fileJob.Params = field.NewField(tableName, "params")
+	_fileJob.Result = field.NewString(tableName, "result")
+	_fileJob.Message = field.NewString(tableName, "message")
+	_fileJob.Attempts = field.NewInt(tableName, "attempts")
+	_fileJob.RunAfter = field.NewTime(tableName, "run_after")
+	_fileJob.TotalBytes = field.NewInt64(tableName, "total_bytes")
+	_fileJob.TotalFiles = field.NewInt64(tableName, "total_files")
+	_fileJob.DoneBytes = field.NewInt64(tableName, "done_bytes")
+	_fileJob.DoneFiles = field.NewInt64(tableName, "done_files")
+	_fileJob.StartedAt = field.NewTime(tableName, "started_at")
+	_fileJob.FinishedAt = field.NewTime(tableName, "finished_at")
+	_fileJob.Owner = field.NewString(tableName, "owner")
+	_fileJob.HeartbeatAt = field.NewTime(tableName, "heartbeat_at")
+	_fileJob.CancelRequested = field.NewBool(tableName, "cancel_requested")
+
+	_fileJob.fillFieldMap()
+
+	return _fileJob
+}
+
+type fileJob struct {
+	fileJobDo fileJobDo
+
+	ALL             field.Asterisk
+	ID              field.Uint
+	CreatedAt       field.Time
+	UpdatedAt       field.Time
+	DeletedAt       field.Field
+	UserID          field.Uint
+	Type            field.String
+	Status          field.String
+	Params          field.Field
+	Result          field.String
+	Message         field.String
+	Attempts        field.Int
+	RunAfter        field.Time
+	TotalBytes      field.Int64
+	TotalFiles      field.Int64
+	DoneBytes       field.Int64
+	DoneFiles       field.Int64
+	StartedAt       field.Time
+	FinishedAt      field.Time
+	Owner           field.String
+	HeartbeatAt     field.Time
+	CancelRequested field.Bool
+
+	fieldMap map[string]field.Expr
+}
+
+func (f fileJob) Table(newTableName string) *fileJob {
+	f.fileJobDo.UseTable(newTableName)
+	return f.updateTableName(newTableName)
+}
+
+func (f fileJob) As(alias string) *fileJob {
+	f.fileJobDo.DO = *(f.fileJobDo.As(alias).(*gen.DO))
+	return f.updateTableName(alias)
+}
+
+func (f *fileJob) updateTableName(table string) *fileJob {
+	f.ALL = field.NewAsterisk(table)
+	f.ID = field.NewUint(table, "id")
+	f.CreatedAt = field.NewTime(table, "created_at")
+	f.UpdatedAt = field.NewTime(table, "updated_at")
+	f.DeletedAt = field.NewField(table, "deleted_at")
+	f.UserID = field.NewUint(table, "user_id")
+	f.Type = field.NewString(table, "type")
+	f.Status = field.NewString(table, "status")
+	f.Params = field.NewField(table, "params")
+	f.Result = field.NewString(table, "result")
+	f.Message = field.NewString(table, "message")
+	f.Attempts = field.NewInt(table, "attempts")
+	f.RunAfter = field.NewTime(table, "run_after")
+	f.TotalBytes = field.NewInt64(table, "total_bytes")
+	f.TotalFiles = field.NewInt64(table, "total_files")
+	f.DoneBytes = field.NewInt64(table, "done_bytes")
+	f.DoneFiles = field.NewInt64(table, "done_files")
+	f.StartedAt = field.NewTime(table, "started_at")
+	f.FinishedAt = field.NewTime(table, "finished_at")
+	f.Owner = field.NewString(table, "owner")
+	f.HeartbeatAt = field.NewTime(table, "heartbeat_at")
+	f.CancelRequested = field.NewBool(table, "cancel_requested")
+
+	f.fillFieldMap()
+
+	return f
+}
+
+func (f *fileJob) WithContext(ctx context.Context) IFileJobDo { return f.fileJobDo.WithContext(ctx) }
+
+func (f fileJob) TableName() string { return f.fileJobDo.TableName() }
+
+func (f fileJob) Alias() string { return f.fileJobDo.Alias() }
+
+func (f fileJob) Columns(cols ...field.Expr) gen.Columns { return f.fileJobDo.Columns(cols...) }
+
+func (f *fileJob) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
+	_f, ok := f.fieldMap[fieldName]
+	if !ok || _f == nil {
+		return nil, false
+	}
+	_oe, ok := _f.(field.OrderExpr)
+	return _oe, ok
+}
+
+func (f *fileJob) fillFieldMap() {
+	f.fieldMap = make(map[string]field.Expr, 21)
+	f.fieldMap["id"] = f.ID
+	f.fieldMap["created_at"] = f.CreatedAt
+	f.fieldMap["updated_at"] = f.UpdatedAt
+	f.fieldMap["deleted_at"] = f.DeletedAt
+	f.fieldMap["user_id"] = f.UserID
+	f.fieldMap["type"] = f.Type
+	f.fieldMap["status"] = f.Status
+	f.fieldMap["params"] = f.Params
+	f.fieldMap["result"] = f.Result
+	f.fieldMap["message"] = f.Message
+	f.fieldMap["attempts"] = f.Attempts
+	f.fieldMap["run_after"] = f.RunAfter
+	f.fieldMap["total_bytes"] = f.TotalBytes
+	f.fieldMap["total_files"] = f.TotalFiles
+	f.fieldMap["done_bytes"] = f.DoneBytes
+	f.fieldMap["done_files"] = f.DoneFiles
+	f.fieldMap["started_at"] = f.StartedAt
+	f.fieldMap["finished_at"] = f.FinishedAt
+	f.fieldMap["owner"] = f.Owner
+	f.fieldMap["heartbeat_at"] = f.HeartbeatAt
+	f.fieldMap["cancel_requested"] = f.CancelRequested
+}
+
+func (f fileJob) clone(db *gorm.DB) fileJob {
+	f.fileJobDo.ReplaceConnPool(db.Statement.ConnPool)
+	return f
+}
+
+func (f fileJob) replaceDB(db *gorm.DB) fileJob {
+	f.fileJobDo.ReplaceDB(db)
+	return f
+}
+
+type fileJobDo struct{ gen.DO }
+
+type IFileJobDo interface {
+	gen.SubQuery
+	Debug() IFileJobDo
+	WithContext(ctx context.Context) IFileJobDo
+	WithResult(fc func(tx gen.Dao)) gen.ResultInfo
+	ReplaceDB(db *gorm.DB)
+	ReadDB() IFileJobDo
+	WriteDB() IFileJobDo
+	As(alias string) gen.Dao
+	Session(config *gorm.Session) IFileJobDo
+	Columns(cols ...field.Expr) gen.Columns
+	Clauses(conds ...clause.Expression) IFileJobDo
+	Not(conds ...gen.Condition) IFileJobDo
+	Or(conds ...gen.Condition) IFileJobDo
+	Select(conds ...field.Expr) IFileJobDo
+	Where(conds ...gen.Condition) IFileJobDo
+	Order(conds ...field.Expr) IFileJobDo
+	Distinct(cols ...field.Expr) IFileJobDo
+	Omit(cols ...field.Expr) IFileJobDo
+	Join(table schema.Tabler, on ...field.Expr) IFileJobDo
+	LeftJoin(table schema.Tabler, on ...field.Expr) IFileJobDo
+	RightJoin(table schema.Tabler, on ...field.Expr) IFileJobDo
+	Group(cols ...field.Expr) IFileJobDo
+	Having(conds ...gen.Condition) IFileJobDo
+	Limit(limit int) IFileJobDo
+	Offset(offset int) IFileJobDo
+	Count() (count int64, err error)
+	Scopes(funcs ...func(gen.Dao) gen.Dao) IFileJobDo
+	Unscoped() IFileJobDo
+	Create(values ...*model.FileJob) error
+	CreateInBatches(values []*model.FileJob, batchSize int) error
+	Save(values ...*model.FileJob) error
+	First() (*model.FileJob, error)
+	Take() (*model.FileJob, error)
+	Last() (*model.FileJob, error)
+	Find() ([]*model.FileJob, error)
+	FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.FileJob, err error)
+	FindInBatches(result *[]*model.FileJob, batchSize int, fc func(tx gen.Dao, batch int) error) error
+	Pluck(column field.Expr, dest interface{}) error
+	Delete(...*model.FileJob) (info gen.ResultInfo, err error)
+	Update(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	Updates(value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumn(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumnSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	UpdateColumns(value interface{}) (info gen.ResultInfo, err error)
+	UpdateFrom(q gen.SubQuery) gen.Dao
+	Attrs(attrs ...field.AssignExpr) IFileJobDo
+	Assign(attrs ...field.AssignExpr) IFileJobDo
+	Joins(fields ...field.RelationField) IFileJobDo
+	Preload(fields ...field.RelationField) IFileJobDo
+	FirstOrInit() (*model.FileJob, error)
+	FirstOrCreate() (*model.FileJob, error)
+	FindByPage(offset int, limit int) (result []*model.FileJob, count int64, err error)
+	ScanByPage(result interface{}, offset int, limit int) (count int64, err error)
+	Scan(result interface{}) (err error)
+	Returning(value interface{}, columns ...string) IFileJobDo
+	UnderlyingDB() *gorm.DB
+	schema.Tabler
+}
+
+func (f fileJobDo) Debug() IFileJobDo {
+	return f.withDO(f.DO.Debug())
+}
+
+func (f fileJobDo) WithContext(ctx context.Context) IFileJobDo {
+	return f.withDO(f.DO.WithContext(ctx))
+}
+
+func (f fileJobDo) ReadDB() IFileJobDo {
+	return f.Clauses(dbresolver.Read)
+}
+
+func (f fileJobDo) WriteDB() IFileJobDo {
+	return f.Clauses(dbresolver.Write)
+}
+
+func (f fileJobDo) Session(config *gorm.Session) IFileJobDo {
+	return f.withDO(f.DO.Session(config))
+}
+
+func (f fileJobDo) Clauses(conds ...clause.Expression) IFileJobDo {
+	return f.withDO(f.DO.Clauses(conds...))
+}
+
+func (f fileJobDo) Returning(value interface{}, columns ...string) IFileJobDo {
+	return f.withDO(f.DO.Returning(value, columns...))
+}
+
+func (f fileJobDo) Not(conds ...gen.Condition) IFileJobDo {
+	return f.withDO(f.DO.Not(conds...))
+}
+
+func (f fileJobDo) Or(conds ...gen.Condition) IFileJobDo {
+	return f.withDO(f.DO.Or(conds...))
+}
+
+func (f fileJobDo) Select(conds ...field.Expr) IFileJobDo {
+	return f.withDO(f.DO.Select(conds...))
+}
+
+func (f fileJobDo) Where(conds ...gen.Condition) IFileJobDo {
+	return f.withDO(f.DO.Where(conds...))
+}
+
+func (f fileJobDo) Order(conds ...field.Expr) IFileJobDo {
+	return f.withDO(f.DO.Order(conds...))
+}
+
+func (f fileJobDo) Distinct(cols ...field.Expr) IFileJobDo {
+	return f.withDO(f.DO.Distinct(cols...))
+}
+
+func (f fileJobDo) Omit(cols ...field.Expr) IFileJobDo {
+	return f.withDO(f.DO.Omit(cols...))
+}
+
+func (f fileJobDo) Join(table schema.Tabler, on ...field.Expr) IFileJobDo {
+	return f.withDO(f.DO.Join(table, on...))
+}
+
+func (f fileJobDo) LeftJoin(table schema.Tabler, on ...field.Expr) IFileJobDo {
+	return f.withDO(f.DO.LeftJoin(table, on...))
+}
+
+func (f fileJobDo) RightJoin(table schema.Tabler, on ...field.Expr) IFileJobDo {
+	return f.withDO(f.DO.RightJoin(table, on...))
+}
+
+func (f fileJobDo) Group(cols ...field.Expr) IFileJobDo {
+	return f.withDO(f.DO.Group(cols...))
+}
+
+func (f fileJobDo) Having(conds ...gen.Condition) IFileJobDo {
+	return f.withDO(f.DO.Having(conds...))
+}
+
+func (f fileJobDo) Limit(limit int) IFileJobDo {
+	return f.withDO(f.DO.Limit(limit))
+}
+
+func (f fileJobDo) Offset(offset int) IFileJobDo {
+	return f.withDO(f.DO.Offset(offset))
+}
+
+func (f fileJobDo) Scopes(funcs ...func(gen.Dao) gen.Dao) IFileJobDo {
+	return f.withDO(f.DO.Scopes(funcs...))
+}
+
+func (f fileJobDo) Unscoped() IFileJobDo {
+	return f.withDO(f.DO.Unscoped())
+}
+
+func (f fileJobDo) Create(values ...*model.FileJob) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return f.DO.Create(values)
+}
+
+func (f fileJobDo) CreateInBatches(values []*model.FileJob, batchSize int) error {
+	return f.DO.CreateInBatches(values, batchSize)
+}
+
+// Save : !!! underlying implementation is different with GORM
+// The method is equivalent to executing the statement: db.Clauses(clause.OnConflict{UpdateAll: true}).Create(values)
+func (f fileJobDo) Save(values ...*model.FileJob) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return f.DO.Save(values)
+}
+
+func (f fileJobDo) First() (*model.FileJob, error) {
+	if result, err := f.DO.First(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.FileJob), nil
+	}
+}
+
+func (f fileJobDo) Take() (*model.FileJob, error) {
+	if result, err := f.DO.Take(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.FileJob), nil
+	}
+}
+
+func (f fileJobDo) Last() (*model.FileJob, error) {
+	if result, err := f.DO.Last(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.FileJob), nil
+	}
+}
+
+func (f fileJobDo) Find() ([]*model.FileJob, error) {
+	result, err := f.DO.Find()
+	return result.([]*model.FileJob), err
+}
+
+func (f fileJobDo) FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.FileJob, err error) {
+	buf := make([]*model.FileJob, 0, batchSize)
+	err = f.DO.FindInBatches(&buf, batchSize, func(tx gen.Dao, batch int) error {
+		defer func() { results = append(results, buf...) }()
+		return fc(tx, batch)
+	})
+	return results, err
+}
+
+func (f fileJobDo) FindInBatches(result *[]*model.FileJob, batchSize int, fc func(tx gen.Dao, batch int) error) error {
+	return f.DO.FindInBatches(result, batchSize, fc)
+}
+
+func (f fileJobDo) Attrs(attrs ...field.AssignExpr) IFileJobDo {
+	return f.withDO(f.DO.Attrs(attrs...))
+}
+
+func (f fileJobDo) Assign(attrs ...field.AssignExpr) IFileJobDo {
+	return f.withDO(f.DO.Assign(attrs...))
+}
+
+func (f fileJobDo) Joins(fields ...field.RelationField) IFileJobDo {
+	for _, _f := range fields {
+		f = *f.withDO(f.DO.Joins(_f))
+	}
+	return &f
+}
+
+func (f fileJobDo) Preload(fields ...field.RelationField) IFileJobDo {
+	for _, _f := range fields {
+		f = *f.withDO(f.DO.Preload(_f))
+	}
+	return &f
+}
+
+func (f fileJobDo) FirstOrInit() (*model.FileJob, error) {
+	if result, err := f.DO.FirstOrInit(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.FileJob), nil
+	}
+}
+
+func (f fileJobDo) FirstOrCreate() (*model.FileJob, error) {
+	if result, err := f.DO.FirstOrCreate(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.FileJob), nil
+	}
+}
+
+func (f fileJobDo) FindByPage(offset int, limit int) (result []*model.FileJob, count int64, err error) {
+	result, err = f.Offset(offset).Limit(limit).Find()
+	if err != nil {
+		return
+	}
+
+	if size := len(result); 0 < limit && 0 < size && size < limit {
+		count = int64(size + offset)
+		return
+	}
+
+	count, err = f.Offset(-1).Limit(-1).Count()
+	return
+}
+
+func (f fileJobDo) ScanByPage(result interface{}, offset int, limit int) (count int64, err error) {
+	count, err = f.Count()
+	if err != nil {
+		return
+	}
+
+	err = f.Offset(offset).Limit(limit).Scan(result)
+	return
+}
+
+func (f fileJobDo) Scan(result interface{}) (err error) {
+	return f.DO.Scan(result)
+}
+
+func (f fileJobDo) Delete(models ...*model.FileJob) (result gen.ResultInfo, err error) {
+	return f.DO.Delete(models)
+}
+
+func (f *fileJobDo) withDO(do gen.Dao) *fileJobDo {
+	f.DO = *do.(*gen.DO)
+	return f
+}
@@ -16,20 +16,42 @@ import (
 )
 
 var (
-	Q              = new(Query)
-	Account        *account
-	AccountDataset *accountDataset
-	Dataset        *dataset
-	User           *user
-	UserAccount    *userAccount
-	UserDataset    *userDataset
+	Q                  = new(Query)
+	APIKey             *aPIKey
+	Account            *account
+	AccountDataset     *accountDataset
+	Dataset            *dataset
+	DatasetChecksum    *datasetChecksum
+	DatasetManifest    *datasetManifest
+	DatasetVersion     *datasetVersion
+	DatasetVersionFile *datasetVersionFile
+	FileIndex          *fileIndex
+	FileJob            *fileJob
+	RevokedToken       *revokedToken
+	ShareLink          *shareLink
+	SpaceUsage         *spaceUsage
+	TrashItem          *trashItem
+	User               *user
+	UserAccount        *userAccount
+	UserDataset        *userDataset
 )
 
 func SetDefault(db *gorm.DB, opts ...gen.DOOption) {
 	*Q = *Use(db, opts...)
+	APIKey = &Q.APIKey
 	Account = &Q.Account
 	AccountDataset = &Q.AccountDataset
 	Dataset = &Q.Dataset
+	DatasetChecksum = &Q.DatasetChecksum
+	DatasetManifest = &Q.DatasetManifest
+	DatasetVersion = &Q.DatasetVersion
+	DatasetVersionFile = &Q.DatasetVersionFile
+	FileIndex = &Q.FileIndex
+	FileJob = &Q.FileJob
+	RevokedToken = &Q.RevokedToken
+	ShareLink = &Q.ShareLink
+	SpaceUsage = &Q.SpaceUsage
+	TrashItem = &Q.TrashItem
 	User = &Q.User
 	UserAccount = &Q.UserAccount
 	UserDataset = &Q.UserDataset
@@ -37,38 +59,71 @@ func SetDefault(db *gorm.DB, opts ...gen.DOOption) {
 
 func Use(db *gorm.DB, opts ...gen.DOOption) *Query {
 	return &Query{
-		db:             db,
-		Account:        newAccount(db, opts...),
-		AccountDataset: newAccountDataset(db, opts...),
-		Dataset:        newDataset(db, opts...),
-		User:           newUser(db, opts...),
-		UserAccount:    newUserAccount(db, opts...),
-		UserDataset:    newUserDataset(db, opts...),
+		db:                 db,
+		APIKey:             newAPIKey(db, opts...),
+		Account:            newAccount(db, opts...),
+		AccountDataset:     newAccountDataset(db, opts...),
+		Dataset:            newDataset(db, opts...),
+		DatasetChecksum:    newDatasetChecksum(db, opts...),
+		DatasetManifest:    newDatasetManifest(db, opts...),
+		DatasetVersion:     newDatasetVersion(db, opts...),
+		DatasetVersionFile: newDatasetVersionFile(db, opts...),
+		FileIndex:          newFileIndex(db, opts...),
+		FileJob:            newFileJob(db, opts...),
+		RevokedToken:       newRevokedToken(db, opts...),
+		ShareLink:          newShareLink(db, opts...),
+		SpaceUsage:         newSpaceUsage(db, opts...),
+		TrashItem:          newTrashItem(db, opts...),
+		User:               newUser(db, opts...),
+		UserAccount:        newUserAccount(db, opts...),
+		UserDataset:        newUserDataset(db, opts...),
 	}
 }
 
 type Query struct {
 	db *gorm.DB
 
-	Account        account
-	AccountDataset accountDataset
-	Dataset        dataset
-	User           user
-	UserAccount    userAccount
-	UserDataset    userDataset
+	APIKey             aPIKey
+	Account            account
+	AccountDataset     accountDataset
+	Dataset            dataset
+	DatasetChecksum    datasetChecksum
+	DatasetManifest    datasetManifest
+	DatasetVersion     datasetVersion
+	DatasetVersionFile datasetVersionFile
+	FileIndex          fileIndex
+	FileJob            fileJob
+	RevokedToken       revokedToken
+	ShareLink          shareLink
+	SpaceUsage         spaceUsage
+	TrashItem          trashItem
+	User               user
+	UserAccount        userAccount
+	UserDataset        userDataset
 }
 
 func (q *Query) Available() bool { return q.db != nil }
 
 func (q *Query) clone(db *gorm.DB) *Query {
 	return &Query{
-		db:             db,
-		Account:        q.Account.clone(db),
-		AccountDataset: q.AccountDataset.clone(db),
-		Dataset:        q.Dataset.clone(db),
-		User:           q.User.clone(db),
-		UserAccount:    q.UserAccount.clone(db),
-		UserDataset:    q.UserDataset.clone(db),
+		db:                 db,
+		APIKey:             q.APIKey.clone(db),
+		Account:            q.Account.clone(db),
+		AccountDataset:     q.AccountDataset.clone(db),
+		Dataset:            q.Dataset.clone(db),
+		DatasetChecksum:    q.DatasetChecksum.clone(db),
+		DatasetManifest:    q.DatasetManifest.clone(db),
+		DatasetVersion:     q.DatasetVersion.clone(db),
+		DatasetVersionFile: q.DatasetVersionFile.clone(db),
+		FileIndex:          q.FileIndex.clone(db),
+		FileJob:            q.FileJob.clone(db),
+		RevokedToken:       q.RevokedToken.clone(db),
+		ShareLink:          q.ShareLink.clone(db),
+		SpaceUsage:         q.SpaceUsage.clone(db),
+		TrashItem:          q.TrashItem.clone(db),
+		User:               q.User.clone(db),
+		UserAccount:        q.UserAccount.clone(db),
+		UserDataset:        q.UserDataset.clone(db),
 	}
 }
 
@@ -82,33 +137,66 @@ func (q *Query) WriteDB() *Query {
 
 func (q *Query) ReplaceDB(db *gorm.DB) *Query {
 	return &Query{
-		db:             db,
-		Account:        q.Account.replaceDB(db),
-		AccountDataset: q.AccountDataset.replaceDB(db),
-		Dataset:        q.Dataset.replaceDB(db),
-		User:           q.User.replaceDB(db),
-		UserAccount:    q.UserAccount.replaceDB(db),
-		UserDataset:    q.UserDataset.replaceDB(db),
+		db:                 db,
+		APIKey:             q.APIKey.replaceDB(db),
+		Account:            q.Account.replaceDB(db),
+		AccountDataset:     q.AccountDataset.replaceDB(db),
+		Dataset:            q.Dataset.replaceDB(db),
+		DatasetChecksum:    q.DatasetChecksum.replaceDB(db),
+		DatasetManifest:    q.DatasetManifest.replaceDB(db),
+		DatasetVersion:     q.DatasetVersion.replaceDB(db),
+		DatasetVersionFile: q.DatasetVersionFile.replaceDB(db),
+		FileIndex:          q.FileIndex.replaceDB(db),
+		FileJob:            q.FileJob.replaceDB(db),
+		RevokedToken:       q.RevokedToken.replaceDB(db),
+		ShareLink:          q.ShareLink.replaceDB(db),
+		SpaceUsage:         q.SpaceUsage.replaceDB(db),
+		TrashItem:          q.TrashItem.replaceDB(db),
+		User:               q.User.replaceDB(db),
+		UserAccount:        q.UserAccount.replaceDB(db),
+		UserDataset:        q.UserDataset.replaceDB(db),
 	}
 }
 
 type queryCtx struct {
-	Account        IAccountDo
-	AccountDataset IAccountDatasetDo
-	Dataset        IDatasetDo
-	User           IUserDo
-	UserAccount    IUserAccountDo
-	UserDataset    IUserDatasetDo
+	APIKey             IAPIKeyDo
+	Account            IAccountDo
+	AccountDataset     IAccountDatasetDo
+	Dataset            IDatasetDo
+	DatasetChecksum    IDatasetChecksumDo
+	DatasetManifest    IDatasetManifestDo
+	DatasetVersion     IDatasetVersionDo
+	DatasetVersionFile IDatasetVersionFileDo
+	FileIndex          IFileIndexDo
+	FileJob            IFileJobDo
+	RevokedToken       IRevokedTokenDo
+	ShareLink          IShareLinkDo
+	SpaceUsage         ISpaceUsageDo
+	TrashItem          ITrashItemDo
+	User               IUserDo
+	UserAccount        IUserAccountDo
+	UserDataset        IUserDatasetDo
 }
 
 func (q *Query) WithContext(ctx context.Context) *queryCtx {
 	return &queryCtx{
-		Account:        q.Account.WithContext(ctx),
-		AccountDataset: q.AccountDataset.WithContext(ctx),
-		Dataset:        q.Dataset.WithContext(ctx),
-		User:           q.User.WithContext(ctx),
-		UserAccount:    q.UserAccount.WithContext(ctx),
-		UserDataset:    q.UserDataset.WithContext(ctx),
+		APIKey:             q.APIKey.WithContext(ctx),
+		Account:            q.Account.WithContext(ctx),
+		AccountDataset:     q.AccountDataset.WithContext(ctx),
+		Dataset:            q.Dataset.WithContext(ctx),
+		DatasetChecksum:    q.DatasetChecksum.WithContext(ctx),
+		DatasetManifest:    q.DatasetManifest.WithContext(ctx),
+		DatasetVersion:     q.DatasetVersion.WithContext(ctx),
+		DatasetVersionFile: q.DatasetVersionFile.WithContext(ctx),
+		FileIndex:          q.FileIndex.WithContext(ctx),
+		FileJob:            q.FileJob.WithContext(ctx),
+		RevokedToken:       q.RevokedToken.WithContext(ctx),
+		ShareLink:          q.ShareLink.WithContext(ctx),
+		SpaceUsage:         q.SpaceUsage.WithContext(ctx),
+		TrashItem:          q.TrashItem.WithContext(ctx),
+		User:               q.User.WithContext(ctx),
+		UserAccount:        q.UserAccount.WithContext(ctx),
+		UserDataset:        q.UserDataset.WithContext(ctx),
 	}
 }
 
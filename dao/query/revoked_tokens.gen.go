@@ -0,0 +1,408 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package query
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"gorm.io/gen"
+	"gorm.io/gen/field"
+
+	"gorm.io/plugin/dbresolver"
+
+	"webdav/dao/model"
+)
+
+func newRevokedToken(db *gorm.DB, opts ...gen.DOOption) revokedToken {
+	_revokedToken := revokedToken{}
+
+	_revokedToken.revokedTokenDo.UseDB(db, opts...)
+	_revokedToken.revokedTokenDo.UseModel(&model.RevokedToken{})
+
+	tableName := _revokedToken.revokedTokenDo.TableName()
+	_revokedToken.ALL = field.NewAsterisk(tableName)
+	_revokedToken.ID = field.NewUint(tableName, "id")
+	_revokedToken.CreatedAt = field.NewTime(tableName, "created_at")
+	_revokedToken.JTI = field.NewString(tableName, "jti")
+	_revokedToken.UserID = field.NewUint(tableName, "user_id")
+	_revokedToken.ExpiresAt = field.NewTime(tableName, "expires_at")
+
+	_revokedToken.fillFieldMap()
+
+	return _revokedToken
+}
+
+type revokedToken struct {
+	revokedTokenDo revokedTokenDo
+
+	ALL       field.Asterisk
+	ID        field.Uint
+	CreatedAt field.Time
+	JTI       field.String
+	UserID    field.Uint
+	ExpiresAt field.Time
+
+	fieldMap map[string]field.Expr
+}
+
+func (r revokedToken) Table(newTableName string) *revokedToken {
+	r.revokedTokenDo.UseTable(newTableName)
+	return r.updateTableName(newTableName)
+}
+
+func (r revokedToken) As(alias string) *revokedToken {
+	r.revokedTokenDo.DO = *(r.revokedTokenDo.As(alias).(*gen.DO))
+	return r.updateTableName(alias)
+}
+
+func (r *revokedToken) updateTableName(table string) *revokedToken {
+	r.ALL = field.NewAsterisk(table)
+	r.ID = field.NewUint(table, "id")
+	r.CreatedAt = field.NewTime(table, "created_at")
+	r.JTI = field.NewString(table, "jti")
+	r.UserID = field.NewUint(table, "user_id")
+	r.ExpiresAt = field.NewTime(table, "expires_at")
+
+	r.fillFieldMap()
+
+	return r
+}
+
+func (r *revokedToken) WithContext(ctx context.Context) IRevokedTokenDo {
+	return r.revokedTokenDo.WithContext(ctx)
+}
+
+func (r revokedToken) TableName() string { return r.revokedTokenDo.TableName() }
+
+func (r revokedToken) Alias() string { return r.revokedTokenDo.Alias() }
+
+func (r revokedToken) Columns(cols ...field.Expr) gen.Columns {
+	return r.revokedTokenDo.Columns(cols...)
+}
+
+func (r *revokedToken) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
+	_f, ok := r.fieldMap[fieldName]
+	if !ok || _f == nil {
+		return nil, false
+	}
+	_oe, ok := _f.(field.OrderExpr)
+	return _oe, ok
+}
+
+func (r *revokedToken) fillFieldMap() {
+	r.fieldMap = make(map[string]field.Expr, 5)
+	r.fieldMap["id"] = r.ID
+	r.fieldMap["created_at"] = r.CreatedAt
+	r.fieldMap["jti"] = r.JTI
+	r.fieldMap["user_id"] = r.UserID
+	r.fieldMap["expires_at"] = r.ExpiresAt
+}
+
+func (r revokedToken) clone(db *gorm.DB) revokedToken {
+	r.revokedTokenDo.ReplaceConnPool(db.Statement.ConnPool)
+	return r
+}
+
+func (r revokedToken) replaceDB(db *gorm.DB) revokedToken {
+	r.revokedTokenDo.ReplaceDB(db)
+	return r
+}
+
+type revokedTokenDo struct{ gen.DO }
+
+type IRevokedTokenDo interface {
+	gen.SubQuery
+	Debug() IRevokedTokenDo
+	WithContext(ctx context.Context) IRevokedTokenDo
+	WithResult(fc func(tx gen.Dao)) gen.ResultInfo
+	ReplaceDB(db *gorm.DB)
+	ReadDB() IRevokedTokenDo
+	WriteDB() IRevokedTokenDo
+	As(alias string) gen.Dao
+	Session(config *gorm.Session) IRevokedTokenDo
+	Columns(cols ...field.Expr) gen.Columns
+	Clauses(conds ...clause.Expression) IRevokedTokenDo
+	Not(conds ...gen.Condition) IRevokedTokenDo
+	Or(conds ...gen.Condition) IRevokedTokenDo
+	Select(conds ...field.Expr) IRevokedTokenDo
+	Where(conds ...gen.Condition) IRevokedTokenDo
+	Order(conds ...field.Expr) IRevokedTokenDo
+	Distinct(cols ...field.Expr) IRevokedTokenDo
+	Omit(cols ...field.Expr) IRevokedTokenDo
+	Join(table schema.Tabler, on ...field.Expr) IRevokedTokenDo
+	LeftJoin(table schema.Tabler, on ...field.Expr) IRevokedTokenDo
+	RightJoin(table schema.Tabler, on ...field.Expr) IRevokedTokenDo
+	Group(cols ...field.Expr) IRevokedTokenDo
+	Having(conds ...gen.Condition) IRevokedTokenDo
+	Limit(limit int) IRevokedTokenDo
+	Offset(offset int) IRevokedTokenDo
+	Count() (count int64, err error)
+	Scopes(funcs ...func(gen.Dao) gen.Dao) IRevokedTokenDo
+	Unscoped() IRevokedTokenDo
+	Create(values ...*model.RevokedToken) error
+	CreateInBatches(values []*model.RevokedToken, batchSize int) error
+	Save(values ...*model.RevokedToken) error
+	First() (*model.RevokedToken, error)
+	Take() (*model.RevokedToken, error)
+	Last() (*model.RevokedToken, error)
+	Find() ([]*model.RevokedToken, error)
+	FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.RevokedToken, err error)
+	FindInBatches(result *[]*model.RevokedToken, batchSize int, fc func(tx gen.Dao, batch int) error) error
+	Pluck(column field.Expr, dest interface{}) error
+	Delete(...*model.RevokedToken) (info gen.ResultInfo, err error)
+	Update(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	Updates(value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumn(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumnSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	UpdateColumns(value interface{}) (info gen.ResultInfo, err error)
+	UpdateFrom(q gen.SubQuery) gen.Dao
+	Attrs(attrs ...field.AssignExpr) IRevokedTokenDo
+	Assign(attrs ...field.AssignExpr) IRevokedTokenDo
+	Joins(fields ...field.RelationField) IRevokedTokenDo
+	Preload(fields ...field.RelationField) IRevokedTokenDo
+	FirstOrInit() (*model.RevokedToken, error)
+	FirstOrCreate() (*model.RevokedToken, error)
+	FindByPage(offset int, limit int) (result []*model.RevokedToken, count int64, err error)
+	ScanByPage(result interface{}, offset int, limit int) (count int64, err error)
+	Scan(result interface{}) (err error)
+	Returning(value interface{}, columns ...string) IRevokedTokenDo
+	UnderlyingDB() *gorm.DB
+	schema.Tabler
+}
+
+func (r revokedTokenDo) Debug() IRevokedTokenDo {
+	return r.withDO(r.DO.Debug())
+}
+
+func (r revokedTokenDo) WithContext(ctx context.Context) IRevokedTokenDo {
+	return r.withDO(r.DO.WithContext(ctx))
+}
+
+func (r revokedTokenDo) ReadDB() IRevokedTokenDo {
+	return r.Clauses(dbresolver.Read)
+}
+
+func (r revokedTokenDo) WriteDB() IRevokedTokenDo {
+	return r.Clauses(dbresolver.Write)
+}
+
+func (r revokedTokenDo) Session(config *gorm.Session) IRevokedTokenDo {
+	return r.withDO(r.DO.Session(config))
+}
+
+func (r revokedTokenDo) Clauses(conds ...clause.Expression) IRevokedTokenDo {
+	return r.withDO(r.DO.Clauses(conds...))
+}
+
+func (r revokedTokenDo) Returning(value interface{}, columns ...string) IRevokedTokenDo {
+	return r.withDO(r.DO.Returning(value, columns...))
+}
+
+func (r revokedTokenDo) Not(conds ...gen.Condition) IRevokedTokenDo {
+	return r.withDO(r.DO.Not(conds...))
+}
+
+func (r revokedTokenDo) Or(conds ...gen.Condition) IRevokedTokenDo {
+	return r.withDO(r.DO.Or(conds...))
+}
+
+func (r revokedTokenDo) Select(conds ...field.Expr) IRevokedTokenDo {
+	return r.withDO(r.DO.Select(conds...))
+}
+
+func (r revokedTokenDo) Where(conds ...gen.Condition) IRevokedTokenDo {
+	return r.withDO(r.DO.Where(conds...))
+}
+
+func (r revokedTokenDo) Order(conds ...field.Expr) IRevokedTokenDo {
+	return r.withDO(r.DO.Order(conds...))
+}
+
+func (r revokedTokenDo) Distinct(cols ...field.Expr) IRevokedTokenDo {
+	return r.withDO(r.DO.Distinct(cols...))
+}
+
+func (r revokedTokenDo) Omit(cols ...field.Expr) IRevokedTokenDo {
+	return r.withDO(r.DO.Omit(cols...))
+}
+
+func (r revokedTokenDo) Join(table schema.Tabler, on ...field.Expr) IRevokedTokenDo {
+	return r.withDO(r.DO.Join(table, on...))
+}
+
+func (r revokedTokenDo) LeftJoin(table schema.Tabler, on ...field.Expr) IRevokedTokenDo {
+	return r.withDO(r.DO.LeftJoin(table, on...))
+}
+
+func (r revokedTokenDo) RightJoin(table schema.Tabler, on ...field.Expr) IRevokedTokenDo {
+	return r.withDO(r.DO.RightJoin(table, on...))
+}
+
+func (r revokedTokenDo) Group(cols ...field.Expr) IRevokedTokenDo {
+	return r.withDO(r.DO.Group(cols...))
+}
+
+func (r revokedTokenDo) Having(conds ...gen.Condition) IRevokedTokenDo {
+	return r.withDO(r.DO.Having(conds...))
+}
+
+func (r revokedTokenDo) Limit(limit int) IRevokedTokenDo {
+	return r.withDO(r.DO.Limit(limit))
+}
+
+func (r revokedTokenDo) Offset(offset int) IRevokedTokenDo {
+	return r.withDO(r.DO.Offset(offset))
+}
+
+func (r revokedTokenDo) Scopes(funcs ...func(gen.Dao) gen.Dao) IRevokedTokenDo {
+	return r.withDO(r.DO.Scopes(funcs...))
+}
+
+func (r revokedTokenDo) Unscoped() IRevokedTokenDo {
+	return r.withDO(r.DO.Unscoped())
+}
+
+func (r revokedTokenDo) Create(values ...*model.RevokedToken) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return r.DO.Create(values)
+}
+
+func (r revokedTokenDo) CreateInBatches(values []*model.RevokedToken, batchSize int) error {
+	return r.DO.CreateInBatches(values, batchSize)
+}
+
+// Save : !!! underlying implementation is different with GORM
+// The method is equivalent to executing the statement: db.Clauses(clause.OnConflict{UpdateAll: true}).Create(values)
+func (r revokedTokenDo) Save(values ...*model.RevokedToken) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return r.DO.Save(values)
+}
+
+func (r revokedTokenDo) First() (*model.RevokedToken, error) {
+	if result, err := r.DO.First(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.RevokedToken), nil
+	}
+}
+
+func (r revokedTokenDo) Take() (*model.RevokedToken, error) {
+	if result, err := r.DO.Take(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.RevokedToken), nil
+	}
+}
+
+func (r revokedTokenDo) Last() (*model.RevokedToken, error) {
+	if result, err := r.DO.Last(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.RevokedToken), nil
+	}
+}
+
+func (r revokedTokenDo) Find() ([]*model.RevokedToken, error) {
+	result, err := r.DO.Find()
+	return result.([]*model.RevokedToken), err
+}
+
+func (r revokedTokenDo) FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.RevokedToken, err error) {
+	buf := make([]*model.RevokedToken, 0, batchSize)
+	err = r.DO.FindInBatches(&buf, batchSize, func(tx gen.Dao, batch int) error {
+		defer func() { results = append(results, buf...) }()
+		return fc(tx, batch)
+	})
+	return results, err
+}
+
+func (r revokedTokenDo) FindInBatches(result *[]*model.RevokedToken, batchSize int, fc func(tx gen.Dao, batch int) error) error {
+	return r.DO.FindInBatches(result, batchSize, fc)
+}
+
+func (r revokedTokenDo) Attrs(attrs ...field.AssignExpr) IRevokedTokenDo {
+	return r.withDO(r.DO.Attrs(attrs...))
+}
+
+func (r revokedTokenDo) Assign(attrs ...field.AssignExpr) IRevokedTokenDo {
+	return r.withDO(r.DO.Assign(attrs...))
+}
+
+func (r revokedTokenDo) Joins(fields ...field.RelationField) IRevokedTokenDo {
+	for _, _f := range fields {
+		r = *r.withDO(r.DO.Joins(_f))
+	}
+	return &r
+}
+
+func (r revokedTokenDo) Preload(fields ...field.RelationField) IRevokedTokenDo {
+	for _, _f := range fields {
+		r = *r.withDO(r.DO.Preload(_f))
+	}
+	return &r
+}
+
+func (r revokedTokenDo) FirstOrInit() (*model.RevokedToken, error) {
+	if result, err := r.DO.FirstOrInit(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.RevokedToken), nil
+	}
+}
+
+func (r revokedTokenDo) FirstOrCreate() (*model.RevokedToken, error) {
+	if result, err := r.DO.FirstOrCreate(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.RevokedToken), nil
+	}
+}
+
+func (r revokedTokenDo) FindByPage(offset int, limit int) (result []*model.RevokedToken, count int64, err error) {
+	result, err = r.Offset(offset).Limit(limit).Find()
+	if err != nil {
+		return
+	}
+
+	if size := len(result); 0 < limit && 0 < size && size < limit {
+		count = int64(size + offset)
+		return
+	}
+
+	count, err = r.Offset(-1).Limit(-1).Count()
+	return
+}
+
+func (r revokedTokenDo) ScanByPage(result interface{}, offset int, limit int) (count int64, err error) {
+	count, err = r.Count()
+	if err != nil {
+		return
+	}
+
+	err = r.Offset(offset).Limit(limit).Scan(result)
+	return
+}
+
+func (r revokedTokenDo) Scan(result interface{}) (err error) {
+	return r.DO.Scan(result)
+}
+
+func (r revokedTokenDo) Delete(models ...*model.RevokedToken) (result gen.ResultInfo, err error) {
+	return r.DO.Delete(models)
+}
+
+func (r *revokedTokenDo) withDO(do gen.Dao) *revokedTokenDo {
+	r.DO = *do.(*gen.DO)
+	return r
+}
@@ -0,0 +1,446 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package query
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"gorm.io/gen"
+	"gorm.io/gen/field"
+
+	"gorm.io/plugin/dbresolver"
+
+	"webdav/dao/model"
+)
+
+func newShareLink(db *gorm.DB, opts ...gen.DOOption) shareLink {
+	_shareLink := shareLink{}
+
+	_shareLink.shareLinkDo.UseDB(db, opts...)
+	_shareLink.shareLinkDo.UseModel(&model.ShareLink{})
+
+	tableName := _shareLink.shareLinkDo.TableName()
+	_shareLink.ALL = field.NewAsterisk(tableName)
+	_shareLink.ID = field.NewUint(tableName, "id")
+	_shareLink.CreatedAt = field.NewTime(tableName, "created_at")
+	_shareLink.UpdatedAt = field.NewTime(tableName, "updated_at")
+	_shareLink.DeletedAt = field.NewField(tableName, "deleted_at")
+	_shareLink.Token = field.NewString(tableName, "token")
+	_shareLink.UserID = field.NewUint(tableName, "user_id")
+	_shareLink.AccountID = field.NewUint(tableName, "account_id")
+	_shareLink.Path = field.NewString(tableName, "path")
+	_shareLink.RealPath = field.NewString(tableName, "real_path")
+	_shareLink.IsDir = field.NewBool(tableName, "is_dir")
+	_shareLink.Mode = field.NewString(tableName, "mode")
+	_shareLink.PasswordHash = field.NewString(tableName, "password_hash")
+	_shareLink.ExpiresAt = field.NewTime(tableName, "expires_at")
+	_shareLink.MaxDownloads = field.NewInt64(tableName, "max_downloads")
+	_shareLink.Downloads = field.NewInt64(tableName, "downloads")
+
+	_shareLink.fillFieldMap()
+
+	return _shareLink
+}
+
+type shareLink struct {
+	shareLinkDo shareLinkDo
+
+	ALL          field.Asterisk
+	ID           field.Uint
+	CreatedAt    field.Time
+	UpdatedAt    field.Time
+	DeletedAt    field.Field
+	Token        field.String
+	UserID       field.Uint
+	AccountID    field.Uint
+	Path         field.String
+	RealPath     field.String
+	IsDir        field.Bool
+	Mode         field.String
+	PasswordHash field.String
+	ExpiresAt    field.Time
+	MaxDownloads field.Int64
+	Downloads    field.Int64
+
+	fieldMap map[string]field.Expr
+}
+
+func (s shareLink) Table(newTableName string) *shareLink {
+	s.shareLinkDo.UseTable(newTableName)
+	return s.updateTableName(newTableName)
+}
+
+func (s shareLink) As(alias string) *shareLink {
+	s.shareLinkDo.DO = *(s.shareLinkDo.As(alias).(*gen.DO))
+	return s.updateTableName(alias)
+}
+
+func (s *shareLink) updateTableName(table string) *shareLink {
+	s.ALL = field.NewAsterisk(table)
+	s.ID = field.NewUint(table, "id")
+	s.CreatedAt = field.NewTime(table, "created_at")
+	s.UpdatedAt = field.NewTime(table, "updated_at")
+	s.DeletedAt = field.NewField(table, "deleted_at")
+	s.Token = field.NewString(table, "token")
+	s.UserID = field.NewUint(table, "user_id")
+	s.AccountID = field.NewUint(table, "account_id")
+	s.Path = field.NewString(table, "path")
+	s.RealPath = field.NewString(table, "real_path")
+	s.IsDir = field.NewBool(table, "is_dir")
+	s.Mode = field.NewString(table, "mode")
+	s.PasswordHash = field.NewString(table, "password_hash")
+	s.ExpiresAt = field.NewTime(table, "expires_at")
+	s.MaxDownloads = field.NewInt64(table, "max_downloads")
+	s.Downloads = field.NewInt64(table, "downloads")
+
+	s.fillFieldMap()
+
+	return s
+}
+
+func (s *shareLink) WithContext(ctx context.Context) IShareLinkDo {
+	return s.shareLinkDo.WithContext(ctx)
+}
+
+func (s shareLink) TableName() string { return s.shareLinkDo.TableName() }
+
+func (s shareLink) Alias() string { return s.shareLinkDo.Alias() }
+
+func (s shareLink) Columns(cols ...field.Expr) gen.Columns { return s.shareLinkDo.Columns(cols...) }
+
+func (s *shareLink) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
+	_f, ok := s.fieldMap[fieldName]
+	if !ok || _f == nil {
+		return nil, false
+	}
+	_oe, ok := _f.(field.OrderExpr)
+	return _oe, ok
+}
+
+func (s *shareLink) fillFieldMap() {
+	s.fieldMap = make(map[string]field.Expr, 15)
+	s.fieldMap["id"] = s.ID
+	s.fieldMap["created_at"] = s.CreatedAt
+	s.fieldMap["updated_at"] = s.UpdatedAt
+	s.fieldMap["deleted_at"] = s.DeletedAt
+	s.fieldMap["token"] = s.Token
+	s.fieldMap["user_id"] = s.UserID
+	s.fieldMap["account_id"] = s.AccountID
+	s.fieldMap["path"] = s.Path
+	s.fieldMap["real_path"] = s.RealPath
+	s.fieldMap["is_dir"] = s.IsDir
+	s.fieldMap["mode"] = s.Mode
+	s.fieldMap["password_hash"] = s.PasswordHash
+	s.fieldMap["expires_at"] = s.ExpiresAt
+	s.fieldMap["max_downloads"] = s.MaxDownloads
+	s.fieldMap["downloads"] = s.Downloads
+}
+
+func (s shareLink) clone(db *gorm.DB) shareLink {
+	s.shareLinkDo.ReplaceConnPool(db.Statement.ConnPool)
+	return s
+}
+
+func (s shareLink) replaceDB(db *gorm.DB) shareLink {
+	s.shareLinkDo.ReplaceDB(db)
+	return s
+}
+
+type shareLinkDo struct{ gen.DO }
+
+type IShareLinkDo interface {
+	gen.SubQuery
+	Debug() IShareLinkDo
+	WithContext(ctx context.Context) IShareLinkDo
+	WithResult(fc func(tx gen.Dao)) gen.ResultInfo
+	ReplaceDB(db *gorm.DB)
+	ReadDB() IShareLinkDo
+	WriteDB() IShareLinkDo
+	As(alias string) gen.Dao
+	Session(config *gorm.Session) IShareLinkDo
+	Columns(cols ...field.Expr) gen.Columns
+	Clauses(conds ...clause.Expression) IShareLinkDo
+	Not(conds ...gen.Condition) IShareLinkDo
+	Or(conds ...gen.Condition) IShareLinkDo
+	Select(conds ...field.Expr) IShareLinkDo
+	Where(conds ...gen.Condition) IShareLinkDo
+	Order(conds ...field.Expr) IShareLinkDo
+	Distinct(cols ...field.Expr) IShareLinkDo
+	Omit(cols ...field.Expr) IShareLinkDo
+	Join(table schema.Tabler, on ...field.Expr) IShareLinkDo
+	LeftJoin(table schema.Tabler, on ...field.Expr) IShareLinkDo
+	RightJoin(table schema.Tabler, on ...field.Expr) IShareLinkDo
+	Group(cols ...field.Expr) IShareLinkDo
+	Having(conds ...gen.Condition) IShareLinkDo
+	Limit(limit int) IShareLinkDo
+	Offset(offset int) IShareLinkDo
+	Count() (count int64, err error)
+	Scopes(funcs ...func(gen.Dao) gen.Dao) IShareLinkDo
+	Unscoped() IShareLinkDo
+	Create(values ...*model.ShareLink) error
+	CreateInBatches(values []*model.ShareLink, batchSize int) error
+	Save(values ...*model.ShareLink) error
+	First() (*model.ShareLink, error)
+	Take() (*model.ShareLink, error)
+	Last() (*model.ShareLink, error)
+	Find() ([]*model.ShareLink, error)
+	FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.ShareLink, err error)
+	FindInBatches(result *[]*model.ShareLink, batchSize int, fc func(tx gen.Dao, batch int) error) error
+	Pluck(column field.Expr, dest interface{}) error
+	Delete(...*model.ShareLink) (info gen.ResultInfo, err error)
+	Update(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	Updates(value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumn(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumnSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	UpdateColumns(value interface{}) (info gen.ResultInfo, err error)
+	UpdateFrom(q gen.SubQuery) gen.Dao
+	Attrs(attrs ...field.AssignExpr) IShareLinkDo
+	Assign(attrs ...field.AssignExpr) IShareLinkDo
+	Joins(fields ...field.RelationField) IShareLinkDo
+	Preload(fields ...field.RelationField) IShareLinkDo
+	FirstOrInit() (*model.ShareLink, error)
+	FirstOrCreate() (*model.ShareLink, error)
+	FindByPage(offset int, limit int) (result []*model.ShareLink, count int64, err error)
+	ScanByPage(result interface{}, offset int, limit int) (count int64, err error)
+	Scan(result interface{}) (err error)
+	Returning(value interface{}, columns ...string) IShareLinkDo
+	UnderlyingDB() *gorm.DB
+	schema.Tabler
+}
+
+func (s shareLinkDo) Debug() IShareLinkDo {
+	return s.withDO(s.DO.Debug())
+}
+
+func (s shareLinkDo) WithContext(ctx context.Context) IShareLinkDo {
+	return s.withDO(s.DO.WithContext(ctx))
+}
+
+func (s shareLinkDo) ReadDB() IShareLinkDo {
+	return s.Clauses(dbresolver.Read)
+}
+
+func (s shareLinkDo) WriteDB() IShareLinkDo {
+	return s.Clauses(dbresolver.Write)
+}
+
+func (s shareLinkDo) Session(config *gorm.Session) IShareLinkDo {
+	return s.withDO(s.DO.Session(config))
+}
+
+func (s shareLinkDo) Clauses(conds ...clause.Expression) IShareLinkDo {
+	return s.withDO(s.DO.Clauses(conds...))
+}
+
+func (s shareLinkDo) Returning(value interface{}, columns ...string) IShareLinkDo {
+	return s.withDO(s.DO.Returning(value, columns...))
+}
+
+func (s shareLinkDo) Not(conds ...gen.Condition) IShareLinkDo {
+	return s.withDO(s.DO.Not(conds...))
+}
+
+func (s shareLinkDo) Or(conds ...gen.Condition) IShareLinkDo {
+	return s.withDO(s.DO.Or(conds...))
+}
+
+func (s shareLinkDo) Select(conds ...field.Expr) IShareLinkDo {
+	return s.withDO(s.DO.Select(conds...))
+}
+
+func (s shareLinkDo) Where(conds ...gen.Condition) IShareLinkDo {
+	return s.withDO(s.DO.Where(conds...))
+}
+
+func (s shareLinkDo) Order(conds ...field.Expr) IShareLinkDo {
+	return s.withDO(s.DO.Order(conds...))
+}
+
+func (s shareLinkDo) Distinct(cols ...field.Expr) IShareLinkDo {
+	return s.withDO(s.DO.Distinct(cols...))
+}
+
+func (s shareLinkDo) Omit(cols ...field.Expr) IShareLinkDo {
+	return s.withDO(s.DO.Omit(cols...))
+}
+
+func (s shareLinkDo) Join(table schema.Tabler, on ...field.Expr) IShareLinkDo {
+	return s.withDO(s.DO.Join(table, on...))
+}
+
+func (s shareLinkDo) LeftJoin(table schema.Tabler, on ...field.Expr) IShareLinkDo {
+	return s.withDO(s.DO.LeftJoin(table, on...))
+}
+
+func (s shareLinkDo) RightJoin(table schema.Tabler, on ...field.Expr) IShareLinkDo {
+	return s.withDO(s.DO.RightJoin(table, on...))
+}
+
+func (s shareLinkDo) Group(cols ...field.Expr) IShareLinkDo {
+	return s.withDO(s.DO.Group(cols...))
+}
+
+func (s shareLinkDo) Having(conds ...gen.Condition) IShareLinkDo {
+	return s.withDO(s.DO.Having(conds...))
+}
+
+func (s shareLinkDo) Limit(limit int) IShareLinkDo {
+	return s.withDO(s.DO.Limit(limit))
+}
+
+func (s shareLinkDo) Offset(offset int) IShareLinkDo {
+	return s.withDO(s.DO.Offset(offset))
+}
+
+func (s shareLinkDo) Scopes(funcs ...func(gen.Dao) gen.Dao) IShareLinkDo {
+	return s.withDO(s.DO.Scopes(funcs...))
+}
+
+func (s shareLinkDo) Unscoped() IShareLinkDo {
+	return s.withDO(s.DO.Unscoped())
+}
+
+func (s shareLinkDo) Create(values ...*model.ShareLink) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return s.DO.Create(values)
+}
+
+func (s shareLinkDo) CreateInBatches(values []*model.ShareLink, batchSize int) error {
+	return s.DO.CreateInBatches(values, batchSize)
+}
+
+// Save : !!! underlying implementation is different with GORM
+// The method is equivalent to executing the statement: db.Clauses(clause.OnConflict{UpdateAll: true}).Create(values)
+func (s shareLinkDo) Save(values ...*model.ShareLink) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return s.DO.Save(values)
+}
+
+func (s shareLinkDo) First() (*model.ShareLink, error) {
+	if result, err := s.DO.First(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.ShareLink), nil
+	}
+}
+
+func (s shareLinkDo) Take() (*model.ShareLink, error) {
+	if result, err := s.DO.Take(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.ShareLink), nil
+	}
+}
+
+func (s shareLinkDo) Last() (*model.ShareLink, error) {
+	if result, err := s.DO.Last(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.ShareLink), nil
+	}
+}
+
+func (s shareLinkDo) Find() ([]*model.ShareLink, error) {
+	result, err := s.DO.Find()
+	return result.([]*model.ShareLink), err
+}
+
+func (s shareLinkDo) FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.ShareLink, err error) {
+	buf := make([]*model.ShareLink, 0, batchSize)
+	err = s.DO.FindInBatches(&buf, batchSize, func(tx gen.Dao, batch int) error {
+		defer func() { results = append(results, buf...) }()
+		return fc(tx, batch)
+	})
+	return results, err
+}
+
+func (s shareLinkDo) FindInBatches(result *[]*model.ShareLink, batchSize int, fc func(tx gen.Dao, batch int) error) error {
+	return s.DO.FindInBatches(result, batchSize, fc)
+}
+
+func (s shareLinkDo) Attrs(attrs ...field.AssignExpr) IShareLinkDo {
+	return s.withDO(s.DO.Attrs(attrs...))
+}
+
+func (s shareLinkDo) Assign(attrs ...field.AssignExpr) IShareLinkDo {
+	return s.withDO(s.DO.Assign(attrs...))
+}
+
+func (s shareLinkDo) Joins(fields ...field.RelationField) IShareLinkDo {
+	for _, _f := range fields {
+		s = *s.withDO(s.DO.Joins(_f))
+	}
+	return &s
+}
+
+func (s shareLinkDo) Preload(fields ...field.RelationField) IShareLinkDo {
+	for _, _f := range fields {
+		s = *s.withDO(s.DO.Preload(_f))
+	}
+	return &s
+}
+
+func (s shareLinkDo) FirstOrInit() (*model.ShareLink, error) {
+	if result, err := s.DO.FirstOrInit(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.ShareLink), nil
+	}
+}
+
+func (s shareLinkDo) FirstOrCreate() (*model.ShareLink, error) {
+	if result, err := s.DO.FirstOrCreate(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.ShareLink), nil
+	}
+}
+
+func (s shareLinkDo) FindByPage(offset int, limit int) (result []*model.ShareLink, count int64, err error) {
+	result, err = s.Offset(offset).Limit(limit).Find()
+	if err != nil {
+		return
+	}
+
+	if size := len(result); 0 < limit && 0 < size && size < limit {
+		count = int64(size + offset)
+		return
+	}
+
+	count, err = s.Offset(-1).Limit(-1).Count()
+	return
+}
+
+func (s shareLinkDo) ScanByPage(result interface{}, offset int, limit int) (count int64, err error) {
+	count, err = s.Count()
+	if err != nil {
+		return
+	}
+
+	err = s.Offset(offset).Limit(limit).Scan(result)
+	return
+}
+
+func (s shareLinkDo) Scan(result interface{}) (err error) {
+	return s.DO.Scan(result)
+}
+
+func (s shareLinkDo) Delete(models ...*model.ShareLink) (result gen.ResultInfo, err error) {
+	return s.DO.Delete(models)
+}
+
+func (s *shareLinkDo) withDO(do gen.Dao) *shareLinkDo {
+	s.DO = *do.(*gen.DO)
+	return s
+}
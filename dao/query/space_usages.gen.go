@@ -0,0 +1,414 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package query
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"gorm.io/gen"
+	"gorm.io/gen/field"
+
+	"gorm.io/plugin/dbresolver"
+
+	"webdav/dao/model"
+)
+
+func newSpaceUsage(db *gorm.DB, opts ...gen.DOOption) spaceUsage {
+	_spaceUsage := spaceUsage{}
+
+	_spaceUsage.spaceUsageDo.UseDB(db, opts...)
+	_spaceUsage.spaceUsageDo.UseModel(&model.SpaceUsage{})
+
+	tableName := _spaceUsage.spaceUsageDo.TableName()
+	_spaceUsage.ALL = field.NewAsterisk(tableName)
+	_spaceUsage.ID = field.NewUint(tableName, "id")
+	_spaceUsage.CreatedAt = field.NewTime(tableName, "created_at")
+	_spaceUsage.UpdatedAt = field.NewTime(tableName, "updated_at")
+	_spaceUsage.DeletedAt = field.NewField(tableName, "deleted_at")
+	_spaceUsage.Space = field.NewString(tableName, "space")
+	_spaceUsage.Bytes = field.NewInt64(tableName, "bytes")
+	_spaceUsage.Files = field.NewInt64(tableName, "files")
+
+	_spaceUsage.fillFieldMap()
+
+	return _spaceUsage
+}
+
+type spaceUsage struct {
+	spaceUsageDo spaceUsageDo
+
+	ALL       field.Asterisk
+	ID        field.Uint
+	CreatedAt field.Time
+	UpdatedAt field.Time
+	DeletedAt field.Field
+	Space     field.String
+	Bytes     field.Int64
+	Files     field.Int64
+
+	fieldMap map[string]field.Expr
+}
+
+func (s spaceUsage) Table(newTableName string) *spaceUsage {
+	s.spaceUsageDo.UseTable(newTableName)
+	return s.updateTableName(newTableName)
+}
+
+func (s spaceUsage) As(alias string) *spaceUsage {
+	s.spaceUsageDo.DO = *(s.spaceUsageDo.As(alias).(*gen.DO))
+	return s.updateTableName(alias)
+}
+
+func (s *spaceUsage) updateTableName(table string) *spaceUsage {
+	s.ALL = field.NewAsterisk(table)
+	s.ID = field.NewUint(table, "id")
+	s.CreatedAt = field.NewTime(table, "created_at")
+	s.UpdatedAt = field.NewTime(table, "updated_at")
+	s.DeletedAt = field.NewField(table, "deleted_at")
+	s.Space = field.NewString(table, "space")
+	s.Bytes = field.NewInt64(table, "bytes")
+	s.Files = field.NewInt64(table, "files")
+
+	s.fillFieldMap()
+
+	return s
+}
+
+func (s *spaceUsage) WithContext(ctx context.Context) ISpaceUsageDo {
+	return s.spaceUsageDo.WithContext(ctx)
+}
+
+func (s spaceUsage) TableName() string { return s.spaceUsageDo.TableName() }
+
+func (s spaceUsage) Alias() string { return s.spaceUsageDo.Alias() }
+
+func (s spaceUsage) Columns(cols ...field.Expr) gen.Columns { return s.spaceUsageDo.Columns(cols...) }
+
+func (s *spaceUsage) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
+	_f, ok := s.fieldMap[fieldName]
+	if !ok || _f == nil {
+		return nil, false
+	}
+	_oe, ok := _f.(field.OrderExpr)
+	return _oe, ok
+}
+
+func (s *spaceUsage) fillFieldMap() {
+	s.fieldMap = make(map[string]field.Expr, 7)
+	s.fieldMap["id"] = s.ID
+	s.fieldMap["created_at"] = s.CreatedAt
+	s.fieldMap["updated_at"] = s.UpdatedAt
+	s.fieldMap["deleted_at"] = s.DeletedAt
+	s.fieldMap["space"] = s.Space
+	s.fieldMap["bytes"] = s.Bytes
+	s.fieldMap["files"] = s.Files
+}
+
+func (s spaceUsage) clone(db *gorm.DB) spaceUsage {
+	s.spaceUsageDo.ReplaceConnPool(db.Statement.ConnPool)
+	return s
+}
+
+func (s spaceUsage) replaceDB(db *gorm.DB) spaceUsage {
+	s.spaceUsageDo.ReplaceDB(db)
+	return s
+}
+
+type spaceUsageDo struct{ gen.DO }
+
+type ISpaceUsageDo interface {
+	gen.SubQuery
+	Debug() ISpaceUsageDo
+	WithContext(ctx context.Context) ISpaceUsageDo
+	WithResult(fc func(tx gen.Dao)) gen.ResultInfo
+	ReplaceDB(db *gorm.DB)
+	ReadDB() ISpaceUsageDo
+	WriteDB() ISpaceUsageDo
+	As(alias string) gen.Dao
+	Session(config *gorm.Session) ISpaceUsageDo
+	Columns(cols ...field.Expr) gen.Columns
+	Clauses(conds ...clause.Expression) ISpaceUsageDo
+	Not(conds ...gen.Condition) ISpaceUsageDo
+	Or(conds ...gen.Condition) ISpaceUsageDo
+	Select(conds ...field.Expr) ISpaceUsageDo
+	Where(conds ...gen.Condition) ISpaceUsageDo
+	Order(conds ...field.Expr) ISpaceUsageDo
+	Distinct(cols ...field.Expr) ISpaceUsageDo
+	Omit(cols ...field.Expr) ISpaceUsageDo
+	Join(table schema.Tabler, on ...field.Expr) ISpaceUsageDo
+	LeftJoin(table schema.Tabler, on ...field.Expr) ISpaceUsageDo
+	RightJoin(table schema.Tabler, on ...field.Expr) ISpaceUsageDo
+	Group(cols ...field.Expr) ISpaceUsageDo
+	Having(conds ...gen.Condition) ISpaceUsageDo
+	Limit(limit int) ISpaceUsageDo
+	Offset(offset int) ISpaceUsageDo
+	Count() (count int64, err error)
+	Scopes(funcs ...func(gen.Dao) gen.Dao) ISpaceUsageDo
+	Unscoped() ISpaceUsageDo
+	Create(values ...*model.SpaceUsage) error
+	CreateInBatches(values []*model.SpaceUsage, batchSize int) error
+	Save(values ...*model.SpaceUsage) error
+	First() (*model.SpaceUsage, error)
+	Take() (*model.SpaceUsage, error)
+	Last() (*model.SpaceUsage, error)
+	Find() ([]*model.SpaceUsage, error)
+	FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.SpaceUsage, err error)
+	FindInBatches(result *[]*model.SpaceUsage, batchSize int, fc func(tx gen.Dao, batch int) error) error
+	Pluck(column field.Expr, dest interface{}) error
+	Delete(...*model.SpaceUsage) (info gen.ResultInfo, err error)
+	Update(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	Updates(value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumn(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumnSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	UpdateColumns(value interface{}) (info gen.ResultInfo, err error)
+	UpdateFrom(q gen.SubQuery) gen.Dao
+	Attrs(attrs ...field.AssignExpr) ISpaceUsageDo
+	Assign(attrs ...field.AssignExpr) ISpaceUsageDo
+	Joins(fields ...field.RelationField) ISpaceUsageDo
+	Preload(fields ...field.RelationField) ISpaceUsageDo
+	FirstOrInit() (*model.SpaceUsage, error)
+	FirstOrCreate() (*model.SpaceUsage, error)
+	FindByPage(offset int, limit int) (result []*model.SpaceUsage, count int64, err error)
+	ScanByPage(result interface{}, offset int, limit int) (count int64, err error)
+	Scan(result interface{}) (err error)
+	Returning(value interface{}, columns ...string) ISpaceUsageDo
+	UnderlyingDB() *gorm.DB
+	schema.Tabler
+}
+
+func (s spaceUsageDo) Debug() ISpaceUsageDo {
+	return s.withDO(s.DO.Debug())
+}
+
+func (s spaceUsageDo) WithContext(ctx context.Context) ISpaceUsageDo {
+	return s.withDO(s.DO.WithContext(ctx))
+}
+
+func (s spaceUsageDo) ReadDB() ISpaceUsageDo {
+	return s.Clauses(dbresolver.Read)
+}
+
+func (s spaceUsageDo) WriteDB() ISpaceUsageDo {
+	return s.Clauses(dbresolver.Write)
+}
+
+func (s spaceUsageDo) Session(config *gorm.Session) ISpaceUsageDo {
+	return s.withDO(s.DO.Session(config))
+}
+
+func (s spaceUsageDo) Clauses(conds ...clause.Expression) ISpaceUsageDo {
+	return s.withDO(s.DO.Clauses(conds...))
+}
+
+func (s spaceUsageDo) Returning(value interface{}, columns ...string) ISpaceUsageDo {
+	return s.withDO(s.DO.Returning(value, columns...))
+}
+
+func (s spaceUsageDo) Not(conds ...gen.Condition) ISpaceUsageDo {
+	return s.withDO(s.DO.Not(conds...))
+}
+
+func (s spaceUsageDo) Or(conds ...gen.Condition) ISpaceUsageDo {
+	return s.withDO(s.DO.Or(conds...))
+}
+
+func (s spaceUsageDo) Select(conds ...field.Expr) ISpaceUsageDo {
+	return s.withDO(s.DO.Select(conds...))
+}
+
+func (s spaceUsageDo) Where(conds ...gen.Condition) ISpaceUsageDo {
+	return s.withDO(s.DO.Where(conds...))
+}
+
+func (s spaceUsageDo) Order(conds ...field.Expr) ISpaceUsageDo {
+	return s.withDO(s.DO.Order(conds...))
+}
+
+func (s spaceUsageDo) Distinct(cols ...field.Expr) ISpaceUsageDo {
+	return s.withDO(s.DO.Distinct(cols...))
+}
+
+func (s spaceUsageDo) Omit(cols ...field.Expr) ISpaceUsageDo {
+	return s.withDO(s.DO.Omit(cols...))
+}
+
+func (s spaceUsageDo) Join(table schema.Tabler, on ...field.Expr) ISpaceUsageDo {
+	return s.withDO(s.DO.Join(table, on...))
+}
+
+func (s spaceUsageDo) LeftJoin(table schema.Tabler, on ...field.Expr) ISpaceUsageDo {
+	return s.withDO(s.DO.LeftJoin(table, on...))
+}
+
+func (s spaceUsageDo) RightJoin(table schema.Tabler, on ...field.Expr) ISpaceUsageDo {
+	return s.withDO(s.DO.RightJoin(table, on...))
+}
+
+func (s spaceUsageDo) Group(cols ...field.Expr) ISpaceUsageDo {
+	return s.withDO(s.DO.Group(cols...))
+}
+
+func (s spaceUsageDo) Having(conds ...gen.Condition) ISpaceUsageDo {
+	return s.withDO(s.DO.Having(conds...))
+}
+
+func (s spaceUsageDo) Limit(limit int) ISpaceUsageDo {
+	return s.withDO(s.DO.Limit(limit))
+}
+
+func (s spaceUsageDo) Offset(offset int) ISpaceUsageDo {
+	return s.withDO(s.DO.Offset(offset))
+}
+
+func (s spaceUsageDo) Scopes(funcs ...func(gen.Dao) gen.Dao) ISpaceUsageDo {
+	return s.withDO(s.DO.Scopes(funcs...))
+}
+
+func (s spaceUsageDo) Unscoped() ISpaceUsageDo {
+	return s.withDO(s.DO.Unscoped())
+}
+
+func (s spaceUsageDo) Create(values ...*model.SpaceUsage) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return s.DO.Create(values)
+}
+
+func (s spaceUsageDo) CreateInBatches(values []*model.SpaceUsage, batchSize int) error {
+	return s.DO.CreateInBatches(values, batchSize)
+}
+
+// Save : !!! underlying implementation is different with GORM
+// The method is equivalent to executing the statement: db.Clauses(clause.OnConflict{UpdateAll: true}).Create(values)
+func (s spaceUsageDo) Save(values ...*model.SpaceUsage) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return s.DO.Save(values)
+}
+
+func (s spaceUsageDo) First() (*model.SpaceUsage, error) {
+	if result, err := s.DO.First(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.SpaceUsage), nil
+	}
+}
+
+func (s spaceUsageDo) Take() (*model.SpaceUsage, error) {
+	if result, err := s.DO.Take(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.SpaceUsage), nil
+	}
+}
+
+func (s spaceUsageDo) Last() (*model.SpaceUsage, error) {
+	if result, err := s.DO.Last(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.SpaceUsage), nil
+	}
+}
+
+func (s spaceUsageDo) Find() ([]*model.SpaceUsage, error) {
+	result, err := s.DO.Find()
+	return result.([]*model.SpaceUsage), err
+}
+
+func (s spaceUsageDo) FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.SpaceUsage, err error) {
+	buf := make([]*model.SpaceUsage, 0, batchSize)
+	err = s.DO.FindInBatches(&buf, batchSize, func(tx gen.Dao, batch int) error {
+		defer func() { results = append(results, buf...) }()
+		return fc(tx, batch)
+	})
+	return results, err
+}
+
+func (s spaceUsageDo) FindInBatches(result *[]*model.SpaceUsage, batchSize int, fc func(tx gen.Dao, batch int) error) error {
+	return s.DO.FindInBatches(result, batchSize, fc)
+}
+
+func (s spaceUsageDo) Attrs(attrs ...field.AssignExpr) ISpaceUsageDo {
+	return s.withDO(s.DO.Attrs(attrs...))
+}
+
+func (s spaceUsageDo) Assign(attrs ...field.AssignExpr) ISpaceUsageDo {
+	return s.withDO(s.DO.Assign(attrs...))
+}
+
+func (s spaceUsageDo) Joins(fields ...field.RelationField) ISpaceUsageDo {
+	for _, _f := range fields {
+		s = *s.withDO(s.DO.Joins(_f))
+	}
+	return &s
+}
+
+func (s spaceUsageDo) Preload(fields ...field.RelationField) ISpaceUsageDo {
+	for _, _f := range fields {
+		s = *s.withDO(s.DO.Preload(_f))
+	}
+	return &s
+}
+
+func (s spaceUsageDo) FirstOrInit() (*model.SpaceUsage, error) {
+	if result, err := s.DO.FirstOrInit(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.SpaceUsage), nil
+	}
+}
+
+func (s spaceUsageDo) FirstOrCreate() (*model.SpaceUsage, error) {
+	if result, err := s.DO.FirstOrCreate(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.SpaceUsage), nil
+	}
+}
+
+func (s spaceUsageDo) FindByPage(offset int, limit int) (result []*model.SpaceUsage, count int64, err error) {
+	result, err = s.Offset(offset).Limit(limit).Find()
+	if err != nil {
+		return
+	}
+
+	if size := len(result); 0 < limit && 0 < size && size < limit {
+		count = int64(size + offset)
+		return
+	}
+
+	count, err = s.Offset(-1).Limit(-1).Count()
+	return
+}
+
+func (s spaceUsageDo) ScanByPage(result interface{}, offset int, limit int) (count int64, err error) {
+	count, err = s.Count()
+	if err != nil {
+		return
+	}
+
+	err = s.Offset(offset).Limit(limit).Scan(result)
+	return
+}
+
+func (s spaceUsageDo) Scan(result interface{}) (err error) {
+	return s.DO.Scan(result)
+}
+
+func (s spaceUsageDo) Delete(models ...*model.SpaceUsage) (result gen.ResultInfo, err error) {
+	return s.DO.Delete(models)
+}
+
+func (s *spaceUsageDo) withDO(do gen.Dao) *spaceUsageDo {
+	s.DO = *do.(*gen.DO)
+	return s
+}
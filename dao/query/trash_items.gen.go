@@ -0,0 +1,430 @@
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+// Code generated by gorm.io/gen. DO NOT EDIT.
+
+package query
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"gorm.io/gen"
+	"gorm.io/gen/field"
+
+	"gorm.io/plugin/dbresolver"
+
+	"webdav/dao/model"
+)
+
+func newTrashItem(db *gorm.DB, opts ...gen.DOOption) trashItem {
+	_trashItem := trashItem{}
+
+	_trashItem.trashItemDo.UseDB(db, opts...)
+	_trashItem.trashItemDo.UseModel(&model.TrashItem{})
+
+	tableName := _trashItem.trashItemDo.TableName()
+	_trashItem.ALL = field.NewAsterisk(tableName)
+	_trashItem.ID = field.NewUint(tableName, "id")
+	_trashItem.CreatedAt = field.NewTime(tableName, "created_at")
+	_trashItem.UpdatedAt = field.NewTime(tableName, "updated_at")
+	_trashItem.DeletedAt = field.NewField(tableName, "deleted_at")
+	_trashItem.Space = field.NewString(tableName, "space")
+	_trashItem.Path = field.NewString(tableName, "path")
+	_trashItem.RealPath = field.NewString(tableName, "real_path")
+	_trashItem.TrashPath = field.NewString(tableName, "trash_path")
+	_trashItem.IsDir = field.NewBool(tableName, "is_dir")
+	_trashItem.Size = field.NewInt64(tableName, "size")
+	_trashItem.UserID = field.NewUint(tableName, "user_id")
+
+	_trashItem.fillFieldMap()
+
+	return _trashItem
+}
+
+type trashItem struct {
+	trashItemDo trashItemDo
+
+	ALL       field.Asterisk
+	ID        field.Uint
+	CreatedAt field.Time
+	UpdatedAt field.Time
+	DeletedAt field.Field
+	Space     field.String
+	Path      field.String
+	RealPath  field.String
+	TrashPath field.String
+	IsDir     field.Bool
+	Size      field.Int64
+	UserID    field.Uint
+
+	fieldMap map[string]field.Expr
+}
+
+func (t trashItem) Table(newTableName string) *trashItem {
+	t.trashItemDo.UseTable(newTableName)
+	return t.updateTableName(newTableName)
+}
+
+func (t trashItem) As(alias string) *trashItem {
+	t.trashItemDo.DO = *(t.trashItemDo.As(alias).(*gen.DO))
+	return t.updateTableName(alias)
+}
+
+func (t *trashItem) updateTableName(table string) *trashItem {
+	t.ALL = field.NewAsterisk(table)
+	t.ID = field.NewUint(table, "id")
+	t.CreatedAt = field.NewTime(table, "created_at")
+	t.UpdatedAt = field.NewTime(table, "updated_at")
+	t.DeletedAt = field.NewField(table, "deleted_at")
+	t.Space = field.NewString(table, "space")
+	t.Path = field.NewString(table, "path")
+	t.RealPath = field.NewString(table, "real_path")
+	t.TrashPath = field.NewString(table, "trash_path")
+	t.IsDir = field.NewBool(table, "is_dir")
+	t.Size = field.NewInt64(table, "size")
+	t.UserID = field.NewUint(table, "user_id")
+
+	t.fillFieldMap()
+
+	return t
+}
+
+func (t *trashItem) WithContext(ctx context.Context) ITrashItemDo {
+	return t.trashItemDo.WithContext(ctx)
+}
+
+func (t trashItem) TableName() string { return t.trashItemDo.TableName() }
+
+func (t trashItem) Alias() string { return t.trashItemDo.Alias() }
+
+func (t trashItem) Columns(cols ...field.Expr) gen.Columns { return t.trashItemDo.Columns(cols...) }
+
+func (t *trashItem) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
+	_f, ok := t.fieldMap[fieldName]
+	if !ok || _f == nil {
+		return nil, false
+	}
+	_oe, ok := _f.(field.OrderExpr)
+	return _oe, ok
+}
+
+func (t *trashItem) fillFieldMap() {
+	t.fieldMap = make(map[string]field.Expr, 11)
+	t.fieldMap["id"] = t.ID
+	t.fieldMap["created_at"] = t.CreatedAt
+	t.fieldMap["updated_at"] = t.UpdatedAt
+	t.fieldMap["deleted_at"] = t.DeletedAt
+	t.fieldMap["space"] = t.Space
+	t.fieldMap["path"] = t.Path
+	t.fieldMap["real_path"] = t.RealPath
+	t.fieldMap["trash_path"] = t.TrashPath
+	t.fieldMap["is_dir"] = t.IsDir
+	t.fieldMap["size"] = t.Size
+	t.fieldMap["user_id"] = t.UserID
+}
+
+func (t trashItem) clone(db *gorm.DB) trashItem {
+	t.trashItemDo.ReplaceConnPool(db.Statement.ConnPool)
+	return t
+}
+
+func (t trashItem) replaceDB(db *gorm.DB) trashItem {
+	t.trashItemDo.ReplaceDB(db)
+	return t
+}
+
+type trashItemDo struct{ gen.DO }
+
+type ITrashItemDo interface {
+	gen.SubQuery
+	Debug() ITrashItemDo
+	WithContext(ctx context.Context) ITrashItemDo
+	WithResult(fc func(tx gen.Dao)) gen.ResultInfo
+	ReplaceDB(db *gorm.DB)
+	ReadDB() ITrashItemDo
+	WriteDB() ITrashItemDo
+	As(alias string) gen.Dao
+	Session(config *gorm.Session) ITrashItemDo
+	Columns(cols ...field.Expr) gen.Columns
+	Clauses(conds ...clause.Expression) ITrashItemDo
+	Not(conds ...gen.Condition) ITrashItemDo
+	Or(conds ...gen.Condition) ITrashItemDo
+	Select(conds ...field.Expr) ITrashItemDo
+	Where(conds ...gen.Condition) ITrashItemDo
+	Order(conds ...field.Expr) ITrashItemDo
+	Distinct(cols ...field.Expr) ITrashItemDo
+	Omit(cols ...field.Expr) ITrashItemDo
+	Join(table schema.Tabler, on ...field.Expr) ITrashItemDo
+	LeftJoin(table schema.Tabler, on ...field.Expr) ITrashItemDo
+	RightJoin(table schema.Tabler, on ...field.Expr) ITrashItemDo
+	Group(cols ...field.Expr) ITrashItemDo
+	Having(conds ...gen.Condition) ITrashItemDo
+	Limit(limit int) ITrashItemDo
+	Offset(offset int) ITrashItemDo
+	Count() (count int64, err error)
+	Scopes(funcs ...func(gen.Dao) gen.Dao) ITrashItemDo
+	Unscoped() ITrashItemDo
+	Create(values ...*model.TrashItem) error
+	CreateInBatches(values []*model.TrashItem, batchSize int) error
+	Save(values ...*model.TrashItem) error
+	First() (*model.TrashItem, error)
+	Take() (*model.TrashItem, error)
+	Last() (*model.TrashItem, error)
+	Find() ([]*model.TrashItem, error)
+	FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.TrashItem, err error)
+	FindInBatches(result *[]*model.TrashItem, batchSize int, fc func(tx gen.Dao, batch int) error) error
+	Pluck(column field.Expr, dest interface{}) error
+	Delete(...*model.TrashItem) (info gen.ResultInfo, err error)
+	Update(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	Updates(value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumn(column field.Expr, value interface{}) (info gen.ResultInfo, err error)
+	UpdateColumnSimple(columns ...field.AssignExpr) (info gen.ResultInfo, err error)
+	UpdateColumns(value interface{}) (info gen.ResultInfo, err error)
+	UpdateFrom(q gen.SubQuery) gen.Dao
+	Attrs(attrs ...field.AssignExpr) ITrashItemDo
+	Assign(attrs ...field.AssignExpr) ITrashItemDo
+	Joins(fields ...field.RelationField) ITrashItemDo
+	Preload(fields ...field.RelationField) ITrashItemDo
+	FirstOrInit() (*model.TrashItem, error)
+	FirstOrCreate() (*model.TrashItem, error)
+	FindByPage(offset int, limit int) (result []*model.TrashItem, count int64, err error)
+	ScanByPage(result interface{}, offset int, limit int) (count int64, err error)
+	Scan(result interface{}) (err error)
+	Returning(value interface{}, columns ...string) ITrashItemDo
+	UnderlyingDB() *gorm.DB
+	schema.Tabler
+}
+
+func (t trashItemDo) Debug() ITrashItemDo {
+	return t.withDO(t.DO.Debug())
+}
+
+func (t trashItemDo) WithContext(ctx context.Context) ITrashItemDo {
+	return t.withDO(t.DO.WithContext(ctx))
+}
+
+func (t trashItemDo) ReadDB() ITrashItemDo {
+	return t.Clauses(dbresolver.Read)
+}
+
+func (t trashItemDo) WriteDB() ITrashItemDo {
+	return t.Clauses(dbresolver.Write)
+}
+
+func (t trashItemDo) Session(config *gorm.Session) ITrashItemDo {
+	return t.withDO(t.DO.Session(config))
+}
+
+func (t trashItemDo) Clauses(conds ...clause.Expression) ITrashItemDo {
+	return t.withDO(t.DO.Clauses(conds...))
+}
+
+func (t trashItemDo) Returning(value interface{}, columns ...string) ITrashItemDo {
+	return t.withDO(t.DO.Returning(value, columns...))
+}
+
+func (t trashItemDo) Not(conds ...gen.Condition) ITrashItemDo {
+	return t.withDO(t.DO.Not(conds...))
+}
+
+func (t trashItemDo) Or(conds ...gen.Condition) ITrashItemDo {
+	return t.withDO(t.DO.Or(conds...))
+}
+
+func (t trashItemDo) Select(conds ...field.Expr) ITrashItemDo {
+	return t.withDO(t.DO.Select(conds...))
+}
+
+func (t trashItemDo) Where(conds ...gen.Condition) ITrashItemDo {
+	return t.withDO(t.DO.Where(conds...))
+}
+
+func (t trashItemDo) Order(conds ...field.Expr) ITrashItemDo {
+	return t.withDO(t.DO.Order(conds...))
+}
+
+func (t trashItemDo) Distinct(cols ...field.Expr) ITrashItemDo {
+	return t.withDO(t.DO.Distinct(cols...))
+}
+
+func (t trashItemDo) Omit(cols ...field.Expr) ITrashItemDo {
+	return t.withDO(t.DO.Omit(cols...))
+}
+
+func (t trashItemDo) Join(table schema.Tabler, on ...field.Expr) ITrashItemDo {
+	return t.withDO(t.DO.Join(table, on...))
+}
+
+func (t trashItemDo) LeftJoin(table schema.Tabler, on ...field.Expr) ITrashItemDo {
+	return t.withDO(t.DO.LeftJoin(table, on...))
+}
+
+func (t trashItemDo) RightJoin(table schema.Tabler, on ...field.Expr) ITrashItemDo {
+	return t.withDO(t.DO.RightJoin(table, on...))
+}
+
+func (t trashItemDo) Group(cols ...field.Expr) ITrashItemDo {
+	return t.withDO(t.DO.Group(cols...))
+}
+
+func (t trashItemDo) Having(conds ...gen.Condition) ITrashItemDo {
+	return t.withDO(t.DO.Having(conds...))
+}
+
+func (t trashItemDo) Limit(limit int) ITrashItemDo {
+	return t.withDO(t.DO.Limit(limit))
+}
+
+func (t trashItemDo) Offset(offset int) ITrashItemDo {
+	return t.withDO(t.DO.Offset(offset))
+}
+
+func (t trashItemDo) Scopes(funcs ...func(gen.Dao) gen.Dao) ITrashItemDo {
+	return t.withDO(t.DO.Scopes(funcs...))
+}
+
+func (t trashItemDo) Unscoped() ITrashItemDo {
+	return t.withDO(t.DO.Unscoped())
+}
+
+func (t trashItemDo) Create(values ...*model.TrashItem) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return t.DO.Create(values)
+}
+
+func (t trashItemDo) CreateInBatches(values []*model.TrashItem, batchSize int) error {
+	return t.DO.CreateInBatches(values, batchSize)
+}
+
+// Save : !!! underlying implementation is different with GORM
+// The method is equivalent to executing the statement: db.Clauses(clause.OnConflict{UpdateAll: true}).Create(values)
+func (t trashItemDo) Save(values ...*model.TrashItem) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return t.DO.Save(values)
+}
+
+func (t trashItemDo) First() (*model.TrashItem, error) {
+	if result, err := t.DO.First(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.TrashItem), nil
+	}
+}
+
+func (t trashItemDo) Take() (*model.TrashItem, error) {
+	if result, err := t.DO.Take(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.TrashItem), nil
+	}
+}
+
+func (t trashItemDo) Last() (*model.TrashItem, error) {
+	if result, err := t.DO.Last(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.TrashItem), nil
+	}
+}
+
+func (t trashItemDo) Find() ([]*model.TrashItem, error) {
+	result, err := t.DO.Find()
+	return result.([]*model.TrashItem), err
+}
+
+func (t trashItemDo) FindInBatch(batchSize int, fc func(tx gen.Dao, batch int) error) (results []*model.TrashItem, err error) {
+	buf := make([]*model.TrashItem, 0, batchSize)
+	err = t.DO.FindInBatches(&buf, batchSize, func(tx gen.Dao, batch int) error {
+		defer func() { results = append(results, buf...) }()
+		return fc(tx, batch)
+	})
+	return results, err
+}
+
+func (t trashItemDo) FindInBatches(result *[]*model.TrashItem, batchSize int, fc func(tx gen.Dao, batch int) error) error {
+	return t.DO.FindInBatches(result, batchSize, fc)
+}
+
+func (t trashItemDo) Attrs(attrs ...field.AssignExpr) ITrashItemDo {
+	return t.withDO(t.DO.Attrs(attrs...))
+}
+
+func (t trashItemDo) Assign(attrs ...field.AssignExpr) ITrashItemDo {
+	return t.withDO(t.DO.Assign(attrs...))
+}
+
+func (t trashItemDo) Joins(fields ...field.RelationField) ITrashItemDo {
+	for _, _f := range fields {
+		t = *t.withDO(t.DO.Joins(_f))
+	}
+	return &t
+}
+
+func (t trashItemDo) Preload(fields ...field.RelationField) ITrashItemDo {
+	for _, _f := range fields {
+		t = *t.withDO(t.DO.Preload(_f))
+	}
+	return &t
+}
+
+func (t trashItemDo) FirstOrInit() (*model.TrashItem, error) {
+	if result, err := t.DO.FirstOrInit(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.TrashItem), nil
+	}
+}
+
+func (t trashItemDo) FirstOrCreate() (*model.TrashItem, error) {
+	if result, err := t.DO.FirstOrCreate(); err != nil {
+		return nil, err
+	} else {
+		return result.(*model.TrashItem), nil
+	}
+}
+
+func (t trashItemDo) FindByPage(offset int, limit int) (result []*model.TrashItem, count int64, err error) {
+	result, err = t.Offset(offset).Limit(limit).Find()
+	if err != nil {
+		return
+	}
+
+	if size := len(result); 0 < limit && 0 < size && size < limit {
+		count = int64(size + offset)
+		return
+	}
+
+	count, err = t.Offset(-1).Limit(-1).Count()
+	return
+}
+
+func (t trashItemDo) ScanByPage(result interface{}, offset int, limit int) (count int64, err error) {
+	count, err = t.Count()
+	if err != nil {
+		return
+	}
+
+	err = t.Offset(offset).Limit(limit).Scan(result)
+	return
+}
+
+func (t trashItemDo) Scan(result interface{}) (err error) {
+	return t.DO.Scan(result)
+}
+
+func (t trashItemDo) Delete(models ...*model.TrashItem) (result gen.ResultInfo, err error) {
+	return t.DO.Delete(models)
+}
+
+func (t *trashItemDo) withDO(do gen.Dao) *trashItemDo {
+	t.DO = *do.(*gen.DO)
+	return t
+}
@@ -12,7 +12,9 @@ import (
 )
 
 func main() {
-	r := gin.Default()
+	r := gin.New()
+	// 流式响应中途出错时 panic(http.ErrAbortHandler) 中断连接，不能被 gin.Recovery 吞掉
+	r.Use(gin.Logger(), gin.CustomRecoveryWithWriter(nil, service.RecoverPanic))
 	err := query.InitDB()
 	if err != nil {
 		fmt.Println("err init:", err)
@@ -32,6 +34,13 @@ func main() {
 	}
 
 	go service.StartCheckSpace()
+	go service.StartCleanUploads()
+	go service.StartScanUsage()
+	go service.StartPurgeTrash()
+	go service.StartHashDatasets()
+	go service.StartSyncRevocations()
+	go service.StartJobWorkers()
+	go service.StartIndexFiles()
 	methods := []string{
 		"PUT",
 		"MKCOL",
@@ -44,8 +53,25 @@ func main() {
 		r.Handle(m, "/api/ss/*path", service.WebDav)
 	}
 	webdavGroup := r.Group("api/ss", service.WebDAVMiddleware())
+	service.RegisterAuth(webdavGroup)
+	service.RegisterRevoke(webdavGroup)
 	service.RegisterDataset(webdavGroup)
+	service.RegisterGrant(webdavGroup)
+	service.RegisterVersion(webdavGroup)
+	service.RegisterManifest(webdavGroup)
 	service.RegisterFile(webdavGroup)
+	service.RegisterCopy(webdavGroup)
+	service.RegisterBatch(webdavGroup)
+	service.RegisterSearch(webdavGroup)
+	service.RegisterDiskUsage(webdavGroup)
+	service.RegisterPreview(webdavGroup)
+	service.RegisterJob(webdavGroup)
+	service.RegisterUpload(webdavGroup)
+	service.RegisterArchive(webdavGroup)
+	service.RegisterQuota(webdavGroup)
+	service.RegisterTrash(webdavGroup)
+	service.RegisterShare(webdavGroup)
+	service.RegisterAPIKey(webdavGroup)
 
 	err = r.Run(":" + port)
 	if err != nil {
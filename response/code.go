@@ -9,8 +9,12 @@ const (
 	TokenExpired   ErrorCode = 40101
 	UserNotFound   ErrorCode = 40102
 	InvalidToken   ErrorCode = 40103
+	WrongPassword  ErrorCode = 40104
+	UserInactive   ErrorCode = 40105 // 用户未激活或已被禁用
 
-	InvalidRole ErrorCode = 40301
+	InvalidRole    ErrorCode = 40301
+	QuotaExceeded  ErrorCode = 40302
+	AccountExpired ErrorCode = 40303 // 账户已过期，宽限期内只读
 
 	// Indicates laziness of the developer
 	// Frontend will directly print the message without any translation
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"webdav/config"
+	"webdav/dao/model"
+	"webdav/dao/query"
+	"webdav/response"
+	"webdav/util"
+
+	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v5"
+)
+
+// 账户过期的原因，宽限期内 readOnly 为真
+type accountExpiredError struct {
+	expiredAt time.Time
+	until     time.Time
+	readOnly  bool
+}
+
+func (e *accountExpiredError) Error() string {
+	if e.readOnly {
+		return fmt.Sprintf("account expired at %s and is read-only until %s",
+			e.expiredAt.Format(time.RFC3339), e.until.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("account expired at %s", e.expiredAt.Format(time.RFC3339))
+}
+
+// 检查账户是否过期，未过期时返回 nil
+func checkAccountExpiry(account *model.Account) *accountExpiredError {
+	now := time.Now()
+	if account.ExpiredAt == nil || now.Before(*account.ExpiredAt) {
+		return nil
+	}
+	grace := time.Hour * time.Duration(config.GetConfig().Account.ExpireGraceHours)
+	until := account.ExpiredAt.Add(grace)
+	return &accountExpiredError{expiredAt: *account.ExpiredAt, until: until, readOnly: now.Before(until)}
+}
+
+// 过期账户在宽限期内降为只读，之后不可访问
+func limitAccountExpiry(account *model.Account, permission model.FilePermission) model.FilePermission {
+	expired := checkAccountExpiry(account)
+	if expired == nil || !permission.CanRead() {
+		return permission
+	}
+	if expired.readOnly {
+		return model.ReadOnly
+	}
+	return model.NotAllowed
+}
+
+// 访问被拒绝的具体原因，没有特别原因时返回 nil
+func accessDeniedReason(c *gin.Context, path string, token util.JWTMessage) error {
+	switch getFirstToken(path) {
+	case model.AccountPath:
+		a := query.Account
+		account, err := a.WithContext(c).Where(a.ID.Eq(token.AccountID)).First()
+		if err != nil {
+			return nil
+		}
+		if expired := checkAccountExpiry(account); expired != nil {
+			return expired
+		}
+	case model.UserPath:
+		if status, err := userStatus(c, token.UserID); err == nil && status != model.StatusActive {
+			return ErrUserNotActive
+		}
+	}
+	return nil
+}
+
+// 权限不足时的响应，账户过期或用户被禁用时给出明确的错误码
+func permissionDenied(c *gin.Context, path string, token util.JWTMessage, msg string, code response.ErrorCode) {
+	reason := accessDeniedReason(c, strings.TrimLeft(path, "/"), token)
+	var expired *accountExpiredError
+	switch {
+	case errors.As(reason, &expired):
+		response.HTTPError(c, http.StatusForbidden, reason.Error(), response.AccountExpired)
+	case errors.Is(reason, ErrUserNotActive):
+		response.HTTPError(c, http.StatusForbidden, reason.Error(), response.UserInactive)
+	default:
+		response.HTTPError(c, http.StatusUnauthorized, msg, code)
+	}
+}
+
+// 认证失败时的响应，保持原有的错误码，只对能说明原因的错误给出明确的错误码
+func authError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrUserNotActive):
+		response.HTTPError(c, http.StatusForbidden, err.Error(), response.UserInactive)
+	case errors.Is(err, ErrTokenRevoked):
+		response.HTTPError(c, http.StatusUnauthorized, err.Error(), response.InvalidToken)
+	case errors.Is(err, jwt.ErrTokenExpired):
+		response.HTTPError(c, http.StatusUnauthorized, err.Error(), response.TokenExpired)
+	default:
+		response.Error(c, err.Error(), response.NotSpecified)
+	}
+}
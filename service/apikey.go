@@ -0,0 +1,330 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+	"webdav/dao/model"
+	"webdav/dao/query"
+	"webdav/logutils"
+	"webdav/response"
+	"webdav/util"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+)
+
+const (
+	apiKeyBytes       = 20
+	apiKeyPrefixLen   = len(model.APIKeyPrefix) + 6
+	apiKeyTouchPeriod = time.Minute
+)
+
+// 限定了范围的令牌不带管理员身份，不能指定管理员路径
+var apiKeyRoots = []string{model.UserPath, model.PublicPath, model.AccountPath}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// 通过个人访问令牌认证，username 为空时不校验用户名（Bearer 方式）。
+// 访问模式每次从数据库读取，账户成员关系的变化立即生效。
+func authenticateAPIKey(c *gin.Context, username, key string) (util.JWTMessage, error) {
+	var msg util.JWTMessage
+	k := query.APIKey
+	apiKey, err := k.WithContext(c).Where(k.KeyHash.Eq(hashAPIKey(key))).First()
+	if err != nil {
+		return msg, fmt.Errorf("invalid token")
+	}
+	now := time.Now()
+	if apiKey.ExpiresAt != nil && now.After(*apiKey.ExpiresAt) {
+		return msg, fmt.Errorf("token has expired")
+	}
+	u := query.User
+	user, err := u.WithContext(c).Where(u.ID.Eq(apiKey.UserID)).First()
+	if err != nil || (username != "" && username != user.Name) {
+		return msg, fmt.Errorf("invalid token")
+	}
+	if user.Status != model.StatusActive {
+		return msg, ErrUserNotActive
+	}
+	msg = userClaims(c, user, apiKey.AccountID)
+	msg.Scope = &util.KeyScope{
+		ReadOnly:   apiKey.Scope != model.APIKeyReadWrite,
+		Roots:      apiKey.Roots.Data(),
+		DatasetIDs: apiKey.DatasetIDs.Data(),
+	}
+	// 只读或限定了范围的令牌不带管理员身份，部分管理接口只检查角色，不经过路径限制
+	if (msg.Scope.ReadOnly || keyScopeLimited(msg.Scope)) && msg.RolePlatform == model.RoleAdmin {
+		msg.RolePlatform = model.RoleUser
+	}
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > apiKeyTouchPeriod {
+		if _, err = k.WithContext(c).Where(k.ID.Eq(apiKey.ID)).UpdateSimple(k.LastUsedAt.Value(now)); err != nil {
+			logutils.Log.Warnf("update api key %d: %v", apiKey.ID, err)
+		}
+	}
+	return msg, nil
+}
+
+// 按数据库中的当前状态生成用户的身份信息，accountID 不为 0 时带上用户在该账户中的角色和访问模式
+func userClaims(ctx context.Context, user *model.User, accountID uint) util.JWTMessage {
+	msg := util.JWTMessage{
+		UserID:           user.ID,
+		Username:         user.Name,
+		RolePlatform:     user.Role,
+		PublicAccessMode: model.AccessModeNA,
+	}
+	ua := query.UserAccount
+	if public, err := ua.WithContext(ctx).Where(ua.UserID.Eq(user.ID), ua.AccountID.Eq(model.DefaultAccountID)).First(); err == nil {
+		msg.PublicAccessMode = public.AccessMode
+	}
+	if accountID == util.QueueIDNull {
+		return msg
+	}
+	a := query.Account
+	account, err := a.WithContext(ctx).Where(a.ID.Eq(accountID)).First()
+	if err != nil {
+		return msg
+	}
+	if member, err := ua.WithContext(ctx).Where(ua.UserID.Eq(user.ID), ua.AccountID.Eq(accountID)).First(); err == nil {
+		msg.AccountID = accountID
+		msg.AccountName = account.Name
+		msg.RoleAccount = member.Role
+		msg.AccountAccessMode = member.AccessMode
+	}
+	return msg
+}
+
+func keyScopeLimited(scope *util.KeyScope) bool {
+	return len(scope.Roots) != 0 || len(scope.DatasetIDs) != 0
+}
+
+// 个人访问令牌只能访问指定的虚拟路径，只读令牌不能写入
+func limitPathScope(scope *util.KeyScope, name string, permission model.FilePermission) model.FilePermission {
+	if scope == nil || !permission.CanRead() {
+		return permission
+	}
+	name = strings.Trim(path.Clean("/"+name), "/")
+	if name != "" && keyScopeLimited(scope) {
+		allowed := false
+		for _, root := range scope.Roots {
+			if name == root || strings.HasPrefix(name, root+"/") {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return model.NotAllowed
+		}
+	}
+	if scope.ReadOnly {
+		return model.ReadOnly
+	}
+	return permission
+}
+
+func limitDatasetScope(scope *util.KeyScope, datasetID uint, permission model.FilePermission) model.FilePermission {
+	if scope == nil || permission == model.NotAllowed {
+		return permission
+	}
+	if keyScopeLimited(scope) {
+		allowed := false
+		for _, id := range scope.DatasetIDs {
+			if id == datasetID {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return model.NotAllowed
+		}
+	}
+	if scope.ReadOnly {
+		return model.ReadOnly
+	}
+	return permission
+}
+
+type CreateAPIKeyReq struct {
+	Name       string            `json:"name" binding:"required,max=128"`
+	Scope      model.APIKeyScope `json:"scope"`
+	Roots      []string          `json:"roots"`
+	DatasetIDs []uint            `json:"datasetIDs"`
+	ExpiresAt  *time.Time        `json:"expiresAt"`
+}
+
+type APIKeyResp struct {
+	ID         uint              `json:"id"`
+	Name       string            `json:"name"`
+	Key        string            `json:"key,omitempty"` // 只在创建时返回一次
+	Prefix     string            `json:"prefix"`
+	Scope      model.APIKeyScope `json:"scope"`
+	AccountID  uint              `json:"accountID"`
+	Roots      []string          `json:"roots"`
+	DatasetIDs []uint            `json:"datasetIDs"`
+	ExpiresAt  *time.Time        `json:"expiresAt"`
+	LastUsedAt *time.Time        `json:"lastUsedAt"`
+	CreatedAt  time.Time         `json:"createdAt"`
+}
+
+type APIKeyRequest struct {
+	ID uint `uri:"id" binding:"required"`
+}
+
+func toAPIKeyResp(k *model.APIKey) APIKeyResp {
+	roots, ids := k.Roots.Data(), k.DatasetIDs.Data()
+	if roots == nil {
+		roots = []string{}
+	}
+	if ids == nil {
+		ids = []uint{}
+	}
+	return APIKeyResp{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scope:      k.Scope,
+		AccountID:  k.AccountID,
+		Roots:      roots,
+		DatasetIDs: ids,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		CreatedAt:  k.CreatedAt,
+	}
+}
+
+// 管理个人访问令牌需要登录会话，不能用令牌本身创建或撤销令牌
+func checkSessionToken(c *gin.Context) (util.JWTMessage, bool) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return jwttoken, false
+	}
+	if jwttoken.Scope != nil {
+		response.HTTPError(c, http.StatusForbidden, "personal access tokens can't manage tokens", response.InvalidToken)
+		return jwttoken, false
+	}
+	return jwttoken, true
+}
+
+// 创建个人访问令牌，令牌明文只在响应中出现一次
+func CreateAPIKey(c *gin.Context) {
+	jwttoken, ok := checkSessionToken(c)
+	if !ok {
+		return
+	}
+	var req CreateAPIKeyReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequestError(c, err.Error())
+		return
+	}
+	if req.Scope == "" {
+		req.Scope = model.APIKeyReadOnly
+	}
+	if req.Scope != model.APIKeyReadOnly && req.Scope != model.APIKeyReadWrite {
+		response.BadRequestError(c, "scope must be ro or rw")
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		response.BadRequestError(c, "expiresAt must be in the future")
+		return
+	}
+	roots := make([]string, 0, len(req.Roots))
+	for _, r := range req.Roots {
+		root := strings.Trim(path.Clean("/"+r), "/")
+		if !containsString(apiKeyRoots, getFirstToken("/"+root)) {
+			response.BadRequestError(c, "invalid root: "+r)
+			return
+		}
+		if !GetPermission(root, jwttoken, c).CanRead() {
+			response.HTTPError(c, http.StatusUnauthorized, "You have no permission to access "+r, response.NotSpecified)
+			return
+		}
+		roots = append(roots, root)
+	}
+	for _, id := range req.DatasetIDs {
+		if GetDatasetPermission(c, id, jwttoken) == model.NotAllowed {
+			response.Error(c, fmt.Sprintf("dataset %d does not exist or you do not have permission", id), response.NotSpecified)
+			return
+		}
+	}
+	random, err := newRandomID(apiKeyBytes)
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	key := model.APIKeyPrefix + random
+	apiKey := &model.APIKey{
+		UserID:     jwttoken.UserID,
+		AccountID:  jwttoken.AccountID,
+		Name:       req.Name,
+		Prefix:     key[:apiKeyPrefixLen],
+		KeyHash:    hashAPIKey(key),
+		Scope:      req.Scope,
+		Roots:      datatypes.NewJSONType(roots),
+		DatasetIDs: datatypes.NewJSONType(req.DatasetIDs),
+		ExpiresAt:  req.ExpiresAt,
+	}
+	if err = query.APIKey.WithContext(c).Create(apiKey); err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	data := toAPIKeyResp(apiKey)
+	data.Key = key
+	response.Success(c, data)
+}
+
+// 列出自己的个人访问令牌
+func ListAPIKeys(c *gin.Context) {
+	jwttoken, ok := checkSessionToken(c)
+	if !ok {
+		return
+	}
+	k := query.APIKey
+	keys, err := k.WithContext(c).Where(k.UserID.Eq(jwttoken.UserID)).Order(k.CreatedAt.Desc()).Find()
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	data := make([]APIKeyResp, 0, len(keys))
+	for _, key := range keys {
+		data = append(data, toAPIKeyResp(key))
+	}
+	response.Success(c, data)
+}
+
+// 撤销个人访问令牌
+func DeleteAPIKey(c *gin.Context) {
+	jwttoken, ok := checkSessionToken(c)
+	if !ok {
+		return
+	}
+	var req APIKeyRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		response.HTTPError(c, http.StatusBadRequest, err.Error(), response.NotSpecified)
+		return
+	}
+	k := query.APIKey
+	info, err := k.WithContext(c).Where(k.ID.Eq(req.ID), k.UserID.Eq(jwttoken.UserID)).Delete()
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	if info.RowsAffected == 0 {
+		response.HTTPError(c, http.StatusNotFound, "token does not exist", response.NotSpecified)
+		return
+	}
+	response.Success(c, "revoke token successfully")
+}
+
+func RegisterAPIKey(webdavGroup *gin.RouterGroup) {
+	webdavGroup.POST("/keys", CreateAPIKey)
+	webdavGroup.GET("/keys", ListAPIKeys)
+	webdavGroup.DELETE("/keys/:id", DeleteAPIKey)
+}
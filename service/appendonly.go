@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+	"os"
+
+	"golang.org/x/net/webdav"
+)
+
+// appendOnlyFS 用于只能追加的空间：新建文件时不会截断或覆盖已有文件，
+// 删除和重命名一律拒绝
+type appendOnlyFS struct {
+	webdav.FileSystem
+}
+
+func (a appendOnlyFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR) != 0 {
+		if flag&os.O_CREATE == 0 {
+			return nil, os.ErrPermission
+		}
+		flag |= os.O_EXCL
+	}
+	return a.FileSystem.OpenFile(ctx, name, flag, perm)
+}
+
+func (a appendOnlyFS) RemoveAll(context.Context, string) error {
+	return os.ErrPermission
+}
+
+func (a appendOnlyFS) Rename(context.Context, string, string) error {
+	return os.ErrPermission
+}
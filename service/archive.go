@@ -0,0 +1,412 @@
+package service
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"webdav/dao/model"
+	"webdav/dao/query"
+	"webdav/logutils"
+	"webdav/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	ArchiveZip   = "zip"
+	ArchiveTarGz = "tar.gz"
+)
+
+type archiveWriter interface {
+	addDir(name string, fi os.FileInfo) error
+	addFile(name string, fi os.FileInfo, r io.Reader) error
+	Close() error
+}
+
+type zipArchive struct {
+	w *zip.Writer
+}
+
+func (z *zipArchive) addDir(name string, fi os.FileInfo) error {
+	hdr, err := zip.FileInfoHeader(fi)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name + "/"
+	_, err = z.w.CreateHeader(hdr)
+	return err
+}
+
+func (z *zipArchive) addFile(name string, fi os.FileInfo, r io.Reader) error {
+	hdr, err := zip.FileInfoHeader(fi)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	hdr.Method = zip.Deflate
+	w, err := z.w.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+func (z *zipArchive) Close() error {
+	return z.w.Close()
+}
+
+type tarGzArchive struct {
+	gz *gzip.Writer
+	w  *tar.Writer
+}
+
+func (t *tarGzArchive) addDir(name string, fi os.FileInfo) error {
+	hdr, err := tar.FileInfoHeader(fi, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = name + "/"
+	return t.w.WriteHeader(hdr)
+}
+
+func (t *tarGzArchive) addFile(name string, fi os.FileInfo, r io.Reader) error {
+	hdr, err := tar.FileInfoHeader(fi, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if err = t.w.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.CopyN(t.w, r, hdr.Size)
+	return err
+}
+
+func (t *tarGzArchive) Close() error {
+	if err := t.w.Close(); err != nil {
+		return err
+	}
+	return t.gz.Close()
+}
+
+// 打包时的文件筛选规则，不含 / 的规则匹配文件名，否则匹配相对路径
+type archiveFilter struct {
+	include []string
+	exclude []string
+}
+
+func parseGlobList(values []string) ([]string, error) {
+	var patterns []string
+	for _, v := range values {
+		for _, p := range strings.Split(v, ",") {
+			p = strings.Trim(strings.TrimSpace(p), "/")
+			if p == "" {
+				continue
+			}
+			if _, err := path.Match(p, ""); err != nil {
+				return nil, err
+			}
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns, nil
+}
+
+func matchGlobs(patterns []string, rel string) bool {
+	base := path.Base(rel)
+	for _, p := range patterns {
+		target := rel
+		if !strings.Contains(p, "/") {
+			target = base
+		}
+		if ok, _ := path.Match(p, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *archiveFilter) excluded(rel string) bool {
+	return matchGlobs(f.exclude, rel)
+}
+
+func (f *archiveFilter) included(rel string) bool {
+	return len(f.include) == 0 || matchGlobs(f.include, rel)
+}
+
+// 将 root 目录（或单个文件）打包写入 w，压缩包内的路径以 name 为顶层目录。r 不为空时报告进度
+func writeArchive(ctx context.Context, aw archiveWriter, root, name string, filter *archiveFilter, r *runningJob) error {
+	fi, err := fs.FileSystem.Stat(ctx, root)
+	if err != nil {
+		return err
+	}
+	isSpaceRoot := spaceRootOf(root) == cleanRealPath(root)
+	return walkFS(ctx, root, fi, func(p string, fi os.FileInfo) error {
+		rel := strings.TrimPrefix(strings.TrimPrefix(p, root), "/")
+		entry := path.Join(name, rel)
+		// 回收站中的文件已被删除，不打包
+		if isSpaceRoot && fi.IsDir() && rel == model.TrashDir {
+			return filepath.SkipDir
+		}
+		if rel != "" && filter.excluded(rel) {
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if fi.IsDir() {
+			if len(filter.include) != 0 {
+				return nil
+			}
+			return aw.addDir(entry, fi)
+		}
+		// 只打包普通文件，符号链接等可能指向空间之外
+		if !fi.Mode().IsRegular() || (rel != "" && !filter.included(rel)) {
+			return nil
+		}
+		f, err := fs.FileSystem.OpenFile(ctx, p, os.O_RDONLY, 0)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if r == nil {
+			return aw.addFile(entry, fi, f)
+		}
+		if err = aw.addFile(entry, fi, ctxReader{ctx: ctx, r: f, n: &r.doneBytes}); err != nil {
+			return err
+		}
+		r.doneFiles.Add(1)
+		return nil
+	})
+}
+
+func newArchiveWriter(w io.Writer, format string) archiveWriter {
+	if format == ArchiveZip {
+		return &zipArchive{w: zip.NewWriter(w)}
+	}
+	gz := gzip.NewWriter(w)
+	return &tarGzArchive{gz: gz, w: tar.NewWriter(gz)}
+}
+
+// 解析 format、include、exclude 参数，出错时已写入响应
+func parseArchiveOptions(c *gin.Context) (format string, filter archiveFilter, ok bool) {
+	format = c.DefaultQuery("format", ArchiveZip)
+	if format != ArchiveZip && format != ArchiveTarGz {
+		response.BadRequestError(c, "format must be zip or tar.gz")
+		return "", filter, false
+	}
+	var err error
+	if filter.include, err = parseGlobList(c.QueryArray("include")); err != nil {
+		response.BadRequestError(c, "invalid include pattern: "+err.Error())
+		return "", filter, false
+	}
+	if filter.exclude, err = parseGlobList(c.QueryArray("exclude")); err != nil {
+		response.BadRequestError(c, "invalid exclude pattern: "+err.Error())
+		return "", filter, false
+	}
+	return format, filter, true
+}
+
+func archiveName(name string) string {
+	if name == "" || name == "." || name == "/" {
+		return "archive"
+	}
+	return name
+}
+
+// 按 format、include、exclude 参数将实际路径 root 打包后流式返回，不在磁盘上生成临时文件
+func streamArchive(c *gin.Context, root, name string) {
+	format, filter, ok := parseArchiveOptions(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	if _, err := fs.FileSystem.Stat(ctx, root); err != nil {
+		response.BadRequestError(c, "can't find file")
+		return
+	}
+	name = archiveName(name)
+
+	if format == ArchiveZip {
+		c.Header("Content-Type", "application/zip")
+	} else {
+		c.Header("Content-Type", "application/gzip")
+	}
+	aw := newArchiveWriter(c.Writer, format)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + "." + format}))
+	c.Status(http.StatusOK)
+
+	// 响应已经开始写出，出错时只能记录日志并中断连接
+	if err := writeArchive(ctx, aw, root, name, &filter, nil); err != nil && !errors.Is(err, context.Canceled) {
+		logutils.Log.Errorf("archive %s: %v", root, err)
+		panic(http.ErrAbortHandler)
+	}
+	if err := aw.Close(); err != nil {
+		logutils.Log.Errorf("archive %s: %v", root, err)
+	}
+}
+
+// 在后台将目录打包到 ArchivePrefix/<任务 ID>/ 下，完成后通过 /jobs/:id/result 下载
+func runArchiveJob(ctx context.Context, r *runningJob) error {
+	params := r.job.Params.Data()
+	dir := path.Join(model.ArchivePrefix, strconv.FormatUint(uint64(r.job.ID), 10))
+	// 重试时清理上次留下的文件
+	if err := os.RemoveAll(osPath(dir)); err != nil {
+		return err
+	}
+	if err := fs.FileSystem.Mkdir(ctx, dir, model.DefaultFolderPerm); err != nil {
+		return err
+	}
+	if bytes, files, uerr := treeUsage(ctx, params.RealSrc); uerr == nil {
+		r.setTotal(bytes, files)
+	}
+	target := path.Join(dir, params.Name+"."+params.Format)
+	err := func() error {
+		f, err := fs.FileSystem.OpenFile(ctx, target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, model.DefaultFilePerm)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		aw := newArchiveWriter(f, params.Format)
+		filter := archiveFilter{include: params.Include, exclude: params.Exclude}
+		if err = writeArchive(ctx, aw, params.RealSrc, params.Name, &filter, r); err != nil {
+			return err
+		}
+		if err = aw.Close(); err != nil {
+			return err
+		}
+		return f.Close()
+	}()
+	if err != nil {
+		if rerr := os.RemoveAll(osPath(dir)); rerr != nil {
+			logutils.Log.Errorf("remove archive %s: %v", dir, rerr)
+		}
+		return err
+	}
+	r.result = target
+	return nil
+}
+
+// 提交打包任务，适合浏览器下载中断后无法续传的大目录
+func submitArchiveJob(c *gin.Context, userID uint, root, name, src string, datasetID uint) {
+	format, filter, ok := parseArchiveOptions(c)
+	if !ok {
+		return
+	}
+	if _, err := fs.FileSystem.Stat(c, root); err != nil {
+		response.BadRequestError(c, "can't find file")
+		return
+	}
+	job, err := submitJob(c, userID, model.FileJobArchive, model.FileJobParams{
+		Src:       src,
+		RealSrc:   root,
+		DatasetID: datasetID,
+		Format:    format,
+		Include:   filter.include,
+		Exclude:   filter.exclude,
+		Name:      archiveName(name),
+	}, 0, 0)
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	response.Success(c, toJobResp(waitJob(c, job, jobInlineWait)))
+}
+
+// 打包下载目录
+func DownloadArchive(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	param := strings.TrimPrefix(c.Request.URL.Path, "/api/ss/archive/")
+	permission := GetPermission(param, jwttoken, c)
+	if !permission.CanRead() {
+		permissionDenied(c, param, jwttoken, "Your permission is notAllowed", response.NotSpecified)
+		return
+	}
+	realPath, err := Redirect(c, param, jwttoken)
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	streamArchive(c, realPath, path.Base(strings.Trim(param, "/")))
+}
+
+// 在后台打包目录
+func CreateArchiveJob(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	param := strings.TrimPrefix(c.Request.URL.Path, "/api/ss/archive/")
+	if !GetPermission(param, jwttoken, c).CanRead() {
+		permissionDenied(c, param, jwttoken, "Your permission is notAllowed", response.NotSpecified)
+		return
+	}
+	realPath, err := Redirect(c, param, jwttoken)
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	src := strings.Trim(param, "/")
+	submitArchiveJob(c, jwttoken.UserID, realPath, path.Base(src), src, 0)
+}
+
+// 打包下载数据集
+func DownloadDatasetArchive(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	var datasetReq DatasetRequest
+	if err = c.ShouldBindUri(&datasetReq); err != nil {
+		response.HTTPError(c, http.StatusBadRequest, err.Error(), response.NotSpecified)
+		return
+	}
+	permission := GetDatasetPermission(c, datasetReq.ID, jwttoken)
+	if permission == model.NotAllowed {
+		response.Error(c, "This dataset does not exist or you do not have permission", response.NotSpecified)
+		return
+	}
+	d := query.Dataset
+	dataset, err := d.WithContext(c).Where(d.ID.Eq(datasetReq.ID)).First()
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	root, err := datasetRoot(c, datasetReq.ID)
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	name := strings.ReplaceAll(dataset.Name, "/", "_")
+	if v := c.Query("version"); v != "" {
+		name += "-v" + v
+	}
+	if c.Request.Method == http.MethodPost {
+		submitArchiveJob(c, jwttoken.UserID, root, name, "", dataset.ID)
+		return
+	}
+	streamArchive(c, root, name)
+}
+
+func RegisterArchive(webdavGroup *gin.RouterGroup) {
+	webdavGroup.GET("/archive/*path", DownloadArchive)
+	webdavGroup.POST("/archive/*path", CreateArchiveJob)
+	webdavGroup.POST("/datasets/:id/archive", DownloadDatasetArchive)
+}
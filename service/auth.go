@@ -0,0 +1,69 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"webdav/dao/query"
+	"webdav/response"
+	"webdav/util"
+
+	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+type RefreshTokenReq struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+type RefreshTokenResp struct {
+	AccessToken string `json:"accessToken"`
+}
+
+// 使用刷新令牌换取新的访问令牌，长时间运行的上传脚本可以借此续期
+func RefreshAccessToken(c *gin.Context) {
+	var req RefreshTokenReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequestError(c, err.Error())
+		return
+	}
+	msg, err := util.GetTokenMgr().CheckRefreshToken(req.RefreshToken)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		response.HTTPError(c, http.StatusUnauthorized, "refresh token has expired", response.TokenExpired)
+		return
+	}
+	if err != nil {
+		response.HTTPError(c, http.StatusUnauthorized, "invalid refresh token", response.InvalidToken)
+		return
+	}
+	if err = checkTokenActive(c, msg); err != nil {
+		if errors.Is(err, ErrTokenRevoked) || errors.Is(err, ErrUserNotActive) {
+			response.HTTPError(c, http.StatusUnauthorized, err.Error(), response.InvalidToken)
+			return
+		}
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	// 签发刷新令牌后角色、账户成员和访问模式可能已经变化，按数据库中的当前状态签发
+	u := query.User
+	user, err := u.WithContext(c).Where(u.ID.Eq(msg.UserID)).First()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		response.HTTPError(c, http.StatusUnauthorized, ErrUserNotActive.Error(), response.InvalidToken)
+		return
+	}
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	claims := userClaims(c, user, msg.AccountID)
+	accessToken, err := util.GetTokenMgr().CreateAccessToken(&claims)
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	response.Success(c, RefreshTokenResp{AccessToken: accessToken})
+}
+
+func RegisterAuth(webdavGroup *gin.RouterGroup) {
+	webdavGroup.POST("/auth/refresh", RefreshAccessToken)
+}
@@ -0,0 +1,535 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"webdav/dao/model"
+	"webdav/logutils"
+	"webdav/response"
+	"webdav/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BatchOpType string
+
+const (
+	BatchDelete BatchOpType = "delete"
+	BatchMove   BatchOpType = "move"
+	BatchCopy   BatchOpType = "copy"
+	BatchMkdir  BatchOpType = "mkdir"
+	BatchRename BatchOpType = "rename"
+)
+
+var (
+	errBatchNotExecuted = errors.New("not executed")
+	errBatchRolledBack  = errors.New("rolled back")
+)
+
+type BatchOp struct {
+	Op        BatchOpType `json:"op"`
+	Path      string      `json:"path"`
+	Dst       string      `json:"dst"`       // move、copy 的目标路径
+	Name      string      `json:"name"`      // rename 的新文件名
+	Permanent bool        `json:"permanent"` // delete 时不放入回收站
+}
+
+type BatchReq struct {
+	Ops []BatchOp `json:"ops" binding:"required,min=1,max=1000"`
+	// 为真时先检查所有操作，全部通过后才执行，执行失败时撤销已完成的操作
+	Atomic bool `json:"atomic"`
+}
+
+type BatchItemResp struct {
+	Index   int         `json:"index"`
+	Op      BatchOpType `json:"op"`
+	Path    string      `json:"path"`
+	Success bool        `json:"success"`
+	Error   string      `json:"error,omitempty"`
+	Job     *JobResp    `json:"job,omitempty"` // 非原子模式下不被后面的操作依赖的 move、copy 在后台执行，返回任务
+}
+
+type BatchResp struct {
+	Succeeded int             `json:"succeeded"`
+	Failed    int             `json:"failed"`
+	Items     []BatchItemResp `json:"items"`
+}
+
+// 虚拟路径第一段对应的权限和实际路径，一次批量操作中每个根只解析一次
+type batchRoot struct {
+	permission model.FilePermission
+	realPath   string
+	err        error
+}
+
+type batchTarget struct {
+	path       string // 清理后的虚拟路径
+	realPath   string
+	permission model.FilePermission
+}
+
+type batchStep struct {
+	index    int
+	op       BatchOp
+	src, dst batchTarget
+	trash    *model.TrashItem
+	undo     func(ctx context.Context) error
+}
+
+type batchContext struct {
+	c     *gin.Context
+	token util.JWTMessage
+	roots map[string]*batchRoot
+	// 前面的操作将要删除和创建的实际路径，后面的操作据此检查，不必等待后台任务完成
+	removed map[string]bool
+	created map[string]bool
+	atomic  bool
+}
+
+func (b *batchContext) resolve(p string) (batchTarget, error) {
+	var t batchTarget
+	t.path = strings.Trim(path.Clean("/"+p), "/")
+	if t.path == "" {
+		return t, fmt.Errorf("an incorrect path")
+	}
+	name, rest, _ := strings.Cut(t.path, "/")
+	root, ok := b.roots[name]
+	if !ok {
+		root = &batchRoot{permission: getPermission(name, b.token, b.c)}
+		if root.permission.CanRead() {
+			root.realPath, root.err = Redirect(b.c, name, b.token)
+		}
+		b.roots[name] = root
+	}
+	t.permission = limitPathScope(b.token.Scope, t.path, root.permission)
+	if !t.permission.CanRead() {
+		return t, b.denied(t, "access")
+	}
+	if root.err != nil {
+		return t, root.err
+	}
+	t.realPath = root.realPath
+	if rest != "" {
+		t.realPath += "/" + rest
+	}
+	t.realPath = cleanRealPath(t.realPath)
+	if isTrashPath(t.realPath) {
+		return t, fmt.Errorf("an illegal path")
+	}
+	return t, nil
+}
+
+func (b *batchContext) denied(t batchTarget, action string) error {
+	if reason := accessDeniedReason(b.c, t.path, b.token); reason != nil {
+		return reason
+	}
+	return fmt.Errorf("you have no permission to %s %s", action, t.path)
+}
+
+// 考虑前面的操作之后 realPath 是否存在，从自身往上找最近一个被删除或创建的路径
+func (b *batchContext) exists(realPath string) (bool, error) {
+	for p := realPath; p != "/"; p = path.Dir(p) {
+		if b.created[p] {
+			return p == realPath, nil
+		}
+		if b.removed[p] {
+			return false, nil
+		}
+	}
+	if _, err := fs.FileSystem.Stat(b.c, realPath); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (b *batchContext) remove(realPath string) {
+	b.removed[realPath] = true
+	for p := range b.created {
+		if p == realPath || strings.HasPrefix(p, realPath+"/") {
+			delete(b.created, p)
+		}
+	}
+}
+
+func (b *batchContext) create(realPath string) {
+	b.created[realPath] = true
+	delete(b.removed, realPath)
+}
+
+func (b *batchContext) source(p, action string, needModify bool) (batchTarget, error) {
+	t, err := b.resolve(p)
+	if err != nil {
+		return t, err
+	}
+	if needModify && !t.permission.CanModify() {
+		return t, b.denied(t, action)
+	}
+	ok, err := b.exists(t.realPath)
+	if err != nil {
+		return t, err
+	}
+	if !ok {
+		return t, fmt.Errorf("%s does not exist", t.path)
+	}
+	return t, nil
+}
+
+// 目标不能已经存在，且所在目录必须存在
+func (b *batchContext) target(p, action string) (batchTarget, error) {
+	t, err := b.resolve(p)
+	if err != nil {
+		return t, err
+	}
+	// 只能追加的位置也可以新建
+	if !t.permission.CanCreate() {
+		return t, b.denied(t, action+" files to")
+	}
+	ok, err := b.exists(t.realPath)
+	if err != nil {
+		return t, err
+	}
+	if ok {
+		return t, fmt.Errorf("%s already exists", t.path)
+	}
+	if ok, err = b.exists(path.Dir(t.realPath)); err != nil {
+		return t, err
+	} else if !ok {
+		return t, fmt.Errorf("the parent directory of %s does not exist", t.path)
+	}
+	return t, nil
+}
+
+// 同步执行的 move、copy 在执行前检查目标空间的配额，后台任务在任务中检查
+func checkStepQuota(ctx context.Context, step *batchStep) error {
+	if spaceRootOf(step.dst.realPath) == "" ||
+		(step.op.Op == BatchMove && spaceRootOf(step.src.realPath) == spaceRootOf(step.dst.realPath)) {
+		return nil
+	}
+	bytes, _, err := treeUsage(ctx, step.src.realPath)
+	if err != nil {
+		return err
+	}
+	return checkQuota(ctx, step.dst.realPath, bytes)
+}
+
+// 检查一个操作能否执行，并记录它将删除和创建的路径
+func (b *batchContext) prepare(index int, op BatchOp) (*batchStep, error) {
+	step := &batchStep{index: index, op: op}
+	var err error
+	switch op.Op {
+	case BatchDelete:
+		if step.src, err = b.source(op.Path, "delete", true); err != nil {
+			return step, err
+		}
+		// 原子模式下先放入回收站，以便撤销
+		if b.atomic && !canMoveToTrash(step.src.realPath) {
+			return step, fmt.Errorf("%s can't be deleted in an atomic batch", step.src.path)
+		}
+		if err = checkDeletable(step.src.path, step.src.realPath, op.Permanent || b.atomic); err != nil {
+			return step, err
+		}
+		b.remove(step.src.realPath)
+	case BatchMove, BatchCopy:
+		if step.src, err = b.source(op.Path, string(op.Op), op.Op == BatchMove); err != nil {
+			return step, err
+		}
+		if op.Op == BatchMove && !strings.Contains(step.src.path, "/") {
+			return step, fmt.Errorf("can't move %s", step.src.path)
+		}
+		if op.Dst == "" {
+			return step, fmt.Errorf("dst is required")
+		}
+		if step.dst, err = b.target(op.Dst, string(op.Op)); err != nil {
+			return step, err
+		}
+		if strings.HasPrefix(step.dst.realPath, step.src.realPath+"/") {
+			return step, fmt.Errorf("can't %s a directory into itself", op.Op)
+		}
+		if op.Op == BatchMove {
+			b.remove(step.src.realPath)
+		}
+		b.create(step.dst.realPath)
+	case BatchMkdir:
+		if step.dst, err = b.target(op.Path, "create"); err != nil {
+			return step, err
+		}
+		b.create(step.dst.realPath)
+	case BatchRename:
+		if step.src, err = b.source(op.Path, "rename", true); err != nil {
+			return step, err
+		}
+		if !strings.Contains(step.src.path, "/") {
+			return step, fmt.Errorf("can't rename %s", step.src.path)
+		}
+		if op.Name == "" || op.Name == "." || op.Name == ".." || strings.Contains(op.Name, "/") {
+			return step, fmt.Errorf("invalid name %q", op.Name)
+		}
+		if step.dst, err = b.target(path.Join(path.Dir(step.src.path), op.Name), "rename"); err != nil {
+			return step, err
+		}
+		b.remove(step.src.realPath)
+		b.create(step.dst.realPath)
+	default:
+		return step, fmt.Errorf("unknown op %q", op.Op)
+	}
+	return step, nil
+}
+
+// 非原子模式下执行一个操作，move 和 copy 提交后台任务。
+// 后面的操作依赖它的结果时同步执行，否则后面的操作会在任务完成之前执行
+func (b *batchContext) run(step *batchStep, sync bool) (*JobResp, error) {
+	ctx := b.c.Request.Context()
+	switch step.op.Op {
+	case BatchDelete:
+		return nil, deletePath(ctx, step.src.path, step.src.realPath, b.token.UserID, step.op.Permanent)
+	case BatchMove, BatchCopy:
+		if sync {
+			if err := checkStepQuota(ctx, step); err != nil {
+				return nil, err
+			}
+			if step.op.Op == BatchMove {
+				return nil, moveWithUsage(ctx, step.src.realPath, step.dst.realPath, nil)
+			}
+			return nil, copyWithUsage(ctx, step.src.realPath, step.dst.realPath, nil)
+		}
+		jobType := model.FileJobMove
+		if step.op.Op == BatchCopy {
+			jobType = model.FileJobCopy
+		}
+		job, err := submitJob(ctx, b.token.UserID, jobType, model.FileJobParams{
+			Src:     step.src.path,
+			Dst:     step.dst.path,
+			RealSrc: step.src.realPath,
+			RealDst: step.dst.realPath,
+		}, 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		data := toJobResp(job)
+		return &data, nil
+	case BatchMkdir:
+		defer invalidateDU(step.dst.realPath)
+		return nil, fs.FileSystem.Mkdir(ctx, step.dst.realPath, model.RWXFolderPerm)
+	case BatchRename:
+		defer invalidateDU(step.src.realPath)
+		return nil, fs.FileSystem.Rename(ctx, step.src.realPath, step.dst.realPath)
+	}
+	return nil, fmt.Errorf("unknown op %q", step.op.Op)
+}
+
+// 原子模式下同步执行一个操作，并记录撤销的方法
+func (b *batchContext) runUndoable(step *batchStep) error {
+	ctx := b.c.Request.Context()
+	src, dst := step.src.realPath, step.dst.realPath
+	switch step.op.Op {
+	case BatchDelete:
+		item, err := moveToTrash(ctx, step.src.path, src, b.token.UserID)
+		if err != nil {
+			return err
+		}
+		step.trash = item
+		step.undo = func(ctx context.Context) error { return restoreTrashItem(ctx, item) }
+		return nil
+	case BatchMove:
+		if err := checkStepQuota(ctx, step); err != nil {
+			return err
+		}
+		if err := moveWithUsage(ctx, src, dst, nil); err != nil {
+			return err
+		}
+		step.undo = func(ctx context.Context) error { return moveWithUsage(ctx, dst, src, nil) }
+		return nil
+	case BatchCopy:
+		if err := checkStepQuota(ctx, step); err != nil {
+			return err
+		}
+		if err := copyWithUsage(ctx, src, dst, nil); err != nil {
+			return err
+		}
+		step.undo = func(ctx context.Context) error { return deletePath(ctx, step.dst.path, dst, b.token.UserID, true) }
+		return nil
+	case BatchMkdir:
+		defer invalidateDU(dst)
+		if err := fs.FileSystem.Mkdir(ctx, dst, model.RWXFolderPerm); err != nil {
+			return err
+		}
+		step.undo = func(ctx context.Context) error { return fs.FileSystem.RemoveAll(ctx, dst) }
+		return nil
+	case BatchRename:
+		defer invalidateDU(src)
+		if err := fs.FileSystem.Rename(ctx, src, dst); err != nil {
+			return err
+		}
+		step.undo = func(ctx context.Context) error { return fs.FileSystem.Rename(ctx, dst, src) }
+		return nil
+	}
+	return fmt.Errorf("unknown op %q", step.op.Op)
+}
+
+// 先检查全部操作，再依次执行，检查失败的操作不执行
+func (b *batchContext) runAll(ops []BatchOp, items []BatchItemResp) {
+	steps := make([]*batchStep, len(ops))
+	for i, op := range ops {
+		step, err := b.prepare(i, op)
+		if err != nil {
+			setBatchResult(&items[i], err)
+			continue
+		}
+		steps[i] = step
+	}
+	for i, step := range steps {
+		if step == nil {
+			continue
+		}
+		job, err := b.run(step, dependedOn(steps, i))
+		items[i].Job = job
+		setBatchResult(&items[i], err)
+	}
+}
+
+// 后面的操作是否涉及第 i 个操作的源或目标路径
+func dependedOn(steps []*batchStep, i int) bool {
+	for _, later := range steps[i+1:] {
+		if later == nil {
+			continue
+		}
+		for _, p := range []string{steps[i].src.realPath, steps[i].dst.realPath} {
+			for _, q := range []string{later.src.realPath, later.dst.realPath} {
+				if p != "" && q != "" && pathsOverlap(p, q) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// 两个路径相同或一个在另一个之下
+func pathsOverlap(p, q string) bool {
+	return p == q || strings.HasPrefix(p, q+"/") || strings.HasPrefix(q, p+"/")
+}
+
+// 全部检查通过后依次执行，某一步失败时按相反顺序撤销已完成的操作
+func (b *batchContext) runAtomic(ops []BatchOp, items []BatchItemResp) {
+	steps := make([]*batchStep, len(ops))
+	valid := true
+	for i, op := range ops {
+		step, err := b.prepare(i, op)
+		if err != nil {
+			valid = false
+			setBatchResult(&items[i], err)
+			continue
+		}
+		steps[i] = step
+	}
+	if !valid {
+		for i := range items {
+			if items[i].Error == "" {
+				setBatchResult(&items[i], errBatchNotExecuted)
+			}
+		}
+		return
+	}
+	for i, step := range steps {
+		if err := b.runUndoable(step); err != nil {
+			setBatchResult(&items[i], err)
+			b.rollback(steps[:i], items)
+			for j := i + 1; j < len(steps); j++ {
+				setBatchResult(&items[j], errBatchNotExecuted)
+			}
+			return
+		}
+	}
+	// 全部成功后才彻底删除，失败时还能从回收站恢复
+	ctx := context.WithoutCancel(b.c.Request.Context())
+	for i, step := range steps {
+		if step.trash != nil && step.op.Permanent {
+			if err := purgeTrashItem(ctx, step.trash); err != nil {
+				logutils.Log.Warnf("purge trash item %d: %v", step.trash.ID, err)
+			}
+		}
+		setBatchResult(&items[i], nil)
+	}
+}
+
+func (b *batchContext) rollback(done []*batchStep, items []BatchItemResp) {
+	// 撤销不响应请求的取消，否则会留下一半的结果
+	ctx := context.WithoutCancel(b.c.Request.Context())
+	for i := len(done) - 1; i >= 0; i-- {
+		step := done[i]
+		err := errBatchRolledBack
+		uerr := step.undo(ctx)
+		for _, t := range []batchTarget{step.src, step.dst} {
+			if t.realPath != "" {
+				invalidateDU(t.realPath)
+			}
+		}
+		if uerr != nil {
+			logutils.Log.Errorf("roll back %s %s: %v", step.op.Op, step.op.Path, uerr)
+			err = fmt.Errorf("executed but failed to roll back: %w", uerr)
+		}
+		setBatchResult(&items[step.index], err)
+	}
+}
+
+func setBatchResult(item *BatchItemResp, err error) {
+	item.Success = err == nil
+	item.Error = ""
+	if err != nil {
+		item.Error = err.Error()
+	}
+}
+
+// 批量删除、移动、复制、创建目录和重命名，返回每个操作的结果。
+// 非原子模式下各操作独立执行，失败不影响后面的操作
+func BatchFiles(c *gin.Context) {
+	AlloweOption(c)
+	checkfs()
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	var req BatchReq
+	if err = c.ShouldBindJSON(&req); err != nil {
+		response.BadRequestError(c, err.Error())
+		return
+	}
+	b := &batchContext{
+		c:       c,
+		token:   jwttoken,
+		roots:   make(map[string]*batchRoot),
+		removed: make(map[string]bool),
+		created: make(map[string]bool),
+		atomic:  req.Atomic,
+	}
+	items := make([]BatchItemResp, len(req.Ops))
+	for i, op := range req.Ops {
+		items[i] = BatchItemResp{Index: i, Op: op.Op, Path: op.Path}
+	}
+	if req.Atomic {
+		b.runAtomic(req.Ops, items)
+	} else {
+		b.runAll(req.Ops, items)
+	}
+	data := BatchResp{Items: items}
+	for i := range items {
+		if items[i].Success {
+			data.Succeeded++
+		} else {
+			data.Failed++
+		}
+	}
+	response.Success(c, data)
+}
+
+func RegisterBatch(webdavGroup *gin.RouterGroup) {
+	webdavGroup.POST("/batch", BatchFiles)
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"webdav/config"
+	"webdav/dao/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTestBatch(t *testing.T, atomic bool) *batchContext {
+	t.Helper()
+	dir := useTempFS(t)
+	for name, content := range map[string]string{
+		"data/a.txt":     "aaaa",
+		"data/dir/b.txt": "bb",
+		"ro/c.txt":       "c",
+	} {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/api/ss/batch", nil)
+	return &batchContext{
+		c: c,
+		// 预先填好根目录，不查询数据库
+		roots: map[string]*batchRoot{
+			"data": {permission: model.ReadWrite, realPath: "/data"},
+			"ro":   {permission: model.ReadOnly, realPath: "/ro"},
+			"none": {permission: model.NotAllowed},
+		},
+		removed: make(map[string]bool),
+		created: make(map[string]bool),
+		atomic:  atomic,
+	}
+}
+
+func TestBatchPrepare(t *testing.T) {
+	tests := []struct {
+		name   string
+		atomic bool
+		ops    []BatchOp
+		errs   []string // 每个操作的错误应包含的内容，为空表示检查通过
+	}{
+		{"delete", false, []BatchOp{{Op: BatchDelete, Path: "data/a.txt", Permanent: true}}, []string{""}},
+		{"delete outside spaces", false, []BatchOp{{Op: BatchDelete, Path: "data/a.txt"}}, []string{"permanent=true"}},
+		{"delete missing", false, []BatchOp{{Op: BatchDelete, Path: "data/nope"}}, []string{"does not exist"}},
+		{"delete twice", false, []BatchOp{
+			{Op: BatchDelete, Path: "data/a.txt", Permanent: true},
+			{Op: BatchDelete, Path: "/data//a.txt", Permanent: true},
+		}, []string{"", "does not exist"}},
+		{"delete read-only", false, []BatchOp{{Op: BatchDelete, Path: "ro/c.txt"}}, []string{"no permission to delete"}},
+		{"no access", false, []BatchOp{{Op: BatchCopy, Path: "none/x", Dst: "data/x"}}, []string{"no permission to access"}},
+		{"empty path", false, []BatchOp{{Op: BatchDelete, Path: "/"}}, []string{"incorrect path"}},
+		{"move then rename", false, []BatchOp{
+			{Op: BatchMove, Path: "data/a.txt", Dst: "data/x.txt"},
+			{Op: BatchRename, Path: "data/x.txt", Name: "y.txt"},
+			{Op: BatchCopy, Path: "data/y.txt", Dst: "data/z.txt"},
+		}, []string{"", "", ""}},
+		{"moved source is gone", false, []BatchOp{
+			{Op: BatchMove, Path: "data/a.txt", Dst: "data/x.txt"},
+			{Op: BatchCopy, Path: "data/a.txt", Dst: "data/z.txt"},
+		}, []string{"", "does not exist"}},
+		{"copy from read-only", false, []BatchOp{{Op: BatchCopy, Path: "ro/c.txt", Dst: "data/c.txt"}}, []string{""}},
+		{"move from read-only", false, []BatchOp{{Op: BatchMove, Path: "ro/c.txt", Dst: "data/c.txt"}}, []string{"no permission to move"}},
+		{"copy to read-only", false, []BatchOp{{Op: BatchCopy, Path: "data/a.txt", Dst: "ro/a.txt"}}, []string{"no permission to copy files to"}},
+		{"dst exists", false, []BatchOp{{Op: BatchCopy, Path: "data/a.txt", Dst: "data/dir"}}, []string{"already exists"}},
+		{"dst required", false, []BatchOp{{Op: BatchCopy, Path: "data/a.txt"}}, []string{"dst is required"}},
+		{"missing parent", false, []BatchOp{{Op: BatchCopy, Path: "data/a.txt", Dst: "data/new/a.txt"}}, []string{"parent directory"}},
+		{"mkdir then copy into it", false, []BatchOp{
+			{Op: BatchMkdir, Path: "data/new"},
+			{Op: BatchCopy, Path: "data/a.txt", Dst: "data/new/a.txt"},
+		}, []string{"", ""}},
+		{"delete parent then mkdir inside", false, []BatchOp{
+			{Op: BatchDelete, Path: "data/dir", Permanent: true},
+			{Op: BatchMkdir, Path: "data/dir/sub"},
+		}, []string{"", "parent directory"}},
+		{"delete then recreate", false, []BatchOp{
+			{Op: BatchDelete, Path: "data/dir", Permanent: true},
+			{Op: BatchMkdir, Path: "data/dir"},
+			{Op: BatchMkdir, Path: "data/dir/sub"},
+		}, []string{"", "", ""}},
+		{"recreated dir is empty", false, []BatchOp{
+			{Op: BatchDelete, Path: "data/dir", Permanent: true},
+			{Op: BatchMkdir, Path: "data/dir"},
+			{Op: BatchDelete, Path: "data/dir/b.txt", Permanent: true},
+		}, []string{"", "", "does not exist"}},
+		{"copy into itself", false, []BatchOp{{Op: BatchCopy, Path: "data/dir", Dst: "data/dir/sub"}}, []string{"into itself"}},
+		{"move root", false, []BatchOp{{Op: BatchMove, Path: "data", Dst: "data/sub"}}, []string{"can't move data"}},
+		{"rename root", false, []BatchOp{{Op: BatchRename, Path: "data", Name: "x"}}, []string{"can't rename data"}},
+		{"invalid name", false, []BatchOp{
+			{Op: BatchRename, Path: "data/a.txt", Name: "x/y"},
+			{Op: BatchRename, Path: "data/a.txt", Name: ".."},
+			{Op: BatchRename, Path: "data/a.txt"},
+		}, []string{"invalid name", "invalid name", "invalid name"}},
+		{"rename onto existing", false, []BatchOp{{Op: BatchRename, Path: "data/a.txt", Name: "dir"}}, []string{"already exists"}},
+		{"unknown op", false, []BatchOp{{Op: "chmod", Path: "data/a.txt"}}, []string{"unknown op"}},
+		{"atomic delete outside spaces", true, []BatchOp{{Op: BatchDelete, Path: "data/a.txt"}}, []string{"atomic batch"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTestBatch(t, tt.atomic)
+			for i, op := range tt.ops {
+				_, err := b.prepare(i, op)
+				switch {
+				case tt.errs[i] == "" && err != nil:
+					t.Errorf("op %d: unexpected error %v", i, err)
+				case tt.errs[i] != "" && (err == nil || !strings.Contains(err.Error(), tt.errs[i])):
+					t.Errorf("op %d: error = %v, want %q", i, err, tt.errs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBatchDependedOn(t *testing.T) {
+	step := func(src, dst string) *batchStep {
+		return &batchStep{src: batchTarget{realPath: src}, dst: batchTarget{realPath: dst}}
+	}
+	tests := []struct {
+		name  string
+		steps []*batchStep
+		want  []bool
+	}{
+		{"independent", []*batchStep{step("/d/a", "/d/b"), step("/d/c", "/d/e")}, []bool{false, false}},
+		{"delete src", []*batchStep{step("/d/a", "/d/b"), step("/d/a", "")}, []bool{true, false}},
+		{"rename dst", []*batchStep{step("/d/a", "/d/b"), step("/d/b", "/d/c")}, []bool{true, false}},
+		{"mkdir inside dst", []*batchStep{step("/d/a", "/d/b"), step("", "/d/b/sub")}, []bool{true, false}},
+		{"delete parent of src", []*batchStep{step("/d/x/a", "/e/a"), step("/d/x", "")}, []bool{true, false}},
+		{"sibling prefix", []*batchStep{step("/d/a", "/d/b"), step("/d/ab", "/d/bc")}, []bool{false, false}},
+		{"failed later op", []*batchStep{step("/d/a", "/d/b"), nil}, []bool{false, false}},
+		{"chain", []*batchStep{step("/d/a", "/d/b"), step("/d/b", "/d/c"), step("/d/c", "")}, []bool{true, true, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := range tt.steps {
+				if tt.steps[i] == nil {
+					continue
+				}
+				if got := dependedOn(tt.steps, i); got != tt.want[i] {
+					t.Errorf("dependedOn(%d) = %v, want %v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCheckDeletable(t *testing.T) {
+	cfg := config.GetConfig()
+	prefix := cfg.UserSpacePrefix
+	cfg.UserSpacePrefix = "/users"
+	t.Cleanup(func() { cfg.UserSpacePrefix = prefix })
+	tests := []struct {
+		realPath  string
+		permanent bool
+		err       string // 错误应包含的内容，为空表示可以删除
+	}{
+		{"/users/alice/a.txt", false, ""},
+		{"/users/alice/a.txt", true, ""},
+		{"/users/alice", false, "root of a space"},
+		{"/users/alice/", true, "root of a space"},
+		{"/other/a.txt", false, "permanent=true"},
+		{"/other/a.txt", true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.realPath, func(t *testing.T) {
+			err := checkDeletable("x", tt.realPath, tt.permanent)
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("unexpected error %v", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync/atomic"
+	"webdav/dao/model"
+	"webdav/logutils"
+	"webdav/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CopyFileReq struct {
+	Dst string `json:"dst" binding:"required"`
+}
+
+// 复制文件或目录树到不存在的 dst，目录使用默认权限创建，符号链接等特殊文件被忽略。
+// created 表示 dst 已由本次复制创建，r 不为空时报告进度
+func copyTree(ctx context.Context, src, dst string, r *runningJob) (created bool, err error) {
+	var progress *atomic.Int64
+	if r != nil {
+		progress = &r.doneBytes
+	}
+	fi, err := fs.FileSystem.Stat(ctx, src)
+	if err != nil {
+		return false, err
+	}
+	err = walkFS(ctx, src, fi, func(p string, fi os.FileInfo) error {
+		target := path.Join(dst, strings.TrimPrefix(p, src))
+		if fi.IsDir() {
+			if err := fs.FileSystem.Mkdir(ctx, target, model.RWXFolderPerm); err != nil {
+				return err
+			}
+			created = true
+			return nil
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		// copyFile 失败时会删除自己创建的文件
+		if _, err := copyFileProgress(ctx, p, target, fi, progress); err != nil {
+			return err
+		}
+		created = true
+		if r != nil {
+			r.doneFiles.Add(1)
+		}
+		return nil
+	})
+	return created, err
+}
+
+func runCopyJob(ctx context.Context, r *runningJob) error {
+	params := r.job.Params.Data()
+	bytes, files, err := treeUsage(ctx, params.RealSrc)
+	if err != nil {
+		return err
+	}
+	r.setTotal(bytes, files)
+	if err = checkQuota(ctx, params.RealDst, bytes); err != nil {
+		return err
+	}
+	return copyWithUsage(ctx, params.RealSrc, params.RealDst, r)
+}
+
+// 复制并更新目标空间的用量，失败时删除已复制的部分
+func copyWithUsage(ctx context.Context, src, dst string, r *runningJob) error {
+	snapshot := snapshotUsage(ctx, dst)
+	created, err := copyTree(ctx, src, dst, r)
+	if err != nil && created {
+		// 不保留复制了一半的文件
+		if rerr := os.RemoveAll(osPath(dst)); rerr != nil {
+			logutils.Log.Errorf("remove partial copy %s: %v", dst, rerr)
+		}
+	}
+	snapshot.commit(context.Background())
+	return err
+}
+
+// 复制文件或目录，可以在调用者有权限的任意空间之间复制。
+// 复制在后台任务中进行，较大的目录树可以通过 /jobs/:id 查询进度或取消
+func CopyFile(c *gin.Context) {
+	AlloweOption(c)
+	checkfs()
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	var req CopyFileReq
+	if err = c.ShouldBind(&req); err != nil {
+		response.BadRequestError(c, err.Error())
+		return
+	}
+	param := strings.TrimPrefix(c.Request.URL.Path, "/api/ss/copy")
+	if !GetPermission(param, jwttoken, c).CanRead() {
+		permissionDenied(c, param, jwttoken, "You have no permission to copy files", response.NotSpecified)
+		return
+	}
+	// 复制到只能追加的位置相当于新建
+	if !GetPermission(req.Dst, jwttoken, c).CanCreate() {
+		permissionDenied(c, req.Dst, jwttoken, "You have no permission to copy files to this location", response.NotSpecified)
+		return
+	}
+	realPath, err := Redirect(c, param, jwttoken)
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	realDst, err := Redirect(c, req.Dst, jwttoken)
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	realPath, realDst = cleanRealPath(realPath), cleanRealPath(realDst)
+	if realDst == realPath || strings.HasPrefix(realDst, realPath+"/") {
+		response.BadRequestError(c, "can't copy a directory into itself")
+		return
+	}
+	ctx := c.Request.Context()
+	if _, err = fs.FileSystem.Stat(ctx, realPath); err != nil {
+		response.HTTPError(c, http.StatusNotFound, "source does not exist", response.NotSpecified)
+		return
+	}
+	if fi, serr := fs.FileSystem.Stat(ctx, path.Dir(realDst)); serr != nil || !fi.IsDir() {
+		response.HTTPError(c, http.StatusNotFound, "target directory does not exist", response.NotSpecified)
+		return
+	}
+	if _, err = fs.FileSystem.Stat(ctx, realDst); err == nil {
+		response.HTTPError(c, http.StatusConflict, "target already exists", response.NotSpecified)
+		return
+	}
+	// 统计文件数和检查配额需要遍历整个目录树，在任务中进行
+	job, err := submitJob(ctx, jwttoken.UserID, model.FileJobCopy, model.FileJobParams{
+		Src:     strings.TrimLeft(param, "/"),
+		Dst:     strings.TrimLeft(req.Dst, "/"),
+		RealSrc: realPath,
+		RealDst: realDst,
+	}, 0, 0)
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	response.Success(c, toJobResp(waitJob(ctx, job, jobInlineWait)))
+}
+
+func RegisterCopy(webdavGroup *gin.RouterGroup) {
+	webdavGroup.POST("/copy/*path", CopyFile)
+}
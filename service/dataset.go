@@ -2,17 +2,25 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 	"webdav/dao/model"
 	"webdav/dao/query"
+	"webdav/logutils"
 	"webdav/response"
+	"webdav/util"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+	"gorm.io/gen"
+	"gorm.io/gorm/clause"
 )
 
 type MoveFileReq struct {
@@ -24,7 +32,7 @@ func MoveFile(c *gin.Context) {
 	checkfs()
 	jwttoken, err := CheckJWTToken(c)
 	if err != nil {
-		response.Error(c, err.Error(), response.NotSpecified)
+		authError(c, err)
 		return
 	}
 	var moveFileReq MoveFileReq
@@ -36,9 +44,13 @@ func MoveFile(c *gin.Context) {
 	param := strings.TrimPrefix(c.Request.URL.Path, "/api/ss/move")
 	sourcePermission := GetPermission(param, jwttoken, c)
 	dstPermission := GetPermission(moveFileReq.Dst, jwttoken, c)
-	if sourcePermission != model.ReadWrite || dstPermission != model.ReadWrite {
-		response.HTTPError(c, http.StatusUnauthorized, "You have no permission to move files or move files to this location ",
-			response.NotSpecified)
+	// 移动到只能追加的位置相当于新建，不会覆盖已有文件
+	if !sourcePermission.CanModify() {
+		permissionDenied(c, param, jwttoken, "You have no permission to move files or move files to this location ", response.NotSpecified)
+		return
+	}
+	if !dstPermission.CanCreate() {
+		permissionDenied(c, moveFileReq.Dst, jwttoken, "You have no permission to move files or move files to this location ", response.NotSpecified)
 		return
 	}
 	realPath, err := Redirect(c, param, jwttoken)
@@ -49,13 +61,50 @@ func MoveFile(c *gin.Context) {
 	realDst, err := Redirect(c, moveFileReq.Dst, jwttoken)
 	if err != nil {
 		response.Error(c, err.Error(), response.NotSpecified)
+		return
 	}
-	err = moveFiles(c.Request.Context(), realPath, realDst, false)
+	// 统计文件数和检查配额需要遍历整个目录树，在任务中进行
+	ctx := c.Request.Context()
+	job, err := submitJob(ctx, jwttoken.UserID, model.FileJobMove, model.FileJobParams{
+		Src:     strings.TrimLeft(param, "/"),
+		Dst:     strings.TrimLeft(moveFileReq.Dst, "/"),
+		RealSrc: realPath,
+		RealDst: realDst,
+	}, 0, 0)
 	if err != nil {
 		response.Error(c, err.Error(), response.NotSpecified)
 		return
 	}
-	response.Success(c, "move files successfully")
+	response.Success(c, toJobResp(waitJob(ctx, job, jobInlineWait)))
+}
+
+func runMoveJob(ctx context.Context, r *runningJob) error {
+	params := r.job.Params.Data()
+	bytes, files, err := treeUsage(ctx, params.RealSrc)
+	if err != nil {
+		return err
+	}
+	r.setTotal(bytes, files)
+	if spaceRootOf(params.RealSrc) != spaceRootOf(params.RealDst) {
+		if err = checkQuota(ctx, params.RealDst, bytes); err != nil {
+			return err
+		}
+	}
+	if err = moveWithUsage(ctx, params.RealSrc, params.RealDst, &r.doneBytes); err != nil {
+		return err
+	}
+	r.doneBytes.Store(r.totalBytes.Load())
+	r.doneFiles.Store(r.totalFiles.Load())
+	return nil
+}
+
+// 移动并更新两端空间的用量
+func moveWithUsage(ctx context.Context, src, dst string, progress *atomic.Int64) error {
+	srcSnapshot, dstSnapshot := snapshotUsage(ctx, src), snapshotUsage(ctx, dst)
+	err := moveFiles(ctx, src, dst, false, progress)
+	srcSnapshot.commit(context.Background())
+	dstSnapshot.commit(context.Background())
+	return err
 }
 
 func MoveDatasetOrModel(c *gin.Context) {
@@ -63,7 +112,7 @@ func MoveDatasetOrModel(c *gin.Context) {
 	checkfs()
 	jwttoken, err := CheckJWTToken(c)
 	if err != nil {
-		response.Error(c, err.Error(), response.NotSpecified)
+		authError(c, err)
 		return
 	}
 	var datasetReq DatasetRequest
@@ -92,17 +141,50 @@ func MoveDatasetOrModel(c *gin.Context) {
 	}
 	dest = dest + "/" + strconv.FormatUint(uint64(datasetReq.ID), 10)
 	dest = filepath.Join(dest, filepath.Base(dataset.URL))
-	err = moveFiles(c.Request.Context(), dataset.URL, dest, false)
+	job, err := submitJob(c, jwttoken.UserID, model.FileJobDatasetMove, model.FileJobParams{
+		DatasetID: dataset.ID,
+		RealSrc:   dataset.URL,
+		RealDst:   dest,
+	}, 0, 0)
 	if err != nil {
 		response.Error(c, err.Error(), response.NotSpecified)
 		return
 	}
-	dataset.URL = dest
-	if _, err := d.WithContext(c).Updates(dataset); err != nil {
-		response.Error(c, "failed to update dataset URL", response.NotSpecified)
-		return
+	response.Success(c, toJobResp(waitJob(c, job, jobInlineWait)))
+}
+
+func runDatasetMoveJob(ctx context.Context, r *runningJob) error {
+	params := r.job.Params.Data()
+	d := query.Dataset
+	dataset, err := d.WithContext(ctx).Where(d.ID.Eq(params.DatasetID)).First()
+	if err != nil {
+		return err
+	}
+	if dataset.URL != params.RealSrc {
+		return fmt.Errorf("dataset %d has been moved to %s", dataset.ID, dataset.URL)
+	}
+	snapshot := snapshotUsage(ctx, dataset.URL)
+	r.setTotal(snapshot.bytes, snapshot.files)
+	err = moveFiles(ctx, dataset.URL, params.RealDst, false, &r.doneBytes)
+	snapshot.commit(context.Background())
+	if err != nil {
+		return err
+	}
+	r.doneBytes.Store(snapshot.bytes)
+	r.doneFiles.Store(snapshot.files)
+	// 文件已经移动，不再响应取消
+	ctx = context.WithoutCancel(ctx)
+	dataset.URL = params.RealDst
+	if _, err = d.WithContext(ctx).Updates(dataset); err != nil {
+		return fmt.Errorf("failed to update dataset URL: %w", err)
+	}
+	// 已有清单时在后台校验移动后的文件是否完整
+	if m, merr := getManifest(ctx, dataset.ID); merr == nil && m.Status == model.ManifestSucceeded {
+		if _, _, merr = startVerifyManifest(ctx, r.job.UserID, dataset.ID); merr != nil {
+			logutils.Log.Warnf("verify dataset %d: %v", dataset.ID, merr)
+		}
 	}
-	response.Success(c, "move dataset or model successfully")
+	return nil
 }
 
 type RestoreFileReq struct {
@@ -116,7 +198,7 @@ func RestoreDatasetOrModel(c *gin.Context) {
 	checkfs()
 	jwttoken, err := CheckJWTToken(c)
 	if err != nil {
-		response.Error(c, err.Error(), response.NotSpecified)
+		authError(c, err)
 		return
 	}
 	var restoreFileReq RestoreFileReq
@@ -141,7 +223,9 @@ func RestoreDatasetOrModel(c *gin.Context) {
 		srcName := filepath.Base(soure)
 		dstPath = filepath.Join(dstPath, srcName)
 	}
-	err = moveFiles(c.Request.Context(), soure, dstPath, false)
+	snapshot := snapshotUsage(c.Request.Context(), dstPath)
+	err = moveFiles(c.Request.Context(), soure, dstPath, false, nil)
+	snapshot.commit(c.Request.Context())
 	if err != nil {
 		response.Error(c, err.Error(), response.NotSpecified)
 		return
@@ -154,7 +238,10 @@ func RestoreDatasetOrModel(c *gin.Context) {
 	response.Success(c, "restore dataset or model successfully")
 }
 
-func moveFiles(ctx context.Context, src, dst string, overwrite bool) error {
+// 移动文件或目录，跨设备时复制并校验后删除源文件，progress 累加已复制的字节数
+func moveFiles(ctx context.Context, src, dst string, overwrite bool, progress *atomic.Int64) error {
+	defer invalidateDU(src)
+	defer invalidateDU(dst)
 	if !overwrite {
 		if _, err := fs.FileSystem.Stat(ctx, dst); err == nil {
 			return fmt.Errorf("destination %s already exists", dst)
@@ -181,11 +268,395 @@ func moveFiles(ctx context.Context, src, dst string, overwrite bool) error {
 		}
 	}
 
-	return fs.FileSystem.Rename(ctx, src, dst)
+	return renameOrCopy(ctx, src, dst, progress)
+}
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+type DatasetResp struct {
+	ID        uint           `json:"id"`
+	Name      string         `json:"name"`
+	URL       string         `json:"url"`
+	Describe  string         `json:"describe"`
+	Type      model.DataType `json:"type"`
+	Tags      []string       `json:"tags"`
+	WebURL    *string        `json:"weburl,omitempty"`
+	UserID    uint           `json:"userID"`
+	Username  string         `json:"username"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+}
+
+type DatasetListResp struct {
+	Items []DatasetResp `json:"items"`
+	Total int64         `json:"total"`
+}
+
+func toDatasetResp(dataset *model.Dataset) DatasetResp {
+	extra := dataset.Extra.Data()
+	tags := extra.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return DatasetResp{
+		ID:        dataset.ID,
+		Name:      dataset.Name,
+		URL:       dataset.URL,
+		Describe:  dataset.Describe,
+		Type:      dataset.Type,
+		Tags:      tags,
+		WebURL:    extra.WebURL,
+		UserID:    dataset.UserID,
+		Username:  dataset.User.Name,
+		CreatedAt: dataset.CreatedAt,
+		UpdatedAt: dataset.UpdatedAt,
+	}
+}
+
+// 用户通过共享可以访问的数据集 ID，不包含自己创建的
+func sharedDatasetIDs(c *gin.Context, token util.JWTMessage) ([]uint, error) {
+	var ids []uint
+	ud := query.UserDataset
+	if err := ud.WithContext(c).Where(ud.UserID.Eq(token.UserID)).Pluck(ud.DatasetID, &ids); err != nil {
+		return nil, err
+	}
+	accountIDs := []uint{model.DefaultAccountID}
+	if token.AccountID != util.QueueIDNull {
+		accountIDs = append(accountIDs, token.AccountID)
+	}
+	var accountDatasetIDs []uint
+	ad := query.AccountDataset
+	if err := ad.WithContext(c).Where(ad.AccountID.In(accountIDs...)).Pluck(ad.DatasetID, &accountDatasetIDs); err != nil {
+		return nil, err
+	}
+	return append(ids, accountDatasetIDs...), nil
+}
+
+// 校验数据集路径，url 为用户可读的虚拟路径，返回实际路径
+func resolveDatasetURL(c *gin.Context, url string, token util.JWTMessage) (string, error) {
+	if !GetPermission(url, token, c).CanRead() {
+		return "", fmt.Errorf("you have no permission to read %s", url)
+	}
+	realPath, err := Redirect(c, url, token)
+	if err != nil {
+		return "", err
+	}
+	if _, err = fs.FileSystem.Stat(c.Request.Context(), realPath); err != nil {
+		return "", fmt.Errorf("%s does not exist", url)
+	}
+	return realPath, nil
+}
+
+func checkDataType(t model.DataType) error {
+	if t != model.DataTypeDataset && t != model.DataTypeModel {
+		return fmt.Errorf("the type of dataset is incorrect")
+	}
+	return nil
+}
+
+type CreateDatasetReq struct {
+	Name     string         `json:"name" binding:"required"`
+	URL      string         `json:"url" binding:"required"`
+	Describe string         `json:"describe"`
+	Type     model.DataType `json:"type"`
+	Tags     []string       `json:"tags"`
+	WebURL   *string        `json:"weburl"`
+}
+
+// 创建数据集或模型，url 为用户可读的虚拟路径
+func CreateDataset(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	var req CreateDatasetReq
+	if err = c.ShouldBindJSON(&req); err != nil {
+		response.BadRequestError(c, err.Error())
+		return
+	}
+	if req.Type == "" {
+		req.Type = model.DataTypeDataset
+	}
+	if err = checkDataType(req.Type); err != nil {
+		response.BadRequestError(c, err.Error())
+		return
+	}
+	realPath, err := resolveDatasetURL(c, req.URL, jwttoken)
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	dataset := &model.Dataset{
+		Name:     req.Name,
+		URL:      realPath,
+		Describe: req.Describe,
+		Type:     req.Type,
+		Extra:    datatypes.NewJSONType(model.Extracontent{Tags: req.Tags, WebURL: req.WebURL}),
+		UserID:   jwttoken.UserID,
+	}
+	if err = query.Dataset.WithContext(c).Create(dataset); err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	dataset.User.Name = jwttoken.Username
+	response.Success(c, toDatasetResp(dataset))
+}
+
+type ListDatasetReq struct {
+	Page int            `form:"page"`
+	Size int            `form:"size"`
+	Type model.DataType `form:"type"`
+	Tags []string       `form:"tag"`
+	Name string         `form:"name"`
+	Mine bool           `form:"mine"`
+}
+
+// 分页列出用户可以访问的数据集，可按类型、标签和名称筛选
+func ListDatasets(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	var req ListDatasetReq
+	if err = c.ShouldBindQuery(&req); err != nil {
+		response.BadRequestError(c, err.Error())
+		return
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.Size <= 0 || req.Size > maxPageSize {
+		req.Size = defaultPageSize
+	}
+	d := query.Dataset
+	q := d.WithContext(c).Preload(d.User).Order(d.ID.Desc())
+	if req.Mine {
+		q = q.Where(d.UserID.Eq(jwttoken.UserID))
+	} else if jwttoken.RolePlatform != model.RoleAdmin {
+		ids, serr := sharedDatasetIDs(c, jwttoken)
+		if serr != nil {
+			response.Error(c, serr.Error(), response.NotSpecified)
+			return
+		}
+		q = q.Where(d.WithContext(c).Where(d.UserID.Eq(jwttoken.UserID)).Or(d.ID.In(ids...)))
+	}
+	if scope := jwttoken.Scope; scope != nil && (len(scope.Roots) != 0 || len(scope.DatasetIDs) != 0) {
+		// 0 不是合法的 ID，保证 IN 的列表不为空
+		q = q.Where(d.ID.In(append([]uint{0}, scope.DatasetIDs...)...))
+	}
+	if req.Type != "" {
+		q = q.Where(d.Type.Eq(string(req.Type)))
+	}
+	if req.Name != "" {
+		q = q.Where(d.Name.Like("%" + req.Name + "%"))
+	}
+	if len(req.Tags) != 0 {
+		// extra 为 jsonb，用包含运算要求同时带有所有指定的标签
+		tags, merr := json.Marshal(model.Extracontent{Tags: req.Tags})
+		if merr != nil {
+			response.Error(c, merr.Error(), response.NotSpecified)
+			return
+		}
+		q = q.Where(gen.Cond(clause.Expr{SQL: "? @> ?::jsonb", Vars: []any{clause.Column{Name: "extra"}, string(tags)}})...)
+	}
+	datasets, total, err := q.FindByPage((req.Page-1)*req.Size, req.Size)
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	data := DatasetListResp{Items: make([]DatasetResp, 0, len(datasets)), Total: total}
+	for _, dataset := range datasets {
+		data.Items = append(data.Items, toDatasetResp(dataset))
+	}
+	response.Success(c, data)
+}
+
+func GetDataset(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	var datasetReq DatasetRequest
+	if err = c.ShouldBindUri(&datasetReq); err != nil {
+		response.HTTPError(c, http.StatusBadRequest, err.Error(), response.NotSpecified)
+		return
+	}
+	if GetDatasetPermission(c, datasetReq.ID, jwttoken) == model.NotAllowed {
+		response.Error(c, "This dataset does not exist or you do not have permission", response.NotSpecified)
+		return
+	}
+	d := query.Dataset
+	dataset, err := d.WithContext(c).Preload(d.User).Where(d.ID.Eq(datasetReq.ID)).First()
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	response.Success(c, toDatasetResp(dataset))
+}
+
+type UpdateDatasetReq struct {
+	Name     *string         `json:"name"`
+	URL      *string         `json:"url"`
+	Describe *string         `json:"describe"`
+	Type     *model.DataType `json:"type"`
+	Tags     *[]string       `json:"tags"`
+	WebURL   *string         `json:"weburl"`
+}
+
+// 修改数据集信息，只有创建者和管理员可以修改
+func UpdateDataset(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	var datasetReq DatasetRequest
+	if err = c.ShouldBindUri(&datasetReq); err != nil {
+		response.HTTPError(c, http.StatusBadRequest, err.Error(), response.NotSpecified)
+		return
+	}
+	var req UpdateDatasetReq
+	if err = c.ShouldBindJSON(&req); err != nil {
+		response.BadRequestError(c, err.Error())
+		return
+	}
+	if GetDatasetPermission(c, datasetReq.ID, jwttoken) != model.ReadWrite {
+		response.HTTPError(c, http.StatusUnauthorized, "You have no permission to modify this dataset", response.NotSpecified)
+		return
+	}
+	d := query.Dataset
+	dataset, err := d.WithContext(c).Preload(d.User).Where(d.ID.Eq(datasetReq.ID)).First()
+	if err != nil {
+		response.Error(c, "Dataset don't exist", response.NotSpecified)
+		return
+	}
+	if req.Name != nil {
+		if *req.Name == "" {
+			response.BadRequestError(c, "name can't be empty")
+			return
+		}
+		dataset.Name = *req.Name
+	}
+	if req.Describe != nil {
+		dataset.Describe = *req.Describe
+	}
+	if req.Type != nil {
+		if err = checkDataType(*req.Type); err != nil {
+			response.BadRequestError(c, err.Error())
+			return
+		}
+		dataset.Type = *req.Type
+	}
+	if req.URL != nil {
+		realPath, rerr := resolveDatasetURL(c, *req.URL, jwttoken)
+		if rerr != nil {
+			response.Error(c, rerr.Error(), response.NotSpecified)
+			return
+		}
+		dataset.URL = realPath
+	}
+	extra := dataset.Extra.Data()
+	if req.Tags != nil {
+		extra.Tags = *req.Tags
+	}
+	if req.WebURL != nil {
+		extra.WebURL = req.WebURL
+	}
+	dataset.Extra = datatypes.NewJSONType(extra)
+	if _, err = d.WithContext(c).Where(d.ID.Eq(dataset.ID)).Select(d.Name, d.URL, d.Describe, d.Type, d.Extra).Updates(dataset); err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	response.Success(c, toDatasetResp(dataset))
+}
+
+// 删除数据集记录及其共享关系，不删除数据集路径下的文件
+func DeleteDataset(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	var datasetReq DatasetRequest
+	if err = c.ShouldBindUri(&datasetReq); err != nil {
+		response.HTTPError(c, http.StatusBadRequest, err.Error(), response.NotSpecified)
+		return
+	}
+	if GetDatasetPermission(c, datasetReq.ID, jwttoken) != model.ReadWrite {
+		response.HTTPError(c, http.StatusUnauthorized, "You have no permission to delete this dataset", response.NotSpecified)
+		return
+	}
+	dv := query.DatasetVersion
+	creating, err := dv.WithContext(c).Where(dv.DatasetID.Eq(datasetReq.ID), dv.Status.Eq(string(model.VersionCreating))).Count()
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	if creating > 0 {
+		response.HTTPError(c, http.StatusConflict, "a version of this dataset is still being created", response.NotSpecified)
+		return
+	}
+	// 清单正在生成或校验时删除会留下清单记录
+	if busy, berr := manifestBusy(c, datasetReq.ID); berr != nil {
+		response.Error(c, berr.Error(), response.NotSpecified)
+		return
+	} else if busy {
+		response.HTTPError(c, http.StatusConflict, errManifestBusy.Error(), response.NotSpecified)
+		return
+	}
+	// 快照随数据集一起删除
+	if err = removeSnapshotDir(c.Request.Context(), datasetVersionDir(datasetReq.ID)); err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	err = query.Q.Transaction(func(tx *query.Query) error {
+		var versionIDs []uint
+		if terr := tx.DatasetVersion.WithContext(c).Where(tx.DatasetVersion.DatasetID.Eq(datasetReq.ID)).Pluck(tx.DatasetVersion.ID, &versionIDs); terr != nil {
+			return terr
+		}
+		if len(versionIDs) > 0 {
+			if _, terr := tx.DatasetVersionFile.WithContext(c).Where(tx.DatasetVersionFile.VersionID.In(versionIDs...)).Delete(); terr != nil {
+				return terr
+			}
+		}
+		if _, terr := tx.DatasetVersion.WithContext(c).Where(tx.DatasetVersion.DatasetID.Eq(datasetReq.ID)).Delete(); terr != nil {
+			return terr
+		}
+		if _, terr := tx.DatasetChecksum.WithContext(c).Where(tx.DatasetChecksum.DatasetID.Eq(datasetReq.ID)).Delete(); terr != nil {
+			return terr
+		}
+		if _, terr := tx.DatasetManifest.WithContext(c).Where(tx.DatasetManifest.DatasetID.Eq(datasetReq.ID)).Delete(); terr != nil {
+			return terr
+		}
+		if _, terr := tx.UserDataset.WithContext(c).Where(tx.UserDataset.DatasetID.Eq(datasetReq.ID)).Delete(); terr != nil {
+			return terr
+		}
+		if _, terr := tx.AccountDataset.WithContext(c).Where(tx.AccountDataset.DatasetID.Eq(datasetReq.ID)).Delete(); terr != nil {
+			return terr
+		}
+		_, terr := tx.Dataset.WithContext(c).Where(tx.Dataset.ID.Eq(datasetReq.ID)).Delete()
+		return terr
+	})
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	response.Success(c, "delete dataset successfully")
 }
 
 func RegisterDataset(webdavGroup *gin.RouterGroup) {
 	webdavGroup.POST("/move/*path", MoveFile)
+	webdavGroup.POST("/datasets", CreateDataset)
+	webdavGroup.GET("/datasets", ListDatasets)
+	webdavGroup.GET("/datasets/:id", GetDataset)
+	webdavGroup.PATCH("/datasets/:id", UpdateDataset)
+	webdavGroup.DELETE("/datasets/:id", DeleteDataset)
 	webdavGroup.POST("/datasets/:id/move", MoveDatasetOrModel)
 	webdavGroup.POST("/datasets/restore", RestoreDatasetOrModel)
 }
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"webdav/dao/model"
+
+	"golang.org/x/net/webdav"
+)
+
+// RFC 4331 定义的配额属性
+var (
+	quotaAvailableProp = xml.Name{Space: "DAV:", Local: "quota-available-bytes"}
+	quotaUsedProp      = xml.Name{Space: "DAV:", Local: "quota-used-bytes"}
+)
+
+type spaceQuotaProps struct {
+	used      int64
+	available int64
+}
+
+// quotaFS 为 PROPFIND 返回的每个条目附加所在空间的配额属性，
+// 只在单个请求内使用，同一空间的用量和配额只查询一次
+type quotaFS struct {
+	webdav.FileSystem
+	ctx   context.Context
+	mu    sync.Mutex
+	cache map[string]*spaceQuotaProps
+}
+
+func newQuotaFS(ctx context.Context) *quotaFS {
+	return &quotaFS{
+		FileSystem: fs.FileSystem,
+		ctx:        ctx,
+		cache:      make(map[string]*spaceQuotaProps),
+	}
+}
+
+func (q *quotaFS) propsOf(name string) *spaceQuotaProps {
+	root := spaceRootOf(name)
+	if root == "" {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if props, ok := q.cache[root]; ok {
+		return props
+	}
+	var props *spaceQuotaProps
+	if usage, err := spaceUsageOf(q.ctx, root); err == nil {
+		props = &spaceQuotaProps{used: usage.Bytes}
+		if quota := spaceQuota(q.ctx, root); quota >= 0 {
+			props.available = max(quota-usage.Bytes, 0)
+		} else if free, ferr := freeBytes(osPath(root)); ferr == nil {
+			// 不限配额时以文件系统剩余空间作为可用空间
+			props.available = free
+		} else {
+			props.available = -1
+		}
+	}
+	q.cache[root] = props
+	return props
+}
+
+func (q *quotaFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	f, err := q.FileSystem.OpenFile(ctx, name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	props := q.propsOf(name)
+	if props == nil {
+		return f, nil
+	}
+	return &quotaFile{File: f, isSpaceRoot: spaceRootOf(name) == cleanRealPath(name), props: props}, nil
+}
+
+type quotaFile struct {
+	webdav.File
+	isSpaceRoot bool
+	props       *spaceQuotaProps
+}
+
+// 空间根目录下的回收站对客户端不可见
+func (f *quotaFile) Readdir(count int) ([]os.FileInfo, error) {
+	fis, err := f.File.Readdir(count)
+	if !f.isSpaceRoot {
+		return fis, err
+	}
+	res := fis[:0]
+	for _, fi := range fis {
+		if fi.Name() != model.TrashDir {
+			res = append(res, fi)
+		}
+	}
+	return res, err
+}
+
+func (f *quotaFile) DeadProps() (map[xml.Name]webdav.Property, error) {
+	props := map[xml.Name]webdav.Property{
+		quotaUsedProp: {XMLName: quotaUsedProp, InnerXML: []byte(strconv.FormatInt(f.props.used, 10))},
+	}
+	if f.props.available >= 0 {
+		props[quotaAvailableProp] = webdav.Property{
+			XMLName:  quotaAvailableProp,
+			InnerXML: []byte(strconv.FormatInt(f.props.available, 10)),
+		}
+	}
+	return props, nil
+}
+
+// 配额属性是只读的，其余属性与 webdav.Dir 一样不支持修改
+func (f *quotaFile) Patch(patches []webdav.Proppatch) ([]webdav.Propstat, error) {
+	pstat := webdav.Propstat{Status: http.StatusForbidden}
+	for _, patch := range patches {
+		for _, p := range patch.Props {
+			pstat.Props = append(pstat.Props, webdav.Property{XMLName: p.XMLName})
+		}
+	}
+	return []webdav.Propstat{pstat}, nil
+}
@@ -0,0 +1,363 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"webdav/config"
+	"webdav/dao/model"
+	"webdav/dao/query"
+	"webdav/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultDUDepth = 1
+	maxDUDepth     = 5
+	defaultDUTop   = 10
+	maxDUTop       = 100
+	duWalkers      = 16
+	// 绕过本服务写入的文件不会使缓存失效，缓存过期后重新统计
+	duCacheTTL = 10 * time.Minute
+	// 缓存的条目数上限，写操作使缓存失效时需要检查所有条目
+	maxDUCacheEntries = 1024
+)
+
+// 目录的递归用量，Children 为按大小降序排列的最大的若干个子项
+type DUNode struct {
+	Name     string    `json:"name"`
+	IsDir    bool      `json:"isdir"`
+	Bytes    int64     `json:"bytes"`
+	Files    int64     `json:"files"`
+	Dirs     int64     `json:"dirs"`
+	Children []*DUNode `json:"children,omitempty"`
+}
+
+type duCacheEntry struct {
+	node       *DUNode
+	depth      int
+	top        int
+	computedAt time.Time
+}
+
+// 以实际路径为键的用量缓存，经本服务的写操作会使相关路径的缓存失效
+var duCache = struct {
+	sync.Mutex
+	entries map[string]*duCacheEntry
+}{entries: make(map[string]*duCacheEntry)}
+
+// 使 name 的祖先和子孙目录的缓存失效
+func invalidateDU(name string) {
+	p := cleanRealPath(name)
+	duCache.Lock()
+	defer duCache.Unlock()
+	for key := range duCache.entries {
+		if key == "/" || key == p || strings.HasPrefix(key, p+"/") || strings.HasPrefix(p, key+"/") {
+			delete(duCache.entries, key)
+		}
+	}
+}
+
+// 写入缓存，条目数达到上限时先清理过期的条目，仍然不够时淘汰最早统计的条目
+func storeDU(key string, entry *duCacheEntry) {
+	duCache.Lock()
+	defer duCache.Unlock()
+	if _, ok := duCache.entries[key]; !ok && len(duCache.entries) >= maxDUCacheEntries {
+		oldest := ""
+		for k, e := range duCache.entries {
+			if time.Since(e.computedAt) >= duCacheTTL {
+				delete(duCache.entries, k)
+			} else if oldest == "" || e.computedAt.Before(duCache.entries[oldest].computedAt) {
+				oldest = k
+			}
+		}
+		if len(duCache.entries) >= maxDUCacheEntries {
+			delete(duCache.entries, oldest)
+		}
+	}
+	duCache.entries[key] = entry
+}
+
+// 限制同时遍历目录的协程数，没有空闲名额时在当前协程中继续遍历
+type duWalker struct {
+	ctx   context.Context
+	sem   chan struct{}
+	depth int
+	top   int
+}
+
+func (w *duWalker) walk(name string, fi os.FileInfo, level int) (*DUNode, error) {
+	node := &DUNode{Name: fi.Name(), IsDir: fi.IsDir()}
+	if !fi.IsDir() {
+		node.Bytes, node.Files = fi.Size(), 1
+		return node, nil
+	}
+	if err := w.ctx.Err(); err != nil {
+		return nil, err
+	}
+	f, err := fs.FileSystem.OpenFile(w.ctx, name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+	add := func(child *DUNode, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return
+		}
+		node.Bytes += child.Bytes
+		node.Files += child.Files
+		node.Dirs += child.Dirs
+		if child.IsDir {
+			node.Dirs++
+		}
+		if level < w.depth {
+			node.Children = append(node.Children, child)
+		}
+	}
+	isSpaceRoot := spaceRootOf(name) == cleanRealPath(name)
+	for {
+		entries, rerr := f.Readdir(walkBatchSize)
+		for _, entry := range entries {
+			if isSpaceRoot && entry.Name() == model.TrashDir {
+				continue
+			}
+			child := path.Join(name, entry.Name())
+			if entry.IsDir() {
+				select {
+				case w.sem <- struct{}{}:
+					wg.Add(1)
+					go func(child string, entry os.FileInfo) {
+						defer wg.Done()
+						defer func() { <-w.sem }()
+						add(w.walk(child, entry, level+1))
+					}(child, entry)
+					continue
+				default:
+				}
+			}
+			add(w.walk(child, entry, level+1))
+		}
+		if errors.Is(rerr, io.EOF) {
+			break
+		}
+		if rerr != nil {
+			add(nil, rerr)
+			break
+		}
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	sortDUChildren(node, w.top)
+	return node, nil
+}
+
+func sortDUChildren(node *DUNode, top int) {
+	sort.Slice(node.Children, func(i, j int) bool {
+		if node.Children[i].Bytes != node.Children[j].Bytes {
+			return node.Children[i].Bytes > node.Children[j].Bytes
+		}
+		return node.Children[i].Name < node.Children[j].Name
+	})
+	if len(node.Children) > top {
+		node.Children = node.Children[:top]
+	}
+}
+
+// 复制 node 并只保留 depth 层、每层 top 个子项
+func trimDU(node *DUNode, depth, top int) *DUNode {
+	trimmed := *node
+	trimmed.Children = nil
+	if depth > 0 {
+		for i := 0; i < len(node.Children) && i < top; i++ {
+			trimmed.Children = append(trimmed.Children, trimDU(node.Children[i], depth-1, top))
+		}
+	}
+	return &trimmed
+}
+
+// 统计目录的递归用量，缓存中有足够深的结果时直接使用。
+// 同时统计多个目录时共用 sem，总的遍历协程数不超过 sem 的容量
+func diskUsage(ctx context.Context, sem chan struct{}, name string, depth, top int, refresh bool) (*DUNode, time.Time, error) {
+	key := cleanRealPath(name)
+	duCache.Lock()
+	entry, ok := duCache.entries[key]
+	duCache.Unlock()
+	if ok && !refresh && entry.depth >= depth && entry.top >= top && time.Since(entry.computedAt) < duCacheTTL {
+		return trimDU(entry.node, depth, top), entry.computedAt, nil
+	}
+	fi, err := fs.FileSystem.Stat(ctx, name)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	now := time.Now()
+	w := &duWalker{ctx: ctx, sem: sem, depth: depth, top: top}
+	node, err := w.walk(name, fi, 0)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	storeDU(key, &duCacheEntry{node: node, depth: depth, top: top, computedAt: now})
+	return node, now, nil
+}
+
+type DiskUsageReq struct {
+	Depth   *int `form:"depth"` // 返回几层子目录，默认 1
+	Top     int  `form:"top"`   // 每个目录返回的最大子项数，默认 10
+	Refresh bool `form:"refresh"`
+}
+
+type DiskUsageResp struct {
+	*DUNode
+	Path       string    `json:"path"`
+	ComputedAt time.Time `json:"computedAt"`
+}
+
+func bindDiskUsage(c *gin.Context) (depth, top int, refresh, ok bool) {
+	var req DiskUsageReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequestError(c, err.Error())
+		return 0, 0, false, false
+	}
+	depth = defaultDUDepth
+	if req.Depth != nil {
+		depth = *req.Depth
+	}
+	if depth < 0 || depth > maxDUDepth {
+		response.BadRequestError(c, "depth must be between 0 and 5")
+		return 0, 0, false, false
+	}
+	top = req.Top
+	if top <= 0 || top > maxDUTop {
+		top = defaultDUTop
+	}
+	return depth, top, req.Refresh, true
+}
+
+// 统计目录占用的空间和文件数，并列出其中最大的子项
+func GetDiskUsage(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	depth, top, refresh, ok := bindDiskUsage(c)
+	if !ok {
+		return
+	}
+	param := strings.Trim(path.Clean(strings.TrimPrefix(c.Request.URL.Path, "/api/ss/du")), "/")
+	if param == "" || param == "." {
+		response.BadRequestError(c, "path is required")
+		return
+	}
+	if !GetPermission(param, jwttoken, c).CanRead() {
+		permissionDenied(c, param, jwttoken, "Your permission is notAllowed", response.NotSpecified)
+		return
+	}
+	realPath, err := Redirect(c, param, jwttoken)
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	node, computedAt, err := diskUsage(c.Request.Context(), make(chan struct{}, duWalkers), realPath, depth, top, refresh)
+	if os.IsNotExist(err) {
+		response.HTTPError(c, http.StatusNotFound, "can't find file", response.NotSpecified)
+		return
+	}
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	response.Success(c, DiskUsageResp{DUNode: node, Path: param, ComputedAt: computedAt})
+}
+
+type SpaceUsageResp struct {
+	Type      string    `json:"type"` // user 或 account
+	Space     string    `json:"space"`
+	Bytes     int64     `json:"bytes"`
+	Files     int64     `json:"files"`
+	Quota     int64     `json:"quota"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Error     string    `json:"error,omitempty"` // 还没有统计用量等原因，其他空间的结果不受影响
+}
+
+// 管理员按占用空间从大到小列出所有用户和账户空间。用量取自配额使用的 SpaceUsage，
+// 与配额检查的数字一致，不遍历目录树；需要细分时使用 /du/*path
+func RankSpaceUsage(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	if jwttoken.RolePlatform != model.RoleAdmin {
+		response.HTTPError(c, http.StatusUnauthorized, "Your RolePlatform is not RoleAdmin", response.NotSpecified)
+		return
+	}
+	type space struct {
+		kind, prefix, realPath, root string
+	}
+	var spaces []space
+	var roots []string
+	for _, p := range ListAllUserSpaces(c) {
+		spaces = append(spaces, space{"user", config.GetConfig().UserSpacePrefix, p, spaceRootOf(p)})
+	}
+	for _, p := range ListAllAccountSpaces(c) {
+		spaces = append(spaces, space{"account", config.GetConfig().AccountSpacePrefix, p, spaceRootOf(p)})
+	}
+	for _, s := range spaces {
+		roots = append(roots, s.root)
+	}
+	usages := make(map[string]*model.SpaceUsage, len(spaces))
+	if len(roots) != 0 {
+		su := query.SpaceUsage
+		rows, ferr := su.WithContext(c).Where(su.Space.In(roots...)).Find()
+		if ferr != nil {
+			response.Error(c, ferr.Error(), response.NotSpecified)
+			return
+		}
+		for _, row := range rows {
+			usages[row.Space] = row
+		}
+	}
+	data := make([]SpaceUsageResp, 0, len(spaces))
+	for _, s := range spaces {
+		resp := SpaceUsageResp{
+			Type:  s.kind,
+			Space: strings.Trim(strings.TrimPrefix(s.realPath, s.prefix), "/"),
+			Quota: spaceQuota(c, s.root),
+		}
+		if usage, ok := usages[s.root]; ok {
+			resp.Bytes, resp.Files, resp.UpdatedAt = usage.Bytes, usage.Files, usage.UpdatedAt
+		} else {
+			seedSpaceUsage(s.root)
+			resp.Error = errUsageUnknown.Error()
+		}
+		data = append(data, resp)
+	}
+	sort.SliceStable(data, func(i, j int) bool { return data[i].Bytes > data[j].Bytes })
+	response.Success(c, data)
+}
+
+func RegisterDiskUsage(webdavGroup *gin.RouterGroup) {
+	webdavGroup.GET("/du/*path", GetDiskUsage)
+	webdavGroup.GET("/admin/du", RankSpaceUsage)
+}
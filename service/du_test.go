@@ -0,0 +1,72 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestStoreDU(t *testing.T) {
+	old := duCache.entries
+	t.Cleanup(func() { duCache.entries = old })
+	now := time.Now()
+	fill := func(expired int) {
+		duCache.entries = make(map[string]*duCacheEntry)
+		for i := 0; i < maxDUCacheEntries; i++ {
+			computedAt := now.Add(time.Duration(i-maxDUCacheEntries) * time.Millisecond)
+			if i < expired {
+				computedAt = now.Add(-duCacheTTL - time.Minute)
+			}
+			duCache.entries[fmt.Sprintf("/d/%d", i)] = &duCacheEntry{node: &DUNode{}, computedAt: computedAt}
+		}
+	}
+	tests := []struct {
+		name    string
+		expired int
+		key     string
+		want    int      // 写入后的条目数
+		evicted []string // 应被淘汰的键
+		kept    []string
+	}{
+		{"evict oldest", 0, "/new", maxDUCacheEntries, []string{"/d/0"}, []string{"/d/1", "/new"}},
+		{"drop expired first", 3, "/new", maxDUCacheEntries - 2, []string{"/d/0", "/d/1", "/d/2"}, []string{"/d/3", "/new"}},
+		{"replace existing", 0, "/d/5", maxDUCacheEntries, nil, []string{"/d/0", "/d/5"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fill(tt.expired)
+			storeDU(tt.key, &duCacheEntry{node: &DUNode{}, computedAt: now})
+			if len(duCache.entries) != tt.want {
+				t.Errorf("%d entries, want %d", len(duCache.entries), tt.want)
+			}
+			for _, k := range tt.evicted {
+				if _, ok := duCache.entries[k]; ok {
+					t.Errorf("%s was not evicted", k)
+				}
+			}
+			for _, k := range tt.kept {
+				if _, ok := duCache.entries[k]; !ok {
+					t.Errorf("%s was evicted", k)
+				}
+			}
+		})
+	}
+}
+
+func TestInvalidateDU(t *testing.T) {
+	old := duCache.entries
+	t.Cleanup(func() { duCache.entries = old })
+	duCache.entries = make(map[string]*duCacheEntry)
+	for _, k := range []string{"/", "/a", "/a/b", "/a/b/c", "/ab", "/x"} {
+		duCache.entries[k] = &duCacheEntry{}
+	}
+	invalidateDU("/a/b/")
+	for _, k := range []string{"/ab", "/x"} {
+		if _, ok := duCache.entries[k]; !ok {
+			t.Errorf("%s was invalidated", k)
+		}
+	}
+	if len(duCache.entries) != 2 {
+		t.Errorf("entries left: %v", duCache.entries)
+	}
+}
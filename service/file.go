@@ -2,12 +2,16 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"os"
 	"path/filepath"
+	"runtime/debug"
 	"strconv"
 	"strings"
 	"sync"
@@ -31,7 +35,18 @@ type Files struct {
 	Size       int64     `json:"size"`
 	IsDir      bool      `json:"isdir"`
 	ModifyTime time.Time `json:"modifytime"`
-	Sys        any       `json:"sys"`
+	// 以下字段通过 fields 参数选择
+	Path   string `json:"path,omitempty"`   // 虚拟路径，数据集中为相对数据集的路径
+	Mode   string `json:"mode,omitempty"`   // 如 drwxr-xr-x，符号链接以 L 开头
+	Owner  string `json:"owner,omitempty"`  // 所有者的用户名，没有对应的用户时为 UID
+	Group  string `json:"group,omitempty"`  // GID 对应的用户名，没有对应的用户时为 GID
+	MIME   string `json:"mime,omitempty"`   // 媒体类型
+	Link   string `json:"link,omitempty"`   // 符号链接指向的路径
+	Hidden *bool  `json:"hidden,omitempty"` // 文件名以 . 开头
+
+	realPath string
+	mode     os.FileMode
+	uid, gid int // 未知时为 -1
 }
 
 type Permissions struct {
@@ -58,16 +73,26 @@ func checkfs() {
 
 func CheckJWTToken(c *gin.Context) (util.JWTMessage, error) {
 	var tmp util.JWTMessage
+	// davfs2、rclone 等客户端使用 Basic 认证，用户名加个人访问令牌
+	if username, key, ok := c.Request.BasicAuth(); ok {
+		return authenticateAPIKey(c, username, key)
+	}
 	authHeader := c.Request.Header.Get("Authorization")
 	t := strings.Split(authHeader, " ")
 	if len(t) < 2 || t[0] != "Bearer" {
 		return tmp, fmt.Errorf("invalid token")
 	}
 	authToken := t[1]
+	if strings.HasPrefix(authToken, model.APIKeyPrefix) {
+		return authenticateAPIKey(c, "", authToken)
+	}
 	token, err := util.GetTokenMgr().CheckToken(authToken)
 	if err != nil {
 		return tmp, err
 	}
+	if err = checkTokenActive(c, token); err != nil {
+		return tmp, err
+	}
 	return token, nil
 }
 
@@ -79,13 +104,23 @@ func WebDAVMiddleware() gin.HandlerFunc {
 	}
 }
 
+// 代替 gin.Recovery 处理 panic。http.ErrAbortHandler 交给 net/http 中断连接，
+// 否则已经写出一部分的响应会被当作完整的响应
+func RecoverPanic(c *gin.Context, err any) {
+	if err == http.ErrAbortHandler {
+		panic(err)
+	}
+	logutils.Log.Errorf("panic recovered: %v\n%s", err, debug.Stack())
+	c.AbortWithStatus(http.StatusInternalServerError)
+}
+
 func GetPermissionFromToken(token util.JWTMessage) model.FilePermission {
 	if token.RolePlatform == model.RoleAdmin {
 		return model.ReadWrite
 	} else if token.AccountID == util.QueueIDNull {
-		return model.FilePermission(token.PublicAccessMode)
+		return token.PublicAccessMode.FilePermission()
 	} else {
-		return model.FilePermission(token.AccountAccessMode)
+		return token.AccountAccessMode.FilePermission()
 	}
 }
 
@@ -171,14 +206,20 @@ func WebDav(c *gin.Context) {
 	AlloweOption(c)
 	checkfs()
 	jwttoken, err := CheckJWTToken(c)
+	if errors.Is(err, ErrUserNotActive) {
+		authError(c, err)
+		return
+	}
 	if err != nil {
-		response.Error(c, err.Error(), response.NotSpecified)
+		// WebDAV 客户端收到质询后才会发送 Basic 认证
+		c.Header("WWW-Authenticate", `Basic realm="crater"`)
+		response.HTTPError(c, http.StatusUnauthorized, err.Error(), response.InvalidToken)
 		return
 	}
 	param := strings.TrimPrefix(c.Request.URL.Path, "/api/ss")
 	permission := GetPermission(param, jwttoken, c)
-	if permission == model.NotAllowed {
-		response.HTTPError(c, http.StatusUnauthorized, "Your permission is notAllowed", response.InvalidRole)
+	if !permission.CanRead() {
+		permissionDenied(c, param, jwttoken, "Your permission is notAllowed", response.InvalidRole)
 		return
 	}
 	realPath, err := Redirect(c, param, jwttoken)
@@ -187,19 +228,95 @@ func WebDav(c *gin.Context) {
 		return
 	}
 	rwMethods := []string{"PROPPATCH", "MKCOL", "PUT", "DELETE"}
-	if permission == model.ReadOnly && containsString(rwMethods, c.Request.Method) {
-		response.HTTPError(c, http.StatusUnauthorized, "You have no permission to do this", response.NotSpecified)
+	createMethods := []string{"MKCOL", "PUT"}
+	if containsString(rwMethods, c.Request.Method) && !permission.CanModify() &&
+		!(permission.CanCreate() && containsString(createMethods, c.Request.Method)) {
+		permissionDenied(c, param, jwttoken, "You have no permission to do this", response.NotSpecified)
 		return
 	}
+	if c.Request.Method == "PUT" && !permission.CanModify() {
+		if _, err = fs.FileSystem.Stat(c.Request.Context(), realPath); err == nil {
+			response.HTTPError(c, http.StatusForbidden, "You can't overwrite existing files in an append-only space", response.NotSpecified)
+			return
+		}
+	}
+	var snapshot usageSnapshot
+	var body *quotaReader
+	var w http.ResponseWriter = c.Writer
+	if c.Request.Method == "PUT" {
+		snapshot = snapshotUsage(c.Request.Context(), realPath)
+		if c.Request.ContentLength >= 0 {
+			err = checkQuota(c.Request.Context(), realPath, c.Request.ContentLength-snapshot.bytes)
+		} else if remaining, rerr := remainingQuota(c.Request.Context(), realPath); rerr != nil {
+			err = rerr
+		} else if remaining >= 0 {
+			// 长度未知时边写边限制，被覆盖的文件占用的空间也可以使用
+			body = &quotaReader{ReadCloser: c.Request.Body, n: remaining + snapshot.bytes}
+			c.Request.Body = body
+			w = quotaPutWriter{ResponseWriter: c.Writer, body: body}
+		}
+		if err != nil {
+			quotaError(c, err)
+			return
+		}
+	}
+	handler := fs
+	if c.Request.Method == "PROPFIND" {
+		handler = &webdav.Handler{
+			Prefix:     fs.Prefix,
+			FileSystem: newQuotaFS(c.Request.Context()),
+			LockSystem: fs.LockSystem,
+		}
+	} else if !permission.CanModify() {
+		// 上面的检查与写入之间文件可能被创建，由文件系统层保证不会覆盖
+		handler = &webdav.Handler{
+			Prefix:     fs.Prefix,
+			FileSystem: appendOnlyFS{fs.FileSystem},
+			LockSystem: fs.LockSystem,
+		}
+	}
 	http.StripPrefix("/api/ss", fs)
 	c.Request.URL.Path = "/api/ss/" + realPath
-	fs.ServeHTTP(c.Writer, c.Request)
+	handler.ServeHTTP(w, c.Request)
+	if body != nil && body.exceeded {
+		// 不保留写了一半的文件
+		if err = fs.FileSystem.RemoveAll(c.Request.Context(), realPath); err != nil {
+			logutils.Log.Errorf("remove %s after exceeding quota: %v", realPath, err)
+		}
+		snapshot.commit(c.Request.Context())
+		quotaError(c, fmt.Errorf("%w: the request body is larger than the remaining quota", ErrQuotaExceeded))
+		return
+	}
+	if c.Request.Method == "PUT" {
+		snapshot.commit(c.Request.Context())
+	} else if containsString(rwMethods, c.Request.Method) {
+		invalidateDU(realPath)
+	}
 	// 直接创建文件夹使用777权限也没有用，可能是因为父目录有设置SetGID位，权限是drwxr-sr-x，于是选择直接修改权限
 	if c.Request.Method == "MKCOL" || c.Request.Method == "PUT" {
 		chmod(c, model.RWXFolderPerm)
 	}
 }
 
+// 请求体超出配额后丢弃 webdav 写出的响应，改为返回配额错误
+type quotaPutWriter struct {
+	gin.ResponseWriter
+	body *quotaReader
+}
+
+func (w quotaPutWriter) WriteHeader(code int) {
+	if !w.body.exceeded {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w quotaPutWriter) Write(b []byte) (int, error) {
+	if w.body.exceeded {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
 func chmod(c *gin.Context, mode os.FileMode) {
 	reqPath := strings.TrimPrefix(c.Request.URL.Path, fs.Prefix)
 	if fs.Prefix != "" && len(reqPath) == len(c.Request.URL.Path) {
@@ -215,29 +332,49 @@ func chmod(c *gin.Context, mode os.FileMode) {
 	}
 }
 
+// 将文件系统内的路径转换为宿主机上的路径
+func osPath(name string) string {
+	dir, _ := fs.FileSystem.(webdav.Dir)
+	return filepath.Join(string(dir), filepath.Clean("/"+name))
+}
+
+// 生成 n 字节随机数的十六进制字符串，用作上传、回收站等的标识
+func newRandomID(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
 func AlloweOption(c *gin.Context) {
 	origin := c.Request.Header.Get("Origin")
 	if origin != "" {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
-		c.Header("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE,MKCOL,PROPFIND,PROPPATCH,MOVE,COPY")
+		c.Header("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE,MKCOL,PROPFIND,PROPPATCH,MOVE,COPY,HEAD,PATCH")
 		c.Header("Content-Type", "application/json; charset=utf-8 ")
 		c.Header("Access-Control-Allow-Credentials", "true")
 		c.Header("Access-Control-Allow-Headers", "Authorization, Content-Length,Token,session,Accept,"+
 			"Origin, Host, Connection, Accept-Encoding, Accept-Language,DNT, X-CustomHeader, X-Requested-With,"+
-			"Content-Type, Destination,X-Debug-Username")
+			"Content-Type, Destination,X-Debug-Username,X-Share-Password,"+
+			"Tus-Resumable, Upload-Length, Upload-Offset, Upload-Metadata, Upload-Defer-Length")
+		c.Header("Access-Control-Expose-Headers", "Location, Tus-Resumable, Tus-Version, Tus-Extension, Tus-Max-Size,"+
+			"Upload-Offset, Upload-Length, Upload-Metadata, Upload-Expires,"+
+			"Content-Disposition, Content-Range, Accept-Ranges, ETag, Last-Modified")
 	}
 }
 
+// 下载文件，支持 Range、If-Range、ETag、Last-Modified 等条件请求以及 HEAD
 func Download(c *gin.Context) {
 	jwttoken, err := CheckJWTToken(c)
 	if err != nil {
-		response.Error(c, err.Error(), response.NotSpecified)
+		authError(c, err)
 		return
 	}
 	path := strings.TrimPrefix(c.Request.URL.Path, "/api/ss/download/")
 	permission := GetPermission(path, jwttoken, c)
-	if permission == model.NotAllowed {
-		response.HTTPError(c, http.StatusUnauthorized, "Your permission is notAllowed", response.NotSpecified)
+	if !permission.CanRead() {
+		permissionDenied(c, path, jwttoken, "Your permission is notAllowed", response.NotSpecified)
 		return
 	}
 	realPath, err := Redirect(c, path, jwttoken)
@@ -245,20 +382,36 @@ func Download(c *gin.Context) {
 		response.Error(c, err.Error(), response.NotSpecified)
 		return
 	}
-	f, err := fs.FileSystem.OpenFile(c.Request.Context(), realPath, os.O_RDWR, 0)
+	f, err := fs.FileSystem.OpenFile(c.Request.Context(), realPath, os.O_RDONLY, 0)
 	if err != nil {
 		fmt.Println("err:", err)
 		response.BadRequestError(c, "can't find file")
 		return
 	}
 	defer f.Close()
-	c.Header("Content-Type", "application/octet-stream")
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%q\"", c.Request.URL.Path))
-	_, err = io.Copy(c.Writer, f)
+	fi, err := f.Stat()
 	if err != nil {
-		response.Error(c, "can't download file", response.NotSpecified)
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	if fi.IsDir() {
+		response.BadRequestError(c, "can't download a directory")
 		return
 	}
+	serveFile(c, f, fi)
+}
+
+// 以附件形式返回文件内容，交给 http.ServeContent 处理条件请求和分段请求
+func serveFile(c *gin.Context, f io.ReadSeeker, fi os.FileInfo) {
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fi.Name()}))
+	c.Header("ETag", fileETag(fi))
+	http.ServeContent(c.Writer, c.Request, fi.Name(), fi.ModTime(), f)
+}
+
+// 由修改时间和大小构成的 ETag，与 PROPFIND 返回的 getetag 一致
+func fileETag(fi os.FileInfo) string {
+	return fmt.Sprintf(`"%x%x"`, fi.ModTime().UnixNano(), fi.Size())
 }
 
 func containsString(slice []string, s string) bool {
@@ -325,13 +478,7 @@ func GetFilesByPaths(paths []string, c *gin.Context) []Files {
 	for _, p := range paths {
 		fi, err := fs.FileSystem.Stat(c.Request.Context(), p)
 		if err == nil {
-			var tmp Files
-			tmp.IsDir = fi.IsDir()
-			tmp.ModifyTime = fi.ModTime()
-			tmp.Name = fi.Name()
-			tmp.Size = fi.Size()
-			tmp.Sys = fi.Sys()
-			data = append(data, tmp)
+			data = append(data, fileOf(filepath.Dir(p), fi))
 		}
 	}
 	return data
@@ -342,31 +489,26 @@ func GetFiles(c *gin.Context) {
 	var data []Files
 	jwttoken, err := CheckJWTToken(c)
 	if err != nil {
-		response.Error(c, err.Error(), response.NotSpecified)
+		authError(c, err)
 		return
 	}
 	param := strings.TrimPrefix(c.Request.URL.Path, "/api/ss/files")
 	token := getFirstToken(param)
 	permission := GetPermission(param, jwttoken, c)
-	if permission == model.NotAllowed {
-		response.HTTPError(c, http.StatusUnauthorized, "Your permission is notAllowed", response.NotSpecified)
+	if !permission.CanRead() {
+		permissionDenied(c, param, jwttoken, "Your permission is notAllowed", response.NotSpecified)
 		return
 	}
 	if token == "" {
 		data = GetBasicFiles(c, jwttoken, false)
-		response.Success(c, data)
+		respondFiles(c, data, "")
 	} else {
 		realPath, err := Redirect(c, param, jwttoken)
 		if err != nil {
 			response.Error(c, err.Error(), response.NotSpecified)
 			return
 		}
-		data, err = handleDirsList(fs.FileSystem, realPath)
-		if err != nil {
-			response.Error(c, err.Error(), response.NotSpecified)
-			return
-		}
-		response.Success(c, data)
+		respondDirList(c, realPath, param)
 	}
 }
 
@@ -375,31 +517,26 @@ func GetFilesWithRWAcc(c *gin.Context) {
 	var data []Files
 	jwttoken, err := CheckJWTToken(c)
 	if err != nil {
-		response.Error(c, err.Error(), response.NotSpecified)
+		authError(c, err)
 		return
 	}
 	param := strings.TrimPrefix(c.Request.URL.Path, "/api/ss/rwfiles")
 	token := getFirstToken(param)
 	permission := GetPermission(param, jwttoken, c)
-	if permission == model.NotAllowed || (permission == model.ReadOnly && token != "") {
-		response.HTTPError(c, http.StatusUnauthorized, "You have no permission to get these files", response.NotSpecified)
+	if !permission.CanRead() || (!permission.CanModify() && token != "") {
+		permissionDenied(c, param, jwttoken, "You have no permission to get these files", response.NotSpecified)
 		return
 	}
 	if token == "" {
 		data = GetRWFiles(c, jwttoken)
-		response.Success(c, data)
+		respondFiles(c, data, "")
 	} else {
 		realPath, err := Redirect(c, param, jwttoken)
 		if err != nil {
 			response.Error(c, err.Error(), response.NotSpecified)
 			return
 		}
-		data, err = handleDirsList(fs.FileSystem, realPath)
-		if err != nil {
-			response.Error(c, err.Error(), response.NotSpecified)
-			return
-		}
-		response.Success(c, data)
+		respondDirList(c, realPath, param)
 	}
 }
 
@@ -419,7 +556,7 @@ func GetAllFiles(c *gin.Context) {
 	var data []Files
 	jwttoken, err := CheckJWTToken(c)
 	if err != nil {
-		response.Error(c, err.Error(), response.NotSpecified)
+		authError(c, err)
 		return
 	}
 	if jwttoken.RolePlatform != model.RoleAdmin {
@@ -430,19 +567,14 @@ func GetAllFiles(c *gin.Context) {
 	token := getFirstToken(path)
 	if token == "" {
 		data = GetBasicFiles(c, jwttoken, true)
-		response.Success(c, data)
+		respondFiles(c, data, "")
 	} else {
 		realPath, err := Redirect(c, path, jwttoken)
 		if err != nil {
 			response.Error(c, err.Error(), response.NotSpecified)
 			return
 		}
-		data, err = handleDirsList(fs.FileSystem, realPath)
-		if err != nil {
-			response.Error(c, err.Error(), response.NotSpecified)
-			return
-		}
-		response.Success(c, data)
+		respondDirList(c, realPath, path)
 	}
 }
 
@@ -451,6 +583,10 @@ type DatasetRequest struct {
 }
 
 func GetDatasetPermission(c *gin.Context, datasetID uint, token util.JWTMessage) model.FilePermission {
+	return limitDatasetScope(token.Scope, datasetID, getDatasetPermission(c, datasetID, token))
+}
+
+func getDatasetPermission(c *gin.Context, datasetID uint, token util.JWTMessage) model.FilePermission {
 	ud := query.UserDataset
 	d := query.Dataset
 	ad := query.AccountDataset
@@ -487,10 +623,9 @@ func GetDatasetURLByID(c *gin.Context, datasetID uint) (string, error) {
 
 // 通过数据集获取文件
 func GetDatasetFiles(c *gin.Context) {
-	var data []Files
 	jwttoken, err := CheckJWTToken(c)
 	if err != nil {
-		response.Error(c, err.Error(), response.NotSpecified)
+		authError(c, err)
 		return
 	}
 	var datasetReq DatasetRequest
@@ -503,7 +638,7 @@ func GetDatasetFiles(c *gin.Context) {
 		response.Error(c, "This dataset does not exist or you do not have permission", response.NotSpecified)
 		return
 	}
-	URL, err := GetDatasetURLByID(c, datasetReq.ID)
+	URL, err := datasetRoot(c, datasetReq.ID)
 	if err != nil {
 		response.Error(c, err.Error(), response.NotSpecified)
 		return
@@ -519,45 +654,21 @@ func GetDatasetFiles(c *gin.Context) {
 			response.Error(c, "The dataset's URL does not exist. ", response.NotSpecified)
 			return
 		}
-		response.Success(c, files)
+		respondFiles(c, files, "")
 	} else {
 		realPath := URL + "/" + strings.TrimPrefix(path, "/"+token)
-		data, err = handleDirsList(fs.FileSystem, realPath)
-		if err != nil {
-			response.Error(c, err.Error(), response.NotSpecified)
-			return
-		}
-		response.Success(c, data)
+		respondDirList(c, realPath, path)
 	}
 }
 
-func handleDirsList(fs webdav.FileSystem, path string) ([]Files, error) {
-	ctx := context.Background()
-	f, err := fs.OpenFile(ctx, path, os.O_RDONLY, 0)
-	if err != nil {
-		return nil, err
-	}
-	var files []Files
-	defer f.Close()
-	if fi, _ := f.Stat(); fi != nil && !fi.IsDir() {
-		logutils.Log.Info("cann't read a empty file")
-		return files, nil
-	}
-	dirs, err := f.Readdir(-1)
-	if err != nil {
-		logutils.Log.Info("Error reading directory")
-		return nil, err
-	}
-	var tmp Files
-	for _, d := range dirs {
-		tmp.Name = d.Name()
-		tmp.ModifyTime = d.ModTime()
-		tmp.Size = d.Size()
-		tmp.IsDir = d.IsDir()
-		tmp.Sys = d.Sys()
-		files = append(files, tmp)
+// gin 不允许 /dataset/:id/archive 与 /dataset/:id/*path 同时注册，在这里分发，
+// 数据集顶层目录本身仍可通过 /dataset/:id/<name>/ 访问
+func DatasetPath(c *gin.Context) {
+	if c.Param("path") == "/archive" {
+		DownloadDatasetArchive(c)
+		return
 	}
-	return files, nil
+	GetDatasetFiles(c)
 }
 
 type SpacePaths struct {
@@ -568,7 +679,8 @@ func checkSpace() {
 	ctx := context.Background()
 	var baseSpace []string
 	baseSpace = append(baseSpace, config.GetConfig().AccountSpacePrefix,
-		config.GetConfig().UserSpacePrefix, config.GetConfig().PublicSpacePrefix, model.DatasetPrefix, model.ModelPrefix)
+		config.GetConfig().UserSpacePrefix, config.GetConfig().PublicSpacePrefix, model.DatasetPrefix, model.ModelPrefix,
+		model.UploadPrefix, model.DatasetVersionPrefix, model.ArchivePrefix)
 	for _, space := range baseSpace {
 		_, err := fs.FileSystem.Stat(ctx, space)
 		if err != nil {
@@ -629,13 +741,13 @@ func checkSpace() {
 func DeleteFile(c *gin.Context) {
 	jwttoken, err := CheckJWTToken(c)
 	if err != nil {
-		response.Error(c, err.Error(), response.NotSpecified)
+		authError(c, err)
 		return
 	}
 	param := strings.TrimPrefix(c.Request.URL.Path, "/api/ss/delete/")
 	permission := GetPermission(param, jwttoken, c)
-	if permission != model.ReadWrite {
-		response.HTTPError(c, http.StatusUnauthorized, "You have no permission to delete file", response.NotSpecified)
+	if !permission.CanModify() {
+		permissionDenied(c, param, jwttoken, "You have no permission to delete file", response.NotSpecified)
 		return
 	}
 	realPath, err := Redirect(c, param, jwttoken)
@@ -643,16 +755,51 @@ func DeleteFile(c *gin.Context) {
 		response.Error(c, err.Error(), response.NotSpecified)
 		return
 	}
-	err = fs.FileSystem.RemoveAll(c, realPath)
-	if err != nil {
+	permanent := c.Query("permanent") == "true"
+	if err = checkDeletable(param, realPath, permanent); err != nil {
+		response.BadRequestError(c, err.Error())
+		return
+	}
+	if err = deletePath(c.Request.Context(), param, realPath, jwttoken.UserID, permanent); err != nil {
 		response.Error(c, err.Error(), response.NotSpecified)
 		return
 	}
 	response.Success(c, "Delete file successfully ")
 }
 
+// 空间根目录不能删除。不属于任何空间的路径没有回收站，必须指定 permanent，
+// 不会在放不进回收站时悄悄改为永久删除
+func checkDeletable(virtualPath, realPath string, permanent bool) error {
+	if root := spaceRootOf(realPath); root != "" && root == cleanRealPath(realPath) {
+		return fmt.Errorf("%s is the root of a space and can't be deleted", virtualPath)
+	}
+	if !permanent && !canMoveToTrash(realPath) {
+		return fmt.Errorf("%s can't be moved to trash, set permanent=true to delete it permanently", virtualPath)
+	}
+	return nil
+}
+
+// 默认移入回收站，指定 permanent 时直接删除
+func deletePath(ctx context.Context, virtualPath, realPath string, userID uint, permanent bool) error {
+	if err := checkDeletable(virtualPath, realPath, permanent); err != nil {
+		return err
+	}
+	if !permanent {
+		_, err := moveToTrash(ctx, virtualPath, realPath, userID)
+		return err
+	}
+	snapshot := snapshotUsage(ctx, realPath)
+	err := fs.FileSystem.RemoveAll(ctx, realPath)
+	snapshot.commit(ctx)
+	return err
+}
+
 // 获得用户权限
 func GetPermission(path string, token util.JWTMessage, c *gin.Context) model.FilePermission {
+	return limitPathScope(token.Scope, path, getPermission(path, token, c))
+}
+
+func getPermission(path string, token util.JWTMessage, c *gin.Context) model.FilePermission {
 	path = strings.TrimLeft(path, "/")
 	cleanedPath := filepath.Clean(path)
 	if path == "" {
@@ -662,17 +809,17 @@ func GetPermission(path string, token util.JWTMessage, c *gin.Context) model.Fil
 	switch part[0] {
 	case model.AccountPath:
 		a := query.Account
-		_, err := a.WithContext(c).Where(a.ID.Eq(token.AccountID)).First()
+		account, err := a.WithContext(c).Where(a.ID.Eq(token.AccountID)).First()
 		if err != nil {
 			return model.NotAllowed
 		}
-		return model.FilePermission(token.AccountAccessMode)
+		return limitAccountExpiry(account, token.AccountAccessMode.FilePermission())
 	case model.PublicPath:
-		return model.FilePermission(token.PublicAccessMode)
+		return token.PublicAccessMode.FilePermission()
 	case model.UserPath:
 		u := query.User
-		_, err := u.WithContext(c).Where(u.ID.Eq(token.UserID)).First()
-		if err != nil {
+		user, err := u.WithContext(c).Where(u.ID.Eq(token.UserID)).First()
+		if err != nil || user.Status != model.StatusActive {
 			return model.NotAllowed
 		}
 		return model.ReadWrite
@@ -720,7 +867,7 @@ type AccountSpaceResp struct {
 func GetUserSpace(c *gin.Context) {
 	jwttoken, err := CheckJWTToken(c)
 	if err != nil {
-		response.Error(c, err.Error(), response.NotSpecified)
+		authError(c, err)
 		return
 	}
 	if jwttoken.RolePlatform != model.RoleAdmin {
@@ -746,7 +893,7 @@ func GetUserSpace(c *gin.Context) {
 func GetAccountSpace(c *gin.Context) {
 	jwttoken, err := CheckJWTToken(c)
 	if err != nil {
-		response.Error(c, err.Error(), response.NotSpecified)
+		authError(c, err)
 		return
 	}
 	a := query.Account
@@ -787,6 +934,9 @@ func Redirect(c *gin.Context, path string, token util.JWTMessage) (string, error
 		if err != nil {
 			return "", fmt.Errorf("user does not exist")
 		}
+		if user.Status != model.StatusActive {
+			return "", ErrUserNotActive
+		}
 		res = userSpacePrefix + "/" + user.Space + res
 	} else if strings.HasPrefix(path, model.AccountPath) {
 		res = strings.TrimPrefix(path, model.AccountPath)
@@ -795,6 +945,9 @@ func Redirect(c *gin.Context, path string, token util.JWTMessage) (string, error
 		if err != nil {
 			return "", fmt.Errorf("account does not exist")
 		}
+		if expired := checkAccountExpiry(account); expired != nil && !expired.readOnly {
+			return "", expired
+		}
 		res = accountSpacePrefix + "/" + account.Space + res
 	} else if strings.HasPrefix(path, model.AdminPublicPath) {
 		res = strings.TrimPrefix(path, model.AdminPublicPath)
@@ -813,7 +966,7 @@ func Redirect(c *gin.Context, path string, token util.JWTMessage) (string, error
 	}
 	cleanedPath := filepath.Clean(path)
 	tokens := strings.Split(cleanedPath, "/")
-	if len(tokens) > 0 && tokens[0] != "." {
+	if len(tokens) > 0 && tokens[0] != "." && !isTrashPath(res) {
 		return res, nil
 	}
 	return "", fmt.Errorf("an illegal path")
@@ -821,7 +974,7 @@ func Redirect(c *gin.Context, path string, token util.JWTMessage) (string, error
 
 func RegisterFile(webdavGroup *gin.RouterGroup) {
 	webdavGroup.Handle("OPTIONS", "")
-	webdavGroup.Handle("OPTIONS", "/*path")
+	webdavGroup.Handle("OPTIONS", "/*path", Options)
 	webdavGroup.GET("/files", GetFiles)
 	webdavGroup.GET("/files/*path", GetFiles)
 	webdavGroup.GET("/rwfiles", GetFilesWithRWAcc)
@@ -829,9 +982,10 @@ func RegisterFile(webdavGroup *gin.RouterGroup) {
 	webdavGroup.GET("/admin/files", GetAllFiles)
 	webdavGroup.GET("/admin/files/*path", GetAllFiles)
 	webdavGroup.GET("/download/*path", Download)
+	webdavGroup.HEAD("/download/*path", Download)
 	webdavGroup.DELETE("/delete/*path", DeleteFile)
 	webdavGroup.GET("/userspace", GetUserSpace)
 	webdavGroup.GET("/queuespace", GetAccountSpace)
 	webdavGroup.GET("/dataset/:id", GetDatasetFiles)
-	webdavGroup.GET("/dataset/:id/*path", GetDatasetFiles)
+	webdavGroup.GET("/dataset/:id/*path", DatasetPath)
 }
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"webdav/dao/query"
+	"webdav/logutils"
+	"webdav/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gen"
+	"gorm.io/gorm/clause"
+)
+
+// 列目录时可通过 fields 选择的字段，name、size、isdir、modifytime 总是返回
+const (
+	FieldPath   = "path"
+	FieldMode   = "mode"
+	FieldOwner  = "owner"
+	FieldGroup  = "group"
+	FieldMIME   = "mime"
+	FieldLink   = "link"
+	FieldHidden = "hidden"
+)
+
+var allFileFields = []string{FieldPath, FieldMode, FieldOwner, FieldGroup, FieldMIME, FieldLink, FieldHidden}
+
+// owner、group 需要查询数据库，mime 可能需要读取文件内容，默认不返回
+var defaultFileFields = []string{FieldPath, FieldMode, FieldLink, FieldHidden}
+
+const mimeSniffLen = 512
+
+type fileFields map[string]bool
+
+// 解析逗号分隔的字段列表，all 表示全部字段，为空时使用默认字段
+func parseFileFields(s string) (fileFields, error) {
+	names := defaultFileFields
+	if s == "all" {
+		names = allFileFields
+	} else if s != "" {
+		names = strings.Split(s, ",")
+	}
+	fields := make(fileFields, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if !containsString(allFileFields, name) {
+			return nil, fmt.Errorf("unknown field %q, must be one of %s or all", name, strings.Join(allFileFields, ", "))
+		}
+		fields[name] = true
+	}
+	return fields, nil
+}
+
+func bindFileFields(c *gin.Context) (fileFields, bool) {
+	fields, err := parseFileFields(c.Query("fields"))
+	if err != nil {
+		response.BadRequestError(c, err.Error())
+		return nil, false
+	}
+	return fields, true
+}
+
+// 按 fields 补全条目的附加字段，virtualDir 为条目所在目录的虚拟路径
+func describeFiles(ctx context.Context, items []Files, virtualDir string, fields fileFields) {
+	var owners, groups map[int]string
+	if fields[FieldOwner] || fields[FieldGroup] {
+		owners, groups = lookupOwners(ctx, items)
+	}
+	for i := range items {
+		f := &items[i]
+		if fields[FieldPath] {
+			f.Path = strings.TrimPrefix(path.Join(virtualDir, f.Name), "/")
+		}
+		if fields[FieldMode] {
+			f.Mode = f.mode.String()
+		}
+		if fields[FieldHidden] {
+			hidden := strings.HasPrefix(f.Name, ".")
+			f.Hidden = &hidden
+		}
+		if fields[FieldOwner] && f.uid >= 0 {
+			f.Owner = ownerName(owners, f.uid)
+		}
+		if fields[FieldGroup] && f.gid >= 0 {
+			f.Group = ownerName(groups, f.gid)
+		}
+		if fields[FieldLink] && f.mode&os.ModeSymlink != 0 {
+			// 只返回链接本身的内容，不解析为实际路径
+			if link, err := os.Readlink(osPath(f.realPath)); err == nil {
+				f.Link = link
+			}
+		}
+		if fields[FieldMIME] {
+			f.MIME = detectMIME(ctx, f)
+		}
+	}
+}
+
+func ownerName(names map[int]string, id int) string {
+	if name, ok := names[id]; ok {
+		return name
+	}
+	return strconv.Itoa(id)
+}
+
+// 通过用户属性中的 UID、GID 查找条目所有者对应的平台用户名。
+// 多个用户的 GID 相同时，优先使用 UID 与 GID 相同的用户
+func lookupOwners(ctx context.Context, items []Files) (owners, groups map[int]string) {
+	owners, groups = make(map[int]string), make(map[int]string)
+	var uids, gids []string
+	for i := range items {
+		if uid := strconv.Itoa(items[i].uid); items[i].uid >= 0 && !containsString(uids, uid) {
+			uids = append(uids, uid)
+		}
+		if gid := strconv.Itoa(items[i].gid); items[i].gid >= 0 && !containsString(gids, gid) {
+			gids = append(gids, gid)
+		}
+	}
+	if len(uids) == 0 {
+		return owners, groups
+	}
+	u := query.User
+	users, err := u.WithContext(ctx).Select(u.ID, u.Name, u.Attributes).
+		Where(gen.Cond(clause.Expr{
+			SQL:  "(attributes->>'uid' IN ? OR attributes->>'gid' IN ?)",
+			Vars: []any{uids, gids},
+		})...).Order(u.ID).Find()
+	if err != nil {
+		logutils.Log.Warnf("look up file owners: %v", err)
+		return owners, groups
+	}
+	for _, user := range users {
+		attr := user.Attributes.Data()
+		uid, hasUID := parseID(attr.UID)
+		if _, ok := owners[uid]; hasUID && !ok {
+			owners[uid] = user.Name
+		}
+		gid, hasGID := parseID(attr.GID)
+		if _, ok := groups[gid]; hasGID && (!ok || (hasUID && uid == gid)) {
+			groups[gid] = user.Name
+		}
+	}
+	return owners, groups
+}
+
+func parseID(s *string) (int, bool) {
+	if s == nil {
+		return 0, false
+	}
+	id, err := strconv.Atoi(*s)
+	return id, err == nil
+}
+
+// 先按扩展名判断类型，无法判断的普通文件读取开头的内容识别
+func detectMIME(ctx context.Context, f *Files) string {
+	switch {
+	case f.mode.IsDir():
+		return "inode/directory"
+	case f.mode&os.ModeSymlink != 0:
+		return "inode/symlink"
+	case !f.mode.IsRegular():
+		return "application/octet-stream"
+	}
+	if t := mime.TypeByExtension(path.Ext(f.Name)); t != "" {
+		return t
+	}
+	file, err := fs.FileSystem.OpenFile(ctx, f.realPath, os.O_RDONLY, 0)
+	if err != nil {
+		return "application/octet-stream"
+	}
+	defer file.Close()
+	buf := make([]byte, mimeSniffLen)
+	n, err := io.ReadFull(file, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "application/octet-stream"
+	}
+	return http.DetectContentType(buf[:n])
+}
+
+// 补全附加字段后写入响应
+func respondFiles(c *gin.Context, items []Files, virtualDir string) {
+	fields, ok := bindFileFields(c)
+	if !ok {
+		return
+	}
+	describeFiles(c.Request.Context(), items, virtualDir, fields)
+	response.Success(c, items)
+}
+
+func fileOf(dir string, fi os.FileInfo) Files {
+	f := Files{
+		Name:       fi.Name(),
+		Size:       fi.Size(),
+		IsDir:      fi.IsDir(),
+		ModifyTime: fi.ModTime(),
+		realPath:   path.Join(dir, fi.Name()),
+		mode:       fi.Mode(),
+		uid:        -1,
+		gid:        -1,
+	}
+	if uid, gid, ok := fileOwner(fi); ok {
+		f.uid, f.gid = uid, gid
+	}
+	return f
+}
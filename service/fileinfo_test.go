@@ -0,0 +1,42 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFileFields(t *testing.T) {
+	set := func(names ...string) fileFields {
+		fields := make(fileFields)
+		for _, name := range names {
+			fields[name] = true
+		}
+		return fields
+	}
+	tests := []struct {
+		in      string
+		want    fileFields
+		wantErr bool
+	}{
+		{"", set(defaultFileFields...), false},
+		{"all", set(allFileFields...), false},
+		{"mime", set(FieldMIME), false},
+		{" owner , group ", set(FieldOwner, FieldGroup), false},
+		{"path,path", set(FieldPath), false},
+		{"size", nil, true},
+		{"MIME", nil, true},
+		{"path,", nil, true},
+		{"all,mime", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseFileFields(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
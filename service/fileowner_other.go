@@ -0,0 +1,9 @@
+//go:build !unix
+
+package service
+
+import "os"
+
+func fileOwner(_ os.FileInfo) (uid, gid int, ok bool) {
+	return 0, 0, false
+}
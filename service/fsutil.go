@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"sync/atomic"
+)
+
+// 在读取过程中响应取消，避免大文件的复制或校验无法中断。n 不为空时累加已读取的字节数
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+	n   *atomic.Int64
+}
+
+func (r ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := r.r.Read(p)
+	if r.n != nil {
+		r.n.Add(int64(n))
+	}
+	return n, err
+}
+
+// 计算文件的 SHA-256
+func hashFile(ctx context.Context, name string) (string, error) {
+	f, err := fs.FileSystem.OpenFile(ctx, name, os.O_RDONLY, 0)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, ctxReader{ctx: ctx, r: f}); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// 将普通文件 src 复制到新文件 dst 并返回内容的 SHA-256，保留权限和修改时间。
+// 文件系统支持时优先使用写时复制 (reflink)，不额外占用空间。
+func copyFile(ctx context.Context, src, dst string, fi os.FileInfo) (string, error) {
+	return copyFileProgress(ctx, src, dst, fi, nil)
+}
+
+// 与 copyFile 相同，复制过程中将已复制的字节数累加到 progress
+func copyFileProgress(ctx context.Context, src, dst string, fi os.FileInfo, progress *atomic.Int64) (string, error) {
+	in, err := fs.FileSystem.OpenFile(ctx, src, os.O_RDONLY, 0)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	out, err := fs.FileSystem.OpenFile(ctx, dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, fi.Mode().Perm())
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	var w io.Writer = io.MultiWriter(out, h)
+	inFile, ok1 := in.(*os.File)
+	outFile, ok2 := out.(*os.File)
+	if ok1 && ok2 && reflink(outFile, inFile) == nil {
+		w = h
+	}
+	_, err = io.Copy(w, ctxReader{ctx: ctx, r: in, n: progress})
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = fs.FileSystem.RemoveAll(ctx, dst)
+		return "", err
+	}
+	if err = os.Chtimes(osPath(dst), fi.ModTime(), fi.ModTime()); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"net/http"
+	"webdav/dao/model"
+	"webdav/dao/query"
+	"webdav/response"
+	"webdav/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserGrantRequest struct {
+	ID     uint `uri:"id" binding:"required"`
+	UserID uint `uri:"userID" binding:"required"`
+}
+
+type AccountGrantRequest struct {
+	ID        uint `uri:"id" binding:"required"`
+	AccountID uint `uri:"accountID" binding:"required"`
+}
+
+type GranteeResp struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	Nickname string `json:"nickname"`
+}
+
+type DatasetGranteesResp struct {
+	Users    []GranteeResp `json:"users"`
+	Accounts []GranteeResp `json:"accounts"`
+	Public   bool          `json:"public"`
+}
+
+// 只有数据集的创建者和平台管理员可以修改共享关系
+func checkGrantPermission(c *gin.Context, datasetID uint, token util.JWTMessage) bool {
+	if GetDatasetPermission(c, datasetID, token) != model.ReadWrite {
+		response.HTTPError(c, http.StatusUnauthorized, "Only the owner or admin can manage dataset sharing", response.InvalidRole)
+		return false
+	}
+	return true
+}
+
+// 列出数据集共享给的用户和账户
+func ListDatasetGrantees(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	var datasetReq DatasetRequest
+	if err = c.ShouldBindUri(&datasetReq); err != nil {
+		response.HTTPError(c, http.StatusBadRequest, err.Error(), response.NotSpecified)
+		return
+	}
+	if !checkGrantPermission(c, datasetReq.ID, jwttoken) {
+		return
+	}
+	var userIDs, accountIDs []uint
+	ud := query.UserDataset
+	if err = ud.WithContext(c).Where(ud.DatasetID.Eq(datasetReq.ID)).Pluck(ud.UserID, &userIDs); err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	ad := query.AccountDataset
+	if err = ad.WithContext(c).Where(ad.DatasetID.Eq(datasetReq.ID)).Pluck(ad.AccountID, &accountIDs); err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	data := DatasetGranteesResp{Users: []GranteeResp{}, Accounts: []GranteeResp{}}
+	if len(userIDs) != 0 {
+		u := query.User
+		users, uerr := u.WithContext(c).Where(u.ID.In(userIDs...)).Find()
+		if uerr != nil {
+			response.Error(c, uerr.Error(), response.NotSpecified)
+			return
+		}
+		for _, user := range users {
+			data.Users = append(data.Users, GranteeResp{ID: user.ID, Name: user.Name, Nickname: user.Nickname})
+		}
+	}
+	if len(accountIDs) != 0 {
+		a := query.Account
+		accounts, aerr := a.WithContext(c).Where(a.ID.In(accountIDs...)).Find()
+		if aerr != nil {
+			response.Error(c, aerr.Error(), response.NotSpecified)
+			return
+		}
+		for _, account := range accounts {
+			if account.ID == model.DefaultAccountID {
+				data.Public = true
+			}
+			data.Accounts = append(data.Accounts, GranteeResp{ID: account.ID, Name: account.Name, Nickname: account.Nickname})
+		}
+	}
+	response.Success(c, data)
+}
+
+// 将数据集共享给用户
+func GrantDatasetToUser(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	var req UserGrantRequest
+	if err = c.ShouldBindUri(&req); err != nil {
+		response.HTTPError(c, http.StatusBadRequest, err.Error(), response.NotSpecified)
+		return
+	}
+	if !checkGrantPermission(c, req.ID, jwttoken) {
+		return
+	}
+	u := query.User
+	if _, err = u.WithContext(c).Where(u.ID.Eq(req.UserID)).First(); err != nil {
+		response.Error(c, "user does not exist", response.UserNotFound)
+		return
+	}
+	ud := query.UserDataset
+	if _, err = ud.WithContext(c).Where(ud.DatasetID.Eq(req.ID), ud.UserID.Eq(req.UserID)).First(); err == nil {
+		response.Success(c, "the dataset has been shared with this user")
+		return
+	}
+	if err = ud.WithContext(c).Create(&model.UserDataset{UserID: req.UserID, DatasetID: req.ID}); err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	response.Success(c, "share dataset successfully")
+}
+
+// 取消数据集对用户的共享
+func RevokeDatasetFromUser(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	var req UserGrantRequest
+	if err = c.ShouldBindUri(&req); err != nil {
+		response.HTTPError(c, http.StatusBadRequest, err.Error(), response.NotSpecified)
+		return
+	}
+	if !checkGrantPermission(c, req.ID, jwttoken) {
+		return
+	}
+	ud := query.UserDataset
+	if _, err = ud.WithContext(c).Where(ud.DatasetID.Eq(req.ID), ud.UserID.Eq(req.UserID)).Delete(); err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	response.Success(c, "revoke dataset sharing successfully")
+}
+
+// 将数据集共享给账户，共享给默认账户即对所有用户公开
+func GrantDatasetToAccount(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	var req AccountGrantRequest
+	if err = c.ShouldBindUri(&req); err != nil {
+		response.HTTPError(c, http.StatusBadRequest, err.Error(), response.NotSpecified)
+		return
+	}
+	if !checkGrantPermission(c, req.ID, jwttoken) {
+		return
+	}
+	a := query.Account
+	if _, err = a.WithContext(c).Where(a.ID.Eq(req.AccountID)).First(); err != nil {
+		response.Error(c, "account does not exist", response.NotSpecified)
+		return
+	}
+	ad := query.AccountDataset
+	if _, err = ad.WithContext(c).Where(ad.DatasetID.Eq(req.ID), ad.AccountID.Eq(req.AccountID)).First(); err == nil {
+		response.Success(c, "the dataset has been shared with this account")
+		return
+	}
+	if err = ad.WithContext(c).Create(&model.AccountDataset{AccountID: req.AccountID, DatasetID: req.ID}); err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	response.Success(c, "share dataset successfully")
+}
+
+// 取消数据集对账户的共享
+func RevokeDatasetFromAccount(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	var req AccountGrantRequest
+	if err = c.ShouldBindUri(&req); err != nil {
+		response.HTTPError(c, http.StatusBadRequest, err.Error(), response.NotSpecified)
+		return
+	}
+	if !checkGrantPermission(c, req.ID, jwttoken) {
+		return
+	}
+	ad := query.AccountDataset
+	if _, err = ad.WithContext(c).Where(ad.DatasetID.Eq(req.ID), ad.AccountID.Eq(req.AccountID)).Delete(); err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	response.Success(c, "revoke dataset sharing successfully")
+}
+
+func RegisterGrant(webdavGroup *gin.RouterGroup) {
+	webdavGroup.GET("/datasets/:id/grantees", ListDatasetGrantees)
+	webdavGroup.POST("/datasets/:id/users/:userID", GrantDatasetToUser)
+	webdavGroup.DELETE("/datasets/:id/users/:userID", RevokeDatasetFromUser)
+	webdavGroup.POST("/datasets/:id/accounts/:accountID", GrantDatasetToAccount)
+	webdavGroup.DELETE("/datasets/:id/accounts/:accountID", RevokeDatasetFromAccount)
+}
@@ -0,0 +1,636 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"webdav/config"
+	"webdav/dao/model"
+	"webdav/dao/query"
+	"webdav/logutils"
+	"webdav/response"
+	"webdav/util"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+)
+
+const (
+	defaultJobWorkers       = 4
+	defaultJobRetentionDays = 7
+	maxJobAttempts          = 3
+	jobPollInterval         = 5 * time.Second
+	jobProgressInterval     = 2 * time.Second
+	jobRetryDelay           = 30 * time.Second
+	jobPurgeInterval        = time.Hour
+	// 任务在这段时间内完成时接口直接返回结果，否则由客户端轮询 /jobs/:id
+	jobInlineWait     = 2 * time.Second
+	jobWaitPollPeriod = 200 * time.Millisecond
+	// 执行中的任务超过这段时间没有心跳，说明执行它的实例已经退出
+	jobStaleAfter = time.Minute
+)
+
+// 资源正被其他任务使用，稍后重试
+var errJobBusy = errors.New("the resource is being used by another job")
+
+// 在本实例上执行中的任务，进度保存在内存中并定期写入数据库
+type runningJob struct {
+	job        *model.FileJob
+	cancel     context.CancelFunc
+	done       chan struct{}
+	totalBytes atomic.Int64
+	totalFiles atomic.Int64
+	doneBytes  atomic.Int64
+	doneFiles  atomic.Int64
+	result     string
+}
+
+func (r *runningJob) setTotal(bytes, files int64) {
+	r.totalBytes.Store(bytes)
+	r.totalFiles.Store(files)
+}
+
+type jobHandler func(ctx context.Context, r *runningJob) error
+
+func jobHandlerOf(jobType model.FileJobType) jobHandler {
+	switch jobType {
+	case model.FileJobMove:
+		return runMoveJob
+	case model.FileJobDatasetMove:
+		return runDatasetMoveJob
+	case model.FileJobCopy:
+		return runCopyJob
+	case model.FileJobArchive:
+		return runArchiveJob
+	case model.FileJobHash:
+		return runHashJob
+	case model.FileJobVerify:
+		return runVerifyJob
+	case model.FileJobVersion:
+		return runVersionJob
+	}
+	return nil
+}
+
+// 任务失败、取消或中断且不再重试时，清理任务对应的其他状态
+func abandonJob(ctx context.Context, job *model.FileJob) {
+	params := job.Params.Data()
+	switch job.Type {
+	case model.FileJobVersion:
+		failVersion(ctx, params.VersionID, job.Message)
+	case model.FileJobHash:
+		failManifest(ctx, params.DatasetID, false, job.Message)
+	case model.FileJobVerify:
+		failManifest(ctx, params.DatasetID, true, job.Message)
+	}
+}
+
+var (
+	runningJobs sync.Map // 任务 ID -> *runningJob
+	jobWake     = make(chan struct{}, 1)
+	jobSlots    chan struct{}
+	// 本实例的标识，记录在领取的任务中
+	jobOwner string
+)
+
+func newJobOwner() string {
+	host, _ := os.Hostname()
+	owner := fmt.Sprintf("%s-%d", host, os.Getpid())
+	if id, err := newRandomID(4); err == nil {
+		owner += "-" + id
+	}
+	return owner
+}
+
+func wakeJobs() {
+	select {
+	case jobWake <- struct{}{}:
+	default:
+	}
+}
+
+func jobFinished(status model.FileJobStatus) bool {
+	return status == model.FileJobSucceeded || status == model.FileJobFailed || status == model.FileJobCanceled
+}
+
+// 文件系统暂时不可用或资源被占用时重试，其他错误直接失败
+func isTransientError(err error) bool {
+	for _, target := range []error{errJobBusy, syscall.EAGAIN, syscall.EBUSY, syscall.EINTR, syscall.ETIMEDOUT, syscall.ESTALE} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// 创建任务，由后台的工作协程执行
+func submitJob(ctx context.Context, userID uint, jobType model.FileJobType, params model.FileJobParams,
+	totalBytes, totalFiles int64) (*model.FileJob, error) {
+	job := &model.FileJob{
+		UserID:     userID,
+		Type:       jobType,
+		Status:     model.FileJobPending,
+		Params:     datatypes.NewJSONType(params),
+		RunAfter:   time.Now(),
+		TotalBytes: totalBytes,
+		TotalFiles: totalFiles,
+	}
+	if err := query.FileJob.WithContext(ctx).Create(job); err != nil {
+		return nil, err
+	}
+	wakeJobs()
+	return job, nil
+}
+
+func getJob(ctx context.Context, id uint) (*model.FileJob, error) {
+	j := query.FileJob
+	return j.WithContext(ctx).Where(j.ID.Eq(id)).First()
+}
+
+// 等待任务结束，最多等待 timeout，返回任务最新的状态
+func waitJob(ctx context.Context, job *model.FileJob, timeout time.Duration) *model.FileJob {
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(jobWaitPollPeriod)
+	defer ticker.Stop()
+	for !jobFinished(job.Status) {
+		select {
+		case <-deadline:
+			return job
+		case <-ctx.Done():
+			return job
+		case <-ticker.C:
+		}
+		if latest, err := getJob(ctx, job.ID); err == nil {
+			job = latest
+		}
+	}
+	return job
+}
+
+// 将等待中的任务标记为执行中，多个实例同时领取时只有一个会成功
+func claimJob(ctx context.Context, job *model.FileJob) bool {
+	now := time.Now()
+	j := query.FileJob
+	info, err := j.WithContext(ctx).Where(j.ID.Eq(job.ID), j.Status.Eq(string(model.FileJobPending))).
+		UpdateSimple(j.Status.Value(string(model.FileJobRunning)), j.Attempts.Add(1), j.StartedAt.Value(now),
+			j.Owner.Value(jobOwner), j.HeartbeatAt.Value(now))
+	if err != nil || info.RowsAffected == 0 {
+		return false
+	}
+	job.Status = model.FileJobRunning
+	job.Attempts++
+	job.StartedAt = &now
+	job.Owner = jobOwner
+	job.HeartbeatAt = &now
+	return true
+}
+
+func dispatchJobs(ctx context.Context) {
+	free := cap(jobSlots) - len(jobSlots)
+	if free <= 0 {
+		return
+	}
+	j := query.FileJob
+	jobs, err := j.WithContext(ctx).Where(j.Status.Eq(string(model.FileJobPending)), j.RunAfter.Lte(time.Now())).
+		Order(j.ID).Limit(free).Find()
+	if err != nil {
+		logutils.Log.Errorf("list pending jobs: %v", err)
+		return
+	}
+	for _, job := range jobs {
+		if !claimJob(ctx, job) {
+			continue
+		}
+		jobSlots <- struct{}{}
+		go runJob(job)
+	}
+}
+
+// 保存进度并更新心跳
+func (r *runningJob) saveProgress(ctx context.Context) {
+	j := query.FileJob
+	if _, err := j.WithContext(ctx).Where(j.ID.Eq(r.job.ID), j.Owner.Eq(jobOwner)).UpdateSimple(
+		j.TotalBytes.Value(r.totalBytes.Load()), j.TotalFiles.Value(r.totalFiles.Load()),
+		j.DoneBytes.Value(r.doneBytes.Load()), j.DoneFiles.Value(r.doneFiles.Load()),
+		j.HeartbeatAt.Value(time.Now()),
+	); err != nil {
+		logutils.Log.Warnf("update progress of job %d: %v", r.job.ID, err)
+	}
+}
+
+// 任务可能在其他实例上被取消，通过数据库中的标记得知
+func (r *runningJob) cancelRequested(ctx context.Context) bool {
+	j := query.FileJob
+	job, err := j.WithContext(ctx).Select(j.CancelRequested).Where(j.ID.Eq(r.job.ID)).First()
+	return err == nil && job.CancelRequested
+}
+
+func (r *runningJob) reportProgress(stop <-chan struct{}) {
+	ticker := time.NewTicker(jobProgressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			ctx := context.Background()
+			r.saveProgress(ctx)
+			if r.cancelRequested(ctx) {
+				r.cancel()
+			}
+		}
+	}
+}
+
+func runJob(job *model.FileJob) {
+	defer func() {
+		<-jobSlots
+		wakeJobs()
+	}()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := &runningJob{job: job, cancel: cancel, done: make(chan struct{})}
+	r.setTotal(job.TotalBytes, job.TotalFiles)
+	runningJobs.Store(job.ID, r)
+	defer runningJobs.Delete(job.ID)
+
+	stop := make(chan struct{})
+	go r.reportProgress(stop)
+	var err error
+	if handler := jobHandlerOf(job.Type); handler != nil {
+		err = handler(ctx, r)
+	} else {
+		err = fmt.Errorf("unknown job type %s", job.Type)
+	}
+	close(stop)
+	finishJob(r, err)
+	close(r.done)
+}
+
+// 只更新由本实例执行的任务。任务可能因心跳超时被其他实例重置，
+// 取消标记也可能在执行期间被其他实例设置，都不能被内存中的旧值覆盖
+// 任务以 err 结束后是否会被重试
+func (r *runningJob) willRetry(err error) bool {
+	return isTransientError(err) && r.job.Attempts < maxJobAttempts
+}
+
+func finishJob(r *runningJob, err error) {
+	job := r.job
+	ctx := context.Background()
+	now := time.Now()
+	retry := r.willRetry(err)
+	if retry && r.cancelRequested(ctx) {
+		err, retry = context.Canceled, false
+	}
+	job.TotalBytes, job.TotalFiles = r.totalBytes.Load(), r.totalFiles.Load()
+	job.DoneBytes, job.DoneFiles = r.doneBytes.Load(), r.doneFiles.Load()
+	job.FinishedAt = &now
+	switch {
+	case err == nil:
+		job.Status = model.FileJobSucceeded
+		job.Message = ""
+		job.Result = r.result
+	case errors.Is(err, context.Canceled):
+		job.Status = model.FileJobCanceled
+		job.Message = "canceled"
+	case retry:
+		job.Status = model.FileJobPending
+		job.Message = err.Error()
+		job.RunAfter = now.Add(jobRetryDelay * time.Duration(job.Attempts))
+		job.DoneBytes, job.DoneFiles = 0, 0
+		job.FinishedAt = nil
+		logutils.Log.Warnf("job %d (%s) will be retried: %v", job.ID, job.Type, err)
+	default:
+		job.Status = model.FileJobFailed
+		job.Message = err.Error()
+		logutils.Log.Errorf("job %d (%s): %v", job.ID, job.Type, err)
+	}
+	j := query.FileJob
+	finishedAt := j.FinishedAt.Null()
+	if job.FinishedAt != nil {
+		finishedAt = j.FinishedAt.Value(*job.FinishedAt)
+	}
+	info, serr := j.WithContext(ctx).Where(j.ID.Eq(job.ID), j.Owner.Eq(jobOwner), j.Status.Eq(string(model.FileJobRunning))).
+		UpdateSimple(
+			j.Status.Value(string(job.Status)), j.Message.Value(job.Message), j.Result.Value(job.Result),
+			j.RunAfter.Value(job.RunAfter), finishedAt,
+			j.TotalBytes.Value(job.TotalBytes), j.TotalFiles.Value(job.TotalFiles),
+			j.DoneBytes.Value(job.DoneBytes), j.DoneFiles.Value(job.DoneFiles),
+		)
+	switch {
+	case serr != nil:
+		logutils.Log.Errorf("update job %d: %v", job.ID, serr)
+	case info.RowsAffected == 0:
+		logutils.Log.Warnf("job %d is no longer owned by this instance, result discarded", job.ID)
+	case job.Status == model.FileJobFailed || job.Status == model.FileJobCanceled:
+		abandonJob(ctx, job)
+	}
+}
+
+// 取消任务，等待中的任务直接取消。执行中的任务可能在其他实例上，
+// 先在数据库中标记，执行它的实例轮询到标记后通过 context 中断
+func cancelJob(ctx context.Context, job *model.FileJob) (*model.FileJob, error) {
+	j := query.FileJob
+	if _, err := j.WithContext(ctx).Where(j.ID.Eq(job.ID),
+		j.Status.In(string(model.FileJobPending), string(model.FileJobRunning))).UpdateSimple(j.CancelRequested.Value(true)); err != nil {
+		return nil, err
+	}
+	if _, err := j.WithContext(ctx).Where(j.ID.Eq(job.ID), j.Status.Eq(string(model.FileJobPending))).UpdateSimple(
+		j.Status.Value(string(model.FileJobCanceled)), j.Message.Value("canceled"), j.FinishedAt.Value(time.Now()),
+	); err != nil {
+		return nil, err
+	}
+	if v, ok := runningJobs.Load(job.ID); ok {
+		r, _ := v.(*runningJob)
+		r.cancel()
+	}
+	latest, err := getJob(ctx, job.ID)
+	if err != nil {
+		return nil, err
+	}
+	return waitJob(ctx, latest, jobInlineWait), nil
+}
+
+// 执行中的任务超过 jobStaleAfter 没有心跳时，执行它的实例已经退出，
+// 不能确定执行到哪一步，不自动重试。其他实例上正常执行的任务不受影响
+func resetInterruptedJobs(ctx context.Context) {
+	j := query.FileJob
+	stale := func() query.IFileJobDo {
+		return j.WithContext(ctx).Where(j.Status.Eq(string(model.FileJobRunning))).
+			Where(j.WithContext(ctx).Where(j.HeartbeatAt.IsNull()).Or(j.HeartbeatAt.Lt(time.Now().Add(-jobStaleAfter))))
+	}
+	jobs, err := stale().Find()
+	if err != nil {
+		logutils.Log.Warnf("list interrupted jobs: %v", err)
+		return
+	}
+	for _, job := range jobs {
+		job.Message = "interrupted: the instance running it has stopped"
+		// 多个实例同时重置时只有一个会成功
+		info, uerr := stale().Where(j.ID.Eq(job.ID)).UpdateSimple(
+			j.Status.Value(string(model.FileJobFailed)), j.Message.Value(job.Message), j.FinishedAt.Value(time.Now()),
+		)
+		if uerr != nil {
+			logutils.Log.Warnf("reset interrupted job %d: %v", job.ID, uerr)
+			continue
+		}
+		if info.RowsAffected > 0 {
+			abandonJob(ctx, job)
+		}
+	}
+}
+
+// 清理过期的任务记录和打包结果
+func purgeJobs(ctx context.Context) {
+	days := config.GetConfig().Job.RetentionDays
+	if days <= 0 {
+		days = defaultJobRetentionDays
+	}
+	j := query.FileJob
+	jobs, err := j.WithContext(ctx).Where(
+		j.Status.In(string(model.FileJobSucceeded), string(model.FileJobFailed), string(model.FileJobCanceled)),
+		j.FinishedAt.Lt(time.Now().AddDate(0, 0, -days)),
+	).Find()
+	if err != nil {
+		logutils.Log.Errorf("list expired jobs: %v", err)
+		return
+	}
+	for _, job := range jobs {
+		// 结果保存在以任务 ID 命名的目录中
+		if job.Result != "" {
+			if rerr := os.RemoveAll(osPath(path.Dir(job.Result))); rerr != nil {
+				logutils.Log.Warnf("remove result of job %d: %v", job.ID, rerr)
+				continue
+			}
+		}
+		if _, derr := j.WithContext(ctx).Where(j.ID.Eq(job.ID)).Delete(); derr != nil {
+			logutils.Log.Warnf("delete job %d: %v", job.ID, derr)
+		}
+	}
+}
+
+func StartJobWorkers() {
+	checkfs()
+	workers := config.GetConfig().Job.Workers
+	if workers <= 0 {
+		workers = defaultJobWorkers
+	}
+	jobSlots = make(chan struct{}, workers)
+	jobOwner = newJobOwner()
+	ctx := context.Background()
+	var lastPurge, lastReset time.Time
+	for {
+		if time.Since(lastPurge) > jobPurgeInterval {
+			purgeJobs(ctx)
+			lastPurge = time.Now()
+		}
+		// 其他实例退出时留下的任务也由存活的实例清理
+		if time.Since(lastReset) > jobStaleAfter {
+			resetInterruptedJobs(ctx)
+			lastReset = time.Now()
+		}
+		dispatchJobs(ctx)
+		select {
+		case <-jobWake:
+		case <-time.After(jobPollInterval):
+		}
+	}
+}
+
+type JobRequest struct {
+	ID uint `uri:"id" binding:"required"`
+}
+
+type ListJobsReq struct {
+	Page   int                 `form:"page"`
+	Size   int                 `form:"size"`
+	Type   model.FileJobType   `form:"type"`
+	Status model.FileJobStatus `form:"status"`
+	All    bool                `form:"all"`
+}
+
+type JobResp struct {
+	ID         uint                `json:"id"`
+	Type       model.FileJobType   `json:"type"`
+	Status     model.FileJobStatus `json:"status"`
+	Src        string              `json:"src,omitempty"`
+	Dst        string              `json:"dst,omitempty"`
+	DatasetID  uint                `json:"datasetID,omitempty"`
+	Message    string              `json:"message,omitempty"`
+	Attempts   int                 `json:"attempts"`
+	TotalBytes int64               `json:"totalBytes"`
+	TotalFiles int64               `json:"totalFiles"`
+	DoneBytes  int64               `json:"doneBytes"`
+	DoneFiles  int64               `json:"doneFiles"`
+	ETASeconds int64               `json:"etaSeconds"` // 按目前的平均速度估计的剩余秒数，未知时为 -1
+	HasResult  bool                `json:"hasResult"`  // 可以通过 /jobs/:id/result 下载结果
+	CreatedAt  time.Time           `json:"createdAt"`
+	StartedAt  *time.Time          `json:"startedAt"`
+	FinishedAt *time.Time          `json:"finishedAt"`
+}
+
+type JobListResp struct {
+	Items []JobResp `json:"items"`
+	Total int64     `json:"total"`
+}
+
+func toJobResp(job *model.FileJob) JobResp {
+	params := job.Params.Data()
+	data := JobResp{
+		ID:         job.ID,
+		Type:       job.Type,
+		Status:     job.Status,
+		Src:        params.Src,
+		Dst:        params.Dst,
+		DatasetID:  params.DatasetID,
+		Message:    job.Message,
+		Attempts:   job.Attempts,
+		TotalBytes: job.TotalBytes,
+		TotalFiles: job.TotalFiles,
+		DoneBytes:  job.DoneBytes,
+		DoneFiles:  job.DoneFiles,
+		ETASeconds: -1,
+		HasResult:  job.Status == model.FileJobSucceeded && job.Result != "",
+		CreatedAt:  job.CreatedAt,
+		StartedAt:  job.StartedAt,
+		FinishedAt: job.FinishedAt,
+	}
+	if job.Status != model.FileJobRunning {
+		if jobFinished(job.Status) {
+			data.ETASeconds = 0
+		}
+		return data
+	}
+	// 执行中的任务使用内存中的最新进度
+	if v, ok := runningJobs.Load(job.ID); ok {
+		r, _ := v.(*runningJob)
+		data.TotalBytes, data.TotalFiles = r.totalBytes.Load(), r.totalFiles.Load()
+		data.DoneBytes, data.DoneFiles = r.doneBytes.Load(), r.doneFiles.Load()
+	}
+	if job.StartedAt != nil && data.DoneBytes > 0 {
+		if elapsed := time.Since(*job.StartedAt).Seconds(); elapsed > 0 {
+			rate := float64(data.DoneBytes) / elapsed
+			data.ETASeconds = int64(float64(max(data.TotalBytes-data.DoneBytes, 0)) / rate)
+		}
+	}
+	return data
+}
+
+// 只有任务的创建者和平台管理员可以查看或取消任务
+func bindJob(c *gin.Context) (*model.FileJob, bool) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return nil, false
+	}
+	var req JobRequest
+	if err = c.ShouldBindUri(&req); err != nil {
+		response.HTTPError(c, http.StatusBadRequest, err.Error(), response.NotSpecified)
+		return nil, false
+	}
+	job, err := getJob(c, req.ID)
+	if err != nil || !canAccessJob(job, jwttoken) {
+		response.HTTPError(c, http.StatusNotFound, "job does not exist", response.NotSpecified)
+		return nil, false
+	}
+	return job, true
+}
+
+func canAccessJob(job *model.FileJob, token util.JWTMessage) bool {
+	return job.UserID == token.UserID || token.RolePlatform == model.RoleAdmin
+}
+
+// 分页列出自己的任务，管理员可以通过 all=true 查看所有人的
+func ListJobs(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	var req ListJobsReq
+	if err = c.ShouldBindQuery(&req); err != nil {
+		response.BadRequestError(c, err.Error())
+		return
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.Size <= 0 || req.Size > maxPageSize {
+		req.Size = defaultPageSize
+	}
+	j := query.FileJob
+	q := j.WithContext(c).Order(j.ID.Desc())
+	if !req.All || jwttoken.RolePlatform != model.RoleAdmin {
+		q = q.Where(j.UserID.Eq(jwttoken.UserID))
+	}
+	if req.Type != "" {
+		q = q.Where(j.Type.Eq(string(req.Type)))
+	}
+	if req.Status != "" {
+		q = q.Where(j.Status.Eq(string(req.Status)))
+	}
+	jobs, total, err := q.FindByPage((req.Page-1)*req.Size, req.Size)
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	data := JobListResp{Items: make([]JobResp, 0, len(jobs)), Total: total}
+	for _, job := range jobs {
+		data.Items = append(data.Items, toJobResp(job))
+	}
+	response.Success(c, data)
+}
+
+// 查询任务的状态和进度
+func GetJob(c *gin.Context) {
+	job, ok := bindJob(c)
+	if !ok {
+		return
+	}
+	response.Success(c, toJobResp(job))
+}
+
+// 取消任务，复制和打包任务已生成的部分会被删除
+func CancelJob(c *gin.Context) {
+	job, ok := bindJob(c)
+	if !ok {
+		return
+	}
+	if jobFinished(job.Status) {
+		response.HTTPError(c, http.StatusConflict, "the job has finished", response.NotSpecified)
+		return
+	}
+	job, err := cancelJob(c, job)
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	response.Success(c, toJobResp(job))
+}
+
+// 下载任务的结果，目前只有打包任务有结果
+func DownloadJobResult(c *gin.Context) {
+	job, ok := bindJob(c)
+	if !ok {
+		return
+	}
+	if job.Status != model.FileJobSucceeded || job.Result == "" {
+		response.HTTPError(c, http.StatusNotFound, "the job has no result", response.NotSpecified)
+		return
+	}
+	c.FileAttachment(osPath(job.Result), path.Base(job.Result))
+}
+
+func RegisterJob(webdavGroup *gin.RouterGroup) {
+	webdavGroup.GET("/jobs", ListJobs)
+	webdavGroup.GET("/jobs/:id", GetJob)
+	webdavGroup.DELETE("/jobs/:id", CancelJob)
+	webdavGroup.GET("/jobs/:id/result", DownloadJobResult)
+}
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"cmp"
+	"container/heap"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"time"
+	"webdav/dao/model"
+	"webdav/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	listBatchSize = 1000
+	maxListLimit  = 10000
+)
+
+const (
+	SortByName  = "name"
+	SortBySize  = "size"
+	SortByMtime = "mtime"
+)
+
+var errBadContinue = errors.New("invalid continue token")
+
+type ListOptions struct {
+	Limit     int    `form:"limit"`    // 每页的条目数，不指定时返回全部条目
+	Continue  string `form:"continue"` // 上一页返回的 continue
+	Sort      string `form:"sort"`     // name、size 或 mtime，默认 name
+	Order     string `form:"order"`    // asc 或 desc，默认 asc
+	Filter    string `form:"filter"`   // 文件名的通配符
+	DirsOnly  bool   `form:"dirsOnly"`
+	FilesOnly bool   `form:"filesOnly"`
+	Fields    string `form:"fields"` // 附加字段，见 parseFileFields
+
+	fields fileFields
+}
+
+type FileListResp struct {
+	Items    []Files `json:"items"`
+	Continue string  `json:"continue,omitempty"` // 还有更多条目时，作为下一页的 continue 参数
+}
+
+// 上一页最后一个条目的排序键，目录内的文件名唯一，按其他字段排序时以文件名区分相同的值
+type listCursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	Name  string `json:"n"`
+	Size  int64  `json:"z"`
+	Mtime int64  `json:"m"`
+}
+
+func encodeCursor(opts *ListOptions, f *Files) string {
+	data, _ := json.Marshal(listCursor{
+		Sort:  opts.Sort,
+		Order: opts.Order,
+		Name:  f.Name,
+		Size:  f.Size,
+		Mtime: f.ModifyTime.UnixNano(),
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(opts *ListOptions) (*Files, error) {
+	data, err := base64.RawURLEncoding.DecodeString(opts.Continue)
+	if err != nil {
+		return nil, errBadContinue
+	}
+	var cursor listCursor
+	if err = json.Unmarshal(data, &cursor); err != nil {
+		return nil, errBadContinue
+	}
+	// 换了排序方式后原来的位置没有意义
+	if cursor.Sort != opts.Sort || cursor.Order != opts.Order {
+		return nil, errBadContinue
+	}
+	return &Files{Name: cursor.Name, Size: cursor.Size, ModifyTime: time.Unix(0, cursor.Mtime)}, nil
+}
+
+// 检查并补全列目录的参数，出错时已写入响应
+func bindListOptions(c *gin.Context) (*ListOptions, bool) {
+	var opts ListOptions
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		response.BadRequestError(c, err.Error())
+		return nil, false
+	}
+	if opts.Sort == "" {
+		opts.Sort = SortByName
+	}
+	if opts.Order == "" {
+		opts.Order = "asc"
+	}
+	switch {
+	case opts.Sort != SortByName && opts.Sort != SortBySize && opts.Sort != SortByMtime:
+		response.BadRequestError(c, "sort must be name, size or mtime")
+	case opts.Order != "asc" && opts.Order != "desc":
+		response.BadRequestError(c, "order must be asc or desc")
+	case opts.DirsOnly && opts.FilesOnly:
+		response.BadRequestError(c, "dirsOnly and filesOnly can't be both set")
+	case opts.Limit < 0:
+		response.BadRequestError(c, "limit must not be negative")
+	case opts.Continue != "" && opts.Limit == 0:
+		response.BadRequestError(c, "continue requires limit")
+	default:
+		if _, err := path.Match(opts.Filter, ""); err != nil {
+			response.BadRequestError(c, "invalid filter: "+err.Error())
+			return nil, false
+		}
+		fields, err := parseFileFields(opts.Fields)
+		if err != nil {
+			response.BadRequestError(c, err.Error())
+			return nil, false
+		}
+		opts.fields = fields
+		opts.Limit = min(opts.Limit, maxListLimit)
+		return &opts, true
+	}
+	return nil, false
+}
+
+// a 是否排在 b 之前
+func (opts *ListOptions) less(a, b *Files) bool {
+	var c int
+	switch opts.Sort {
+	case SortBySize:
+		c = cmp.Compare(a.Size, b.Size)
+	case SortByMtime:
+		c = a.ModifyTime.Compare(b.ModifyTime)
+	}
+	if c == 0 {
+		c = cmp.Compare(a.Name, b.Name)
+	}
+	if opts.Order == "desc" {
+		return c > 0
+	}
+	return c < 0
+}
+
+func (opts *ListOptions) match(fi os.FileInfo) bool {
+	if (opts.DirsOnly && !fi.IsDir()) || (opts.FilesOnly && fi.IsDir()) {
+		return false
+	}
+	if opts.Filter == "" {
+		return true
+	}
+	ok, _ := path.Match(opts.Filter, fi.Name())
+	return ok
+}
+
+// 按排序顺序排在最后的条目位于堆顶，超出容量时将其丢弃
+type fileHeap struct {
+	opts  *ListOptions
+	items []Files
+}
+
+func (h *fileHeap) Len() int           { return len(h.items) }
+func (h *fileHeap) Less(i, j int) bool { return h.opts.less(&h.items[j], &h.items[i]) }
+func (h *fileHeap) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *fileHeap) Push(x any)         { h.items = append(h.items, x.(Files)) }
+func (h *fileHeap) Pop() any {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
+
+// 分批读取目录，分页时只保留当前页的条目，内存占用与目录大小无关。
+// 返回排好序的条目和下一页的 continue，path 不是目录时返回空列表
+func listDir(ctx context.Context, name string, opts *ListOptions) ([]Files, string, error) {
+	var after *Files
+	if opts.Continue != "" {
+		var err error
+		if after, err = decodeCursor(opts); err != nil {
+			return nil, "", err
+		}
+	}
+	f, err := fs.FileSystem.OpenFile(ctx, name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+	h := &fileHeap{opts: opts, items: []Files{}}
+	if fi, _ := f.Stat(); fi != nil && !fi.IsDir() {
+		return h.items, "", nil
+	}
+	isSpaceRoot := spaceRootOf(name) == cleanRealPath(name)
+	for {
+		entries, rerr := f.Readdir(listBatchSize)
+		for _, entry := range entries {
+			if isSpaceRoot && entry.Name() == model.TrashDir {
+				continue
+			}
+			if !opts.match(entry) {
+				continue
+			}
+			file := fileOf(name, entry)
+			if after != nil && !opts.less(after, &file) {
+				continue
+			}
+			if opts.Limit == 0 {
+				h.items = append(h.items, file)
+				continue
+			}
+			// 多保留一个条目，用来判断是否还有下一页
+			heap.Push(h, file)
+			if h.Len() > opts.Limit+1 {
+				heap.Pop(h)
+			}
+		}
+		if errors.Is(rerr, io.EOF) {
+			break
+		}
+		if rerr != nil {
+			return nil, "", rerr
+		}
+		if err = ctx.Err(); err != nil {
+			return nil, "", err
+		}
+	}
+	items := h.items
+	sort.Slice(items, func(i, j int) bool { return opts.less(&items[i], &items[j]) })
+	var next string
+	if opts.Limit > 0 && len(items) > opts.Limit {
+		items = items[:opts.Limit]
+		next = encodeCursor(opts, &items[len(items)-1])
+	}
+	return items, next, nil
+}
+
+// 列出目录并写入响应。指定 limit 时返回 FileListResp，否则与原来一样返回全部条目的数组。
+// virtualDir 为目录的虚拟路径，用于生成条目的 path 字段
+func respondDirList(c *gin.Context, realPath, virtualDir string) {
+	opts, ok := bindListOptions(c)
+	if !ok {
+		return
+	}
+	items, next, err := listDir(c.Request.Context(), realPath, opts)
+	if errors.Is(err, errBadContinue) {
+		response.BadRequestError(c, err.Error())
+		return
+	}
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	// 只为当前页的条目补全附加字段
+	describeFiles(c.Request.Context(), items, virtualDir, opts.fields)
+	if opts.Limit == 0 {
+		response.Success(c, items)
+		return
+	}
+	response.Success(c, FileListResp{Items: items, Continue: next})
+}
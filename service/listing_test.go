@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 创建用于列目录的测试目录，返回其在 fs 中的路径
+func newListFixture(t *testing.T) string {
+	t.Helper()
+	dir := filepath.Join(useTempFS(t), "list")
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := []struct {
+		name  string
+		size  int
+		dir   bool
+		mtime int // 相对 base 的秒数
+	}{
+		{"a.txt", 3, false, 5},
+		{"b.txt", 1, false, 1},
+		{"c.log", 2, false, 3},
+		{"d", 0, true, 2},
+		{"e", 0, true, 4},
+		{"f.txt", 3, false, 0},
+	}
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		p := filepath.Join(dir, e.name)
+		var err error
+		if e.dir {
+			err = os.Mkdir(p, 0o755)
+		} else {
+			err = os.WriteFile(p, make([]byte, e.size), 0o644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		mtime := base.Add(time.Duration(e.mtime) * time.Second)
+		if err = os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return "/list"
+}
+
+func listNames(items []Files) []string {
+	names := make([]string, 0, len(items))
+	for _, f := range items {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+func TestListDirPaging(t *testing.T) {
+	dir := newListFixture(t)
+	tests := []struct {
+		name string
+		opts ListOptions
+		want []string
+	}{
+		{"name asc", ListOptions{Sort: SortByName, Order: "asc"}, []string{"a.txt", "b.txt", "c.log", "d", "e", "f.txt"}},
+		{"name desc", ListOptions{Sort: SortByName, Order: "desc"}, []string{"f.txt", "e", "d", "c.log", "b.txt", "a.txt"}},
+		{"size asc, ties by name", ListOptions{Sort: SortBySize, Order: "asc", FilesOnly: true}, []string{"b.txt", "c.log", "a.txt", "f.txt"}},
+		{"size desc, ties by name", ListOptions{Sort: SortBySize, Order: "desc", FilesOnly: true}, []string{"f.txt", "a.txt", "c.log", "b.txt"}},
+		{"mtime asc", ListOptions{Sort: SortByMtime, Order: "asc"}, []string{"f.txt", "b.txt", "d", "c.log", "e", "a.txt"}},
+		{"mtime desc", ListOptions{Sort: SortByMtime, Order: "desc"}, []string{"a.txt", "e", "c.log", "d", "b.txt", "f.txt"}},
+		{"filter", ListOptions{Sort: SortByName, Order: "asc", Filter: "*.txt"}, []string{"a.txt", "b.txt", "f.txt"}},
+		{"filter without match", ListOptions{Sort: SortByName, Order: "asc", Filter: "*.csv"}, []string{}},
+		{"dirs only", ListOptions{Sort: SortByName, Order: "desc", DirsOnly: true}, []string{"e", "d"}},
+		{"files only with filter", ListOptions{Sort: SortByMtime, Order: "asc", FilesOnly: true, Filter: "?.*"}, []string{"f.txt", "b.txt", "c.log", "a.txt"}},
+	}
+	for _, tt := range tests {
+		for _, limit := range []int{0, 1, 2, 4, 100} {
+			t.Run(tt.name+"/limit "+strconv.Itoa(limit), func(t *testing.T) {
+				opts := tt.opts
+				opts.Limit = limit
+				got := []string{}
+				for pages := 0; ; pages++ {
+					if pages > 10 {
+						t.Fatal("too many pages")
+					}
+					items, next, err := listDir(context.Background(), dir, &opts)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if limit > 0 && len(items) > limit {
+						t.Fatalf("page has %d items, limit %d", len(items), limit)
+					}
+					got = append(got, listNames(items)...)
+					if next == "" {
+						break
+					}
+					opts.Continue = next
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestListDirContinue(t *testing.T) {
+	dir := newListFixture(t)
+	byName := ListOptions{Sort: SortByName, Order: "asc", Limit: 2}
+	_, next, err := listDir(context.Background(), dir, &byName)
+	if err != nil || next == "" {
+		t.Fatalf("first page: next = %q, err = %v", next, err)
+	}
+	// 上一页最后的条目被删除后，下一页仍从它之后开始
+	if err = os.Remove(osPath(dir + "/b.txt")); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		opts    ListOptions
+		want    []string
+		wantErr bool
+	}{
+		{"after deleted entry", ListOptions{Sort: SortByName, Order: "asc", Limit: 2, Continue: next}, []string{"c.log", "d"}, false},
+		{"different sort", ListOptions{Sort: SortBySize, Order: "asc", Limit: 2, Continue: next}, nil, true},
+		{"different order", ListOptions{Sort: SortByName, Order: "desc", Limit: 2, Continue: next}, nil, true},
+		{"not base64", ListOptions{Sort: SortByName, Order: "asc", Limit: 2, Continue: "!!!"}, nil, true},
+		{"not json", ListOptions{Sort: SortByName, Order: "asc", Limit: 2, Continue: "bm90IGpzb24"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, _, err := listDir(context.Background(), dir, &tt.opts)
+			if tt.wantErr {
+				if !errors.Is(err, errBadContinue) {
+					t.Fatalf("err = %v, want errBadContinue", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := listNames(items); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindListOptions(t *testing.T) {
+	tests := []struct {
+		query string
+		ok    bool
+		sort  string
+		order string
+		limit int
+	}{
+		{"", true, SortByName, "asc", 0},
+		{"sort=size&order=desc&limit=5", true, SortBySize, "desc", 5},
+		{"limit=999999", true, SortByName, "asc", maxListLimit},
+		{"sort=color", false, "", "", 0},
+		{"order=up", false, "", "", 0},
+		{"dirsOnly=true&filesOnly=true", false, "", "", 0},
+		{"limit=-1", false, "", "", 0},
+		{"continue=abc", false, "", "", 0},
+		{"filter=[", false, "", "", 0},
+		{"fields=size", false, "", "", 0},
+		{"limit=abc", false, "", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", "/?"+tt.query, nil)
+			opts, ok := bindListOptions(c)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v (%s)", ok, tt.ok, w.Body.String())
+			}
+			if !ok {
+				return
+			}
+			if opts.Sort != tt.sort || opts.Order != tt.order || opts.Limit != tt.limit {
+				t.Errorf("got sort=%s order=%s limit=%d", opts.Sort, opts.Order, opts.Limit)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"os"
+	"testing"
+
+	"golang.org/x/net/webdav"
+)
+
+func TestMain(m *testing.M) {
+	// 配置文件的路径相对于仓库根目录
+	if err := os.Chdir(".."); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// 让全局的 fs 指向临时目录，返回该目录的实际路径
+func useTempFS(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	old := fs
+	fs = &webdav.Handler{FileSystem: webdav.Dir(dir)}
+	t.Cleanup(func() { fs = old })
+	return dir
+}
@@ -0,0 +1,657 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"webdav/dao/model"
+	"webdav/dao/query"
+	"webdav/logutils"
+	"webdav/response"
+	"webdav/util"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+	"gorm.io/gen"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	hashDatasetsInterval = 10 * time.Minute
+	checksumBatch        = 500
+)
+
+// 清单生成或校验的等待中和执行中状态。同一个数据集同时只允许一个生成或校验任务，
+// 提交任务前在数据库中将状态改为等待中，多个实例同时提交时只有一个会成功
+var manifestBusyStatus = []string{string(model.ManifestPending), string(model.ManifestRunning)}
+
+type ManifestResp struct {
+	DatasetID uint                 `json:"datasetID"`
+	Status    model.ManifestStatus `json:"status"`
+	Message   string               `json:"message,omitempty"`
+	Files     int64                `json:"files"`
+	Bytes     int64                `json:"bytes"`
+	HashedAt  *time.Time           `json:"hashedAt"`
+	Entries   []ChecksumResp       `json:"entries,omitempty"`
+	JobID     uint                 `json:"jobID,omitempty"` // 重新生成清单的任务
+}
+
+type VerifyResp struct {
+	DatasetID  uint                 `json:"datasetID"`
+	Status     model.ManifestStatus `json:"status"`
+	Error      string               `json:"error,omitempty"`
+	VerifiedAt *time.Time           `json:"verifiedAt"`
+	Missing    []string             `json:"missing"`
+	Extra      []string             `json:"extra"`
+	Corrupted  []string             `json:"corrupted"`
+	JobID      uint                 `json:"jobID,omitempty"` // 校验任务
+}
+
+func toManifestResp(m *model.DatasetManifest) ManifestResp {
+	return ManifestResp{
+		DatasetID: m.DatasetID,
+		Status:    m.Status,
+		Message:   m.Message,
+		Files:     m.Files,
+		Bytes:     m.Bytes,
+		HashedAt:  m.HashedAt,
+	}
+}
+
+func toVerifyResp(m *model.DatasetManifest) VerifyResp {
+	report := m.Report.Data()
+	resp := VerifyResp{
+		DatasetID:  m.DatasetID,
+		Status:     m.VerifyStatus,
+		Error:      m.VerifyError,
+		VerifiedAt: m.VerifiedAt,
+		Missing:    report.Missing,
+		Extra:      report.Extra,
+		Corrupted:  report.Corrupted,
+	}
+	for _, list := range []*[]string{&resp.Missing, &resp.Extra, &resp.Corrupted} {
+		if *list == nil {
+			*list = []string{}
+		}
+	}
+	return resp
+}
+
+// 遍历数据集中的普通文件，rel 为相对数据集根目录的路径，数据集本身是文件时为文件名
+func walkDatasetFiles(ctx context.Context, root string, fn func(p, rel string, fi os.FileInfo) error) error {
+	root = cleanRealPath(root)
+	fi, err := fs.FileSystem.Stat(ctx, root)
+	if err != nil {
+		return err
+	}
+	return walkFS(ctx, root, fi, func(p string, fi os.FileInfo) error {
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(p, root), "/")
+		if rel == "" {
+			rel = fi.Name()
+		}
+		return fn(p, rel, fi)
+	})
+}
+
+// 生成数据集的清单，调用方需已在数据库中占用该数据集。r 不为空时报告进度
+func buildManifest(ctx context.Context, m *model.DatasetManifest, root string, r *runningJob) error {
+	dm := query.DatasetManifest
+	dc := query.DatasetChecksum
+	m.Status = model.ManifestRunning
+	m.Message = ""
+	if err := dm.WithContext(ctx).Save(m); err != nil {
+		return err
+	}
+	var files, bytes int64
+	var batch []*model.DatasetChecksum
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		ferr := dc.WithContext(ctx).CreateInBatches(batch, checksumBatch)
+		batch = batch[:0]
+		return ferr
+	}
+	_, err := dc.WithContext(ctx).Where(dc.DatasetID.Eq(m.DatasetID)).Delete()
+	if err == nil {
+		err = walkDatasetFiles(ctx, root, func(p, rel string, fi os.FileInfo) error {
+			sum, herr := hashFile(ctx, p)
+			if herr != nil {
+				return herr
+			}
+			files++
+			bytes += fi.Size()
+			if r != nil {
+				r.doneFiles.Add(1)
+				r.doneBytes.Add(fi.Size())
+			}
+			batch = append(batch, &model.DatasetChecksum{DatasetID: m.DatasetID, Path: rel, Size: fi.Size(), SHA256: sum})
+			if len(batch) >= checksumBatch {
+				return flush()
+			}
+			return nil
+		})
+	}
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		if _, derr := dc.WithContext(ctx).Where(dc.DatasetID.Eq(m.DatasetID)).Delete(); derr != nil {
+			logutils.Log.Errorf("remove checksums of dataset %d: %v", m.DatasetID, derr)
+		}
+		m.Status = model.ManifestFailed
+		m.Message = err.Error()
+		if serr := dm.WithContext(ctx).Save(m); serr != nil {
+			logutils.Log.Errorf("update manifest of dataset %d: %v", m.DatasetID, serr)
+		}
+		return err
+	}
+	now := time.Now()
+	m.Status = model.ManifestSucceeded
+	m.Files = files
+	m.Bytes = bytes
+	m.HashedAt = &now
+	// 旧的校验结果针对的是旧清单
+	m.VerifyStatus = ""
+	m.VerifyError = ""
+	m.VerifiedAt = nil
+	m.Report = datatypes.NewJSONType(model.VerifyReport{})
+	return dm.WithContext(ctx).Save(m)
+}
+
+// 重新计算数据集中文件的哈希并与清单比对，调用方需已在数据库中占用该数据集。r 不为空时报告进度
+func verifyManifest(ctx context.Context, m *model.DatasetManifest, root string, r *runningJob) error {
+	dm := query.DatasetManifest
+	dc := query.DatasetChecksum
+	m.VerifyStatus = model.ManifestRunning
+	m.VerifyError = ""
+	if err := dm.WithContext(ctx).Save(m); err != nil {
+		return err
+	}
+	expected := make(map[string]*model.DatasetChecksum, m.Files)
+	var results []*model.DatasetChecksum
+	err := dc.WithContext(ctx).Where(dc.DatasetID.Eq(m.DatasetID)).FindInBatches(&results, checksumBatch, func(_ gen.Dao, _ int) error {
+		for _, r := range results {
+			expected[r.Path] = r
+		}
+		return nil
+	})
+	report := model.VerifyReport{Missing: []string{}, Extra: []string{}, Corrupted: []string{}}
+	if err == nil {
+		err = walkDatasetFiles(ctx, root, func(p, rel string, fi os.FileInfo) error {
+			if r != nil {
+				r.doneFiles.Add(1)
+				r.doneBytes.Add(fi.Size())
+			}
+			want, ok := expected[rel]
+			if !ok {
+				report.Extra = append(report.Extra, rel)
+				return nil
+			}
+			delete(expected, rel)
+			if want.Size != fi.Size() {
+				report.Corrupted = append(report.Corrupted, rel)
+				return nil
+			}
+			sum, herr := hashFile(ctx, p)
+			if herr != nil {
+				return herr
+			}
+			if sum != want.SHA256 {
+				report.Corrupted = append(report.Corrupted, rel)
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		m.VerifyStatus = model.ManifestFailed
+		m.VerifyError = err.Error()
+		if serr := dm.WithContext(ctx).Save(m); serr != nil {
+			logutils.Log.Errorf("update manifest of dataset %d: %v", m.DatasetID, serr)
+		}
+		return err
+	}
+	for rel := range expected {
+		report.Missing = append(report.Missing, rel)
+	}
+	sort.Strings(report.Missing)
+	sort.Strings(report.Extra)
+	sort.Strings(report.Corrupted)
+	now := time.Now()
+	m.VerifyStatus = model.ManifestSucceeded
+	m.VerifiedAt = &now
+	m.Report = datatypes.NewJSONType(report)
+	return dm.WithContext(ctx).Save(m)
+}
+
+func getManifest(ctx context.Context, datasetID uint) (*model.DatasetManifest, error) {
+	dm := query.DatasetManifest
+	return dm.WithContext(ctx).Where(dm.DatasetID.Eq(datasetID)).First()
+}
+
+var errManifestBusy = errors.New("the manifest of this dataset is being generated or verified")
+
+// 数据集的清单是否正在生成或校验
+func manifestBusy(ctx context.Context, datasetID uint) (bool, error) {
+	dm := query.DatasetManifest
+	n, err := dm.WithContext(ctx).Where(dm.DatasetID.Eq(datasetID)).
+		Where(dm.WithContext(ctx).Where(dm.Status.In(manifestBusyStatus...)).Or(dm.VerifyStatus.In(manifestBusyStatus...))).
+		Count()
+	return n > 0, err
+}
+
+// 没有在生成或校验的清单
+func manifestIdle(ctx context.Context) query.IDatasetManifestDo {
+	dm := query.DatasetManifest
+	return dm.WithContext(ctx).Where(dm.Status.NotIn(manifestBusyStatus...)).
+		Where(dm.WithContext(ctx).Where(dm.VerifyStatus.NotIn(manifestBusyStatus...)).Or(dm.VerifyStatus.IsNull()))
+}
+
+// 在数据库中占用数据集，将清单标记为等待生成，已被占用时返回 errManifestBusy
+func claimManifestBuild(ctx context.Context, datasetID uint) error {
+	dm := query.DatasetManifest
+	info, err := manifestIdle(ctx).Where(dm.DatasetID.Eq(datasetID)).
+		UpdateSimple(dm.Status.Value(string(model.ManifestPending)), dm.Message.Value(""), dm.UpdatedAt.Value(time.Now()))
+	if err != nil {
+		return err
+	}
+	if info.RowsAffected > 0 {
+		return nil
+	}
+	// 还没有清单时创建，唯一索引保证只有一个实例成功
+	result := dm.WithContext(ctx).UnderlyingDB().Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.DatasetManifest{DatasetID: datasetID, Status: model.ManifestPending})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errManifestBusy
+	}
+	return nil
+}
+
+// 在数据库中占用数据集，将校验状态标记为等待中
+func claimManifestVerify(ctx context.Context, datasetID uint) error {
+	dm := query.DatasetManifest
+	info, err := manifestIdle(ctx).Where(dm.DatasetID.Eq(datasetID), dm.Status.Eq(string(model.ManifestSucceeded))).
+		UpdateSimple(dm.VerifyStatus.Value(string(model.ManifestPending)), dm.VerifyError.Value(""), dm.UpdatedAt.Value(time.Now()))
+	if err != nil {
+		return err
+	}
+	if info.RowsAffected > 0 {
+		return nil
+	}
+	m, err := getManifest(ctx, datasetID)
+	if err != nil || m.Status != model.ManifestSucceeded {
+		return fmt.Errorf("the manifest of dataset %d is not ready", datasetID)
+	}
+	return errManifestBusy
+}
+
+// 生成或校验没有完成，任务不会再执行时将状态标记为失败，释放数据集
+func failManifest(ctx context.Context, datasetID uint, verify bool, message string) {
+	dm := query.DatasetManifest
+	q := dm.WithContext(ctx).Where(dm.DatasetID.Eq(datasetID))
+	var err error
+	if verify {
+		_, err = q.Where(dm.VerifyStatus.In(manifestBusyStatus...)).
+			UpdateSimple(dm.VerifyStatus.Value(string(model.ManifestFailed)), dm.VerifyError.Value(message))
+	} else {
+		_, err = q.Where(dm.Status.In(manifestBusyStatus...)).
+			UpdateSimple(dm.Status.Value(string(model.ManifestFailed)), dm.Message.Value(message))
+	}
+	if err != nil {
+		logutils.Log.Errorf("update manifest of dataset %d: %v", datasetID, err)
+	}
+}
+
+// 提交重新生成清单的任务
+func startBuildManifest(ctx context.Context, userID, datasetID uint) (*model.DatasetManifest, *model.FileJob, error) {
+	if _, err := datasetURL(ctx, datasetID); err != nil {
+		return nil, nil, err
+	}
+	if err := claimManifestBuild(ctx, datasetID); err != nil {
+		return nil, nil, err
+	}
+	job, err := submitJob(ctx, userID, model.FileJobHash, model.FileJobParams{DatasetID: datasetID}, 0, 0)
+	if err != nil {
+		failManifest(ctx, datasetID, false, err.Error())
+		return nil, nil, err
+	}
+	m, err := getManifest(ctx, datasetID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, job, nil
+}
+
+// 提交校验数据集的任务，清单尚未生成时返回错误
+func startVerifyManifest(ctx context.Context, userID, datasetID uint) (*model.DatasetManifest, *model.FileJob, error) {
+	if _, err := datasetURL(ctx, datasetID); err != nil {
+		return nil, nil, err
+	}
+	if err := claimManifestVerify(ctx, datasetID); err != nil {
+		return nil, nil, err
+	}
+	m, err := getManifest(ctx, datasetID)
+	if err != nil {
+		return nil, nil, err
+	}
+	job, err := submitJob(ctx, userID, model.FileJobVerify, model.FileJobParams{DatasetID: datasetID}, m.Bytes, m.Files)
+	if err != nil {
+		failManifest(ctx, datasetID, true, err.Error())
+		return nil, nil, err
+	}
+	return m, job, nil
+}
+
+// 任务被取消时 buildManifest 和 verifyManifest 无法用已取消的 ctx 保存失败状态，在这里补上
+func saveInterruptedManifest(ctx context.Context, m *model.DatasetManifest) {
+	if ctx.Err() == nil {
+		return
+	}
+	if err := query.DatasetManifest.WithContext(context.Background()).Save(m); err != nil {
+		logutils.Log.Errorf("update manifest of dataset %d: %v", m.DatasetID, err)
+	}
+}
+
+// 提交任务时已在数据库中占用数据集，失败且不再重试时由 abandonJob 释放
+func runHashJob(ctx context.Context, r *runningJob) error {
+	datasetID := r.job.Params.Data().DatasetID
+	URL, err := datasetURL(ctx, datasetID)
+	if err != nil {
+		return err
+	}
+	m, err := getManifest(ctx, datasetID)
+	if err != nil {
+		return err
+	}
+	if bytes, files, uerr := treeUsage(ctx, URL); uerr == nil {
+		r.setTotal(bytes, files)
+	}
+	err = buildManifest(ctx, m, URL, r)
+	saveInterruptedManifest(ctx, m)
+	if r.willRetry(err) {
+		// 保持占用，避免重试前又提交了新的任务
+		dm := query.DatasetManifest
+		if _, uerr := dm.WithContext(context.Background()).Where(dm.DatasetID.Eq(datasetID)).
+			UpdateSimple(dm.Status.Value(string(model.ManifestPending))); uerr != nil {
+			logutils.Log.Errorf("update manifest of dataset %d: %v", datasetID, uerr)
+		}
+	}
+	return err
+}
+
+func runVerifyJob(ctx context.Context, r *runningJob) error {
+	datasetID := r.job.Params.Data().DatasetID
+	m, err := getManifest(ctx, datasetID)
+	if err != nil || m.Status != model.ManifestSucceeded {
+		return fmt.Errorf("the manifest of dataset %d is not ready", datasetID)
+	}
+	URL, err := datasetURL(ctx, datasetID)
+	if err != nil {
+		return err
+	}
+	r.setTotal(m.Bytes, m.Files)
+	err = verifyManifest(ctx, m, URL, r)
+	saveInterruptedManifest(ctx, m)
+	if r.willRetry(err) {
+		dm := query.DatasetManifest
+		if _, uerr := dm.WithContext(context.Background()).Where(dm.DatasetID.Eq(datasetID)).
+			UpdateSimple(dm.VerifyStatus.Value(string(model.ManifestPending))); uerr != nil {
+			logutils.Log.Errorf("update manifest of dataset %d: %v", datasetID, uerr)
+		}
+	}
+	return err
+}
+
+func datasetURL(ctx context.Context, datasetID uint) (string, error) {
+	d := query.Dataset
+	dataset, err := d.WithContext(ctx).Where(d.ID.Eq(datasetID)).First()
+	if err != nil {
+		return "", err
+	}
+	return dataset.URL, nil
+}
+
+// sha256sum 的输出格式，路径中含有反斜杠或换行时需要转义并在行首加反斜杠
+func sha256sumLine(sum, p string) string {
+	if strings.ContainsAny(p, "\\\n\r") {
+		p = strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\r", "\\r").Replace(p)
+		return "\\" + sum + "  " + p + "\n"
+	}
+	return sum + "  " + p + "\n"
+}
+
+func bindManifestDataset(c *gin.Context, token util.JWTMessage, write bool) (uint, bool) {
+	var datasetReq DatasetRequest
+	if err := c.ShouldBindUri(&datasetReq); err != nil {
+		response.HTTPError(c, http.StatusBadRequest, err.Error(), response.NotSpecified)
+		return 0, false
+	}
+	permission := GetDatasetPermission(c, datasetReq.ID, token)
+	if permission == model.NotAllowed {
+		response.Error(c, "This dataset does not exist or you do not have permission", response.NotSpecified)
+		return 0, false
+	}
+	if write && permission != model.ReadWrite {
+		response.HTTPError(c, http.StatusUnauthorized, "Only the owner or admin can generate or verify the manifest", response.InvalidRole)
+		return 0, false
+	}
+	return datasetReq.ID, true
+}
+
+// 获取数据集的 SHA-256 清单，format=sha256sum 时以 sha256sum -c 可用的格式下载
+func GetDatasetManifest(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	datasetID, ok := bindManifestDataset(c, jwttoken, false)
+	if !ok {
+		return
+	}
+	m, err := getManifest(c, datasetID)
+	if err != nil {
+		response.HTTPError(c, http.StatusNotFound, "the manifest of this dataset has not been generated", response.NotSpecified)
+		return
+	}
+	format := c.Query("format")
+	if m.Status != model.ManifestSucceeded {
+		if format == "sha256sum" {
+			response.HTTPError(c, http.StatusConflict, fmt.Sprintf("the manifest is %s", m.Status), response.NotSpecified)
+			return
+		}
+		response.Success(c, toManifestResp(m))
+		return
+	}
+	dc := query.DatasetChecksum
+	q := dc.WithContext(c).Where(dc.DatasetID.Eq(datasetID)).Order(dc.Path)
+	if format == "sha256sum" {
+		c.Header("Content-Type", "text/plain; charset=utf-8")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=dataset-%d.sha256", datasetID))
+		c.Status(http.StatusOK)
+		var results []*model.DatasetChecksum
+		// 响应已经开始写出，出错时只能记录日志并中断连接
+		err = q.FindInBatches(&results, checksumBatch, func(_ gen.Dao, _ int) error {
+			for _, r := range results {
+				if _, werr := c.Writer.WriteString(sha256sumLine(r.SHA256, r.Path)); werr != nil {
+					return werr
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			logutils.Log.Errorf("manifest of dataset %d: %v", datasetID, err)
+			panic(http.ErrAbortHandler)
+		}
+		return
+	}
+	entries, err := q.Find()
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	data := toManifestResp(m)
+	data.Entries = make([]ChecksumResp, 0, len(entries))
+	for _, e := range entries {
+		data.Entries = append(data.Entries, ChecksumResp{Path: e.Path, Size: e.Size, SHA256: e.SHA256})
+	}
+	response.Success(c, data)
+}
+
+// 重新生成数据集的清单
+func RebuildDatasetManifest(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	datasetID, ok := bindManifestDataset(c, jwttoken, true)
+	if !ok {
+		return
+	}
+	m, job, err := startBuildManifest(c, jwttoken.UserID, datasetID)
+	if errors.Is(err, errManifestBusy) {
+		response.HTTPError(c, http.StatusConflict, err.Error(), response.NotSpecified)
+		return
+	}
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	data := toManifestResp(m)
+	data.JobID = job.ID
+	response.Success(c, data)
+}
+
+// 按清单重新计算哈希，报告缺失、多出和损坏的文件
+func VerifyDatasetManifest(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	datasetID, ok := bindManifestDataset(c, jwttoken, true)
+	if !ok {
+		return
+	}
+	m, job, err := startVerifyManifest(c, jwttoken.UserID, datasetID)
+	if errors.Is(err, errManifestBusy) {
+		response.HTTPError(c, http.StatusConflict, err.Error(), response.NotSpecified)
+		return
+	}
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	data := toVerifyResp(m)
+	data.JobID = job.ID
+	response.Success(c, data)
+}
+
+// 获取最近一次校验的结果
+func GetDatasetVerifyReport(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	datasetID, ok := bindManifestDataset(c, jwttoken, false)
+	if !ok {
+		return
+	}
+	m, err := getManifest(c, datasetID)
+	if err != nil {
+		response.HTTPError(c, http.StatusNotFound, "the manifest of this dataset has not been generated", response.NotSpecified)
+		return
+	}
+	response.Success(c, toVerifyResp(m))
+}
+
+// 为还没有清单的数据集提交生成清单的任务，由任务框架在某一个实例上执行
+func hashDatasets() {
+	ctx := context.Background()
+	dm := query.DatasetManifest
+	var hashed []uint
+	if err := dm.WithContext(ctx).Pluck(dm.DatasetID, &hashed); err != nil {
+		logutils.Log.Errorf("get dataset manifests: %v", err)
+		return
+	}
+	d := query.Dataset
+	q := d.WithContext(ctx).Where(d.ID.IsNotNull())
+	if len(hashed) != 0 {
+		q = q.Where(d.ID.NotIn(hashed...))
+	}
+	datasets, err := q.Find()
+	if err != nil {
+		logutils.Log.Errorf("get datasets without manifest: %v", err)
+		return
+	}
+	for _, dataset := range datasets {
+		_, job, err := startBuildManifest(ctx, 0, dataset.ID)
+		switch {
+		case errors.Is(err, errManifestBusy):
+		case err != nil:
+			logutils.Log.Warnf("hash dataset %d: %v", dataset.ID, err)
+		default:
+			logutils.Log.Infof("hash dataset %s in job %d", dataset.URL, job.ID)
+		}
+	}
+}
+
+// 占用数据集的任务已经不存在时释放数据集，例如升级前由后台循环直接生成的清单被中断。
+// 刚占用、还没来得及提交任务的数据集不受影响
+func releaseOrphanManifests(ctx context.Context) {
+	j := query.FileJob
+	jobs, err := j.WithContext(ctx).Where(j.Type.In(string(model.FileJobHash), string(model.FileJobVerify)),
+		j.Status.In(string(model.FileJobPending), string(model.FileJobRunning))).Find()
+	if err != nil {
+		logutils.Log.Warnf("get manifest jobs: %v", err)
+		return
+	}
+	active := make([]uint, 0, len(jobs))
+	for _, job := range jobs {
+		active = append(active, job.Params.Data().DatasetID)
+	}
+	dm := query.DatasetManifest
+	q := dm.WithContext(ctx).Where(dm.UpdatedAt.Lt(time.Now().Add(-jobStaleAfter))).
+		Where(dm.WithContext(ctx).Where(dm.Status.In(manifestBusyStatus...)).Or(dm.VerifyStatus.In(manifestBusyStatus...)))
+	if len(active) != 0 {
+		q = q.Where(dm.DatasetID.NotIn(active...))
+	}
+	orphans, err := q.Find()
+	if err != nil {
+		logutils.Log.Warnf("get interrupted manifests: %v", err)
+		return
+	}
+	for _, m := range orphans {
+		failManifest(ctx, m.DatasetID, false, "interrupted")
+		failManifest(ctx, m.DatasetID, true, "interrupted")
+	}
+}
+
+func StartHashDatasets() {
+	checkfs()
+	for {
+		releaseOrphanManifests(context.Background())
+		hashDatasets()
+		time.Sleep(hashDatasetsInterval)
+	}
+}
+
+func RegisterManifest(webdavGroup *gin.RouterGroup) {
+	webdavGroup.GET("/datasets/:id/manifest", GetDatasetManifest)
+	webdavGroup.POST("/datasets/:id/manifest", RebuildDatasetManifest)
+	webdavGroup.GET("/datasets/:id/manifest/verify", GetDatasetVerifyReport)
+	webdavGroup.POST("/datasets/:id/manifest/verify", VerifyDatasetManifest)
+}
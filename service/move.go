@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"webdav/dao/model"
+	"webdav/logutils"
+)
+
+// 重命名 src 为 dst，两者位于不同的挂载点时改为复制后删除源文件
+func renameOrCopy(ctx context.Context, src, dst string, progress *atomic.Int64) error {
+	err := fs.FileSystem.Rename(ctx, src, dst)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	logutils.Log.Infof("move %s to %s across devices, falling back to copy", src, dst)
+	return moveAcrossDevices(ctx, src, dst, progress)
+}
+
+type copiedDir struct {
+	name string
+	fi   os.FileInfo
+}
+
+// 跨设备移动：逐个复制文件并校验 SHA-256，全部成功后才删除源文件。
+// 复制或校验失败时删除已复制的部分，源文件保持不变
+func moveAcrossDevices(ctx context.Context, src, dst string, progress *atomic.Int64) error {
+	fi, err := fs.FileSystem.Stat(ctx, src)
+	if err != nil {
+		return err
+	}
+	if err = copyVerified(ctx, src, dst, fi, progress); err != nil {
+		if !errors.Is(err, os.ErrExist) {
+			removeCopied(dst)
+		}
+		return err
+	}
+	// 目标已经完整，删除源文件失败时只记录日志，不能再回滚
+	if err = fs.FileSystem.RemoveAll(context.WithoutCancel(ctx), src); err != nil {
+		logutils.Log.Errorf("remove %s after copying to %s: %v", src, dst, err)
+	}
+	return nil
+}
+
+func copyVerified(ctx context.Context, src, dst string, fi os.FileInfo, progress *atomic.Int64) error {
+	if _, err := os.Lstat(osPath(dst)); err == nil {
+		return fmt.Errorf("destination %s: %w", dst, os.ErrExist)
+	}
+	// 目录先以可写权限创建，内容复制完后再恢复原来的权限和修改时间
+	var dirs []copiedDir
+	err := walkFS(ctx, src, fi, func(p string, fi os.FileInfo) error {
+		target := path.Join(dst, strings.TrimPrefix(p, src))
+		switch mode := fi.Mode(); {
+		case mode.IsDir():
+			if err := fs.FileSystem.Mkdir(ctx, target, model.RWXFolderPerm); err != nil {
+				return err
+			}
+			dirs = append(dirs, copiedDir{name: target, fi: fi})
+			return nil
+		case mode&os.ModeSymlink != 0:
+			link, err := os.Readlink(osPath(p))
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, osPath(target))
+		case mode.IsRegular():
+			return copyFileVerified(ctx, p, target, fi, progress)
+		default:
+			return fmt.Errorf("can't move special file %s across devices", p)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	// 子目录的修改时间先于父目录设置，避免被后续的写入覆盖
+	for i := len(dirs) - 1; i >= 0; i-- {
+		name := osPath(dirs[i].name)
+		if err = os.Chmod(name, dirs[i].fi.Mode().Perm()); err != nil {
+			return err
+		}
+		if err = os.Chtimes(name, dirs[i].fi.ModTime(), dirs[i].fi.ModTime()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 复制文件后重新读取目标文件，与复制时计算的摘要比较
+func copyFileVerified(ctx context.Context, src, dst string, fi os.FileInfo, progress *atomic.Int64) error {
+	sum, err := copyFileProgress(ctx, src, dst, fi, progress)
+	if err != nil {
+		return err
+	}
+	// 创建文件时的权限受 umask 影响
+	if err = os.Chmod(osPath(dst), fi.Mode().Perm()); err != nil {
+		return err
+	}
+	copied, err := hashFile(ctx, dst)
+	if err != nil {
+		return err
+	}
+	if copied != sum {
+		return fmt.Errorf("checksum mismatch after copying %s", src)
+	}
+	return nil
+}
+
+// 删除复制了一半的目标，只读目录需要先恢复写权限
+func removeCopied(dst string) {
+	name := osPath(dst)
+	_ = filepath.WalkDir(name, func(p string, d iofs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			_ = os.Chmod(p, model.RWXFolderPerm)
+		}
+		return nil
+	})
+	if err := os.RemoveAll(name); err != nil {
+		logutils.Log.Errorf("remove partial copy %s: %v", dst, err)
+	}
+}
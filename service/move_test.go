@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// 创建用于移动的源目录树，包含只读目录和符号链接
+func newMoveFixture(t *testing.T) string {
+	t.Helper()
+	dir := useTempFS(t)
+	for name, content := range map[string]string{
+		"src/a.txt":      "aaaa",
+		"src/ro/b.txt":   "bb",
+		"src/sub/c.txt":  "c",
+		"src/sub/d/e.go": "package e",
+	} {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("a.txt", filepath.Join(dir, "src/link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(dir, "src/a.txt"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(dir, "src/ro"), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(dir, "src/ro"), 0o555); err != nil {
+		t.Fatal(err)
+	}
+	// 只读目录需要恢复写权限，临时目录才能被删除
+	t.Cleanup(func() {
+		removeCopied("/src")
+		removeCopied("/dst")
+	})
+	return dir
+}
+
+// 读取目录树中所有条目的类型、权限和内容
+func readTree(t *testing.T, root string) map[string]string {
+	t.Helper()
+	tree := make(map[string]string)
+	err := filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, p)
+		entry := fi.Mode().String()
+		switch {
+		case fi.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			entry += " -> " + link
+		case fi.Mode().IsRegular():
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			entry += " " + string(data)
+		case fi.IsDir() && rel != ".":
+			entry += " " + fi.ModTime().UTC().String()
+		}
+		tree[rel] = entry
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+func TestMoveAcrossDevices(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, dir string) context.Context
+		wantErr error // 为 nil 时只检查是否出错
+		fail    bool
+	}{
+		{"copy tree", func(t *testing.T, dir string) context.Context {
+			return context.Background()
+		}, nil, false},
+		{"dst exists", func(t *testing.T, dir string) context.Context {
+			if err := os.WriteFile(filepath.Join(dir, "dst"), []byte("keep"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			return context.Background()
+		}, os.ErrExist, true},
+		{"special file", func(t *testing.T, dir string) context.Context {
+			if err := syscall.Mkfifo(filepath.Join(dir, "src/sub/d/fifo"), 0o644); err != nil {
+				t.Skip(err)
+			}
+			return context.Background()
+		}, nil, true},
+		{"canceled", func(t *testing.T, dir string) context.Context {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			return ctx
+		}, context.Canceled, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := newMoveFixture(t)
+			ctx := tt.setup(t, dir)
+			src := readTree(t, filepath.Join(dir, "src"))
+			dst, _ := os.Lstat(filepath.Join(dir, "dst"))
+			var progress atomic.Int64
+			err := moveAcrossDevices(ctx, "/src", "/dst", &progress)
+			if !tt.fail {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := readTree(t, filepath.Join(dir, "dst")); !reflect.DeepEqual(got, src) {
+					t.Errorf("dst = %v, want %v", got, src)
+				}
+				if _, err = os.Lstat(filepath.Join(dir, "src")); !os.IsNotExist(err) {
+					t.Errorf("src still exists: %v", err)
+				}
+				if n := progress.Load(); n != 16 {
+					t.Errorf("progress = %d, want 16", n)
+				}
+				return
+			}
+			if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			// 失败时源文件不变，已存在的目标不被删除，复制了一半的目标被清理
+			if got := readTree(t, filepath.Join(dir, "src")); !reflect.DeepEqual(got, src) {
+				t.Errorf("src = %v, want %v", got, src)
+			}
+			after, _ := os.Lstat(filepath.Join(dir, "dst"))
+			switch {
+			case dst == nil && after != nil:
+				t.Errorf("partial copy left at dst")
+			case dst != nil && (after == nil || !os.SameFile(dst, after)):
+				t.Errorf("existing dst was replaced")
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// 先检查文件末尾记录的元数据长度，再交给 parquet-go 解析元数据并读取前几行
+const (
+	parquetMagic = "PAR1"
+	// 元数据来自文件本身，parquet-go 会一次读入，限制大小避免构造的文件耗尽内存
+	maxParquetMetadata = 16 << 20
+)
+
+var errBadParquet = errors.New("invalid parquet file")
+
+var parquetConvertedTypes = []string{
+	"UTF8", "MAP", "MAP_KEY_VALUE", "LIST", "ENUM", "DECIMAL", "DATE", "TIME_MILLIS", "TIME_MICROS",
+	"TIMESTAMP_MILLIS", "TIMESTAMP_MICROS", "UINT_8", "UINT_16", "UINT_32", "UINT_64",
+	"INT_8", "INT_16", "INT_32", "INT_64", "JSON", "BSON", "INTERVAL",
+}
+
+type ParquetColumn struct {
+	Path          string `json:"path"` // 以 . 连接的字段路径
+	Type          string `json:"type"` // 物理类型，嵌套字段为 GROUP
+	Repetition    string `json:"repetition,omitempty"`
+	ConvertedType string `json:"convertedType,omitempty"`
+	LogicalType   string `json:"logicalType,omitempty"`
+}
+
+type ParquetInfo struct {
+	Version   int32           `json:"version"`
+	NumRows   int64           `json:"numRows"`
+	RowGroups int             `json:"rowGroups"`
+	CreatedBy string          `json:"createdBy,omitempty"`
+	Columns   []ParquetColumn `json:"columns"`
+	Rows      []any           `json:"rows"` // 前几行，每行是字段名到值的对象
+}
+
+// 读取文件的元数据和前 n 行
+func readParquet(f io.ReadSeeker, size int64, n int) (*ParquetInfo, bool, error) {
+	if err := checkParquetFooter(f, size); err != nil {
+		return nil, false, err
+	}
+	pf, err := openParquet(readerAtOf(f), size)
+	if err != nil {
+		return nil, false, err
+	}
+	meta := pf.Metadata()
+	info := &ParquetInfo{
+		Version:   meta.Version,
+		NumRows:   meta.NumRows,
+		RowGroups: len(meta.RowGroups),
+		CreatedBy: meta.CreatedBy,
+		Columns:   parquetColumns(nil, pf.Schema().Fields(), nil),
+	}
+	var truncated bool
+	info.Rows, truncated, err = readParquetRows(pf, n)
+	if err != nil {
+		return nil, false, err
+	}
+	return info, truncated, nil
+}
+
+// 只检查文件末尾的长度和魔数，parquet-go 打开文件时会按这个长度读入全部元数据
+func checkParquetFooter(f io.ReadSeeker, size int64) error {
+	if size < 12 {
+		return errBadParquet
+	}
+	tail := make([]byte, 8)
+	if _, err := f.Seek(size-8, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(f, tail); err != nil {
+		return err
+	}
+	if string(tail[4:]) != parquetMagic {
+		return errBadParquet
+	}
+	n := int64(binary.LittleEndian.Uint32(tail))
+	if n > maxParquetMetadata {
+		return fmt.Errorf("parquet metadata is larger than %d bytes", maxParquetMetadata)
+	}
+	if n > size-12 {
+		return errBadParquet
+	}
+	return nil
+}
+
+func openParquet(r io.ReaderAt, size int64) (pf *parquet.File, err error) {
+	defer func() {
+		// 元数据不合法时解析库可能 panic
+		if p := recover(); p != nil {
+			pf, err = nil, fmt.Errorf("%w: %v", errBadParquet, p)
+		}
+	}()
+	pf, err = parquet.OpenFile(r, size, parquet.SkipPageIndex(true), parquet.SkipBloomFilters(true))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errBadParquet, err)
+	}
+	return pf, nil
+}
+
+// 按深度优先顺序列出所有字段
+func parquetColumns(prefix []string, fields []parquet.Field, cols []ParquetColumn) []ParquetColumn {
+	for _, field := range fields {
+		p := append(prefix[:len(prefix):len(prefix)], field.Name())
+		typ := field.Type()
+		col := ParquetColumn{Path: strings.Join(p, "."), Type: "GROUP", Repetition: "REQUIRED"}
+		if t := typ.PhysicalType(); t != nil && field.Leaf() {
+			col.Type = t.String()
+		}
+		switch {
+		case field.Optional():
+			col.Repetition = "OPTIONAL"
+		case field.Repeated():
+			col.Repetition = "REPEATED"
+		}
+		if ct := typ.ConvertedType(); ct != nil {
+			col.ConvertedType = enumName(parquetConvertedTypes, int32(*ct))
+		}
+		if lt := typ.LogicalType(); lt != nil {
+			col.LogicalType = lt.String()
+		}
+		cols = append(cols, col)
+		cols = parquetColumns(p, field.Fields(), cols)
+	}
+	return cols
+}
+
+// 读取前 n 行，嵌套的字段还原为对象，过长的字符串被截断
+func readParquetRows(pf *parquet.File, n int) (rows []any, truncated bool, err error) {
+	defer func() {
+		// 数据页损坏时解码库可能 panic
+		if p := recover(); p != nil {
+			rows, truncated, err = nil, false, fmt.Errorf("%w: %v", errBadParquet, p)
+		}
+	}()
+	schema := pf.Schema()
+	buf := make([]parquet.Row, min(n, 64))
+	rows = []any{}
+	for _, rg := range pf.RowGroups() {
+		if len(rows) >= n {
+			break
+		}
+		if err = readRowGroup(schema, rg, buf, n, &rows, &truncated); err != nil {
+			return nil, false, err
+		}
+	}
+	return rows, truncated || pf.NumRows() > int64(len(rows)), nil
+}
+
+func readRowGroup(schema *parquet.Schema, rg parquet.RowGroup, buf []parquet.Row, n int, rows *[]any, truncated *bool) error {
+	rr := rg.Rows()
+	defer rr.Close()
+	for len(*rows) < n {
+		k, err := rr.ReadRows(buf[:min(len(buf), n-len(*rows))])
+		for _, row := range buf[:k] {
+			var v any
+			// ReadRows 会复用值的缓冲区
+			if rerr := schema.Reconstruct(&v, row.Clone()); rerr != nil {
+				return rerr
+			}
+			*rows = append(*rows, truncateValue(v, truncated))
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func truncateValue(v any, truncated *bool) any {
+	switch v := v.(type) {
+	case string:
+		if len(v) > maxPreviewLine {
+			*truncated = true
+			return strings.ToValidUTF8(v[:maxPreviewLine], "") + "…"
+		}
+	case []byte:
+		if len(v) > maxPreviewLine {
+			*truncated = true
+			return v[:maxPreviewLine]
+		}
+	case map[string]any:
+		for k, e := range v {
+			v[k] = truncateValue(e, truncated)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = truncateValue(e, truncated)
+		}
+	}
+	return v
+}
+
+// webdav.File 不一定实现 io.ReaderAt，用 Seek 和 Read 代替
+type seekReaderAt struct {
+	mu sync.Mutex
+	f  io.ReadSeeker
+}
+
+func readerAtOf(f io.ReadSeeker) io.ReaderAt {
+	if r, ok := f.(io.ReaderAt); ok {
+		return r
+	}
+	return &seekReaderAt{f: f}
+}
+
+func (r *seekReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.f.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(r.f, p)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func enumName(names []string, v int32) string {
+	if v >= 0 && int(v) < len(names) {
+		return names[v]
+	}
+	return fmt.Sprintf("UNKNOWN(%d)", v)
+}
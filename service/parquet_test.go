@@ -0,0 +1,130 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
+)
+
+type parquetFixture struct {
+	ID    int64    `parquet:"id"`
+	Name  string   `parquet:"name"`
+	Score *float64 `parquet:"score,optional"`
+	Tags  []string `parquet:"tags,list"`
+}
+
+func writeParquetFixture(t *testing.T, n int, codec compress.Codec) []byte {
+	t.Helper()
+	rows := make([]parquetFixture, n)
+	for i := range rows {
+		rows[i] = parquetFixture{ID: int64(i), Name: "row" + strings.Repeat("x", i%3), Tags: []string{"a", "b"}[:i%3%2+1]}
+		if i%2 == 0 {
+			score := float64(i) / 2
+			rows[i].Score = &score
+		}
+	}
+	var b bytes.Buffer
+	if err := parquet.Write(&b, rows, parquet.Compression(codec), parquet.MaxRowsPerRowGroup(4)); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestReadParquet(t *testing.T) {
+	tests := []struct {
+		name      string
+		codec     compress.Codec
+		rows      int
+		limit     int
+		wantRows  int
+		truncated bool
+	}{
+		{"snappy", &parquet.Snappy, 10, 3, 3, true},
+		{"zstd", &parquet.Zstd, 10, 100, 10, false},
+		{"gzip", &parquet.Gzip, 5, 5, 5, false},
+		{"uncompressed", &parquet.Uncompressed, 9, 6, 6, true},
+		{"empty", &parquet.Snappy, 0, 10, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := writeParquetFixture(t, tt.rows, tt.codec)
+			info, truncated, err := readParquet(bytes.NewReader(data), int64(len(data)), tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.NumRows != int64(tt.rows) || info.RowGroups != (tt.rows+3)/4 {
+				t.Errorf("numRows=%d rowGroups=%d", info.NumRows, info.RowGroups)
+			}
+			var paths []string
+			for _, col := range info.Columns {
+				paths = append(paths, col.Path+":"+col.Type)
+			}
+			want := "id:INT64,name:BYTE_ARRAY,score:DOUBLE,tags:GROUP,tags.list:GROUP,tags.list.element:BYTE_ARRAY"
+			if got := strings.Join(paths, ","); got != want {
+				t.Errorf("columns = %s, want %s", got, want)
+			}
+			rows := info.Rows
+			if len(rows) != tt.wantRows || truncated != tt.truncated {
+				t.Fatalf("got %d rows, truncated=%v", len(rows), truncated)
+			}
+			for i, row := range rows {
+				m, ok := row.(map[string]any)
+				if !ok {
+					t.Fatalf("row %d is %T", i, row)
+				}
+				if m["id"] != int64(i) || m["name"] != "row"+strings.Repeat("x", i%3) {
+					t.Errorf("row %d = %v", i, m)
+				}
+				if (m["score"] == nil) != (i%2 == 1) {
+					t.Errorf("row %d score = %v", i, m["score"])
+				}
+			}
+		})
+	}
+}
+
+func withFooter(meta []byte) []byte {
+	data := append([]byte(parquetMagic), meta...)
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(meta)))
+	return append(data, parquetMagic...)
+}
+
+func TestReadParquetMalformed(t *testing.T) {
+	valid := writeParquetFixture(t, 3, &parquet.Snappy)
+	metaLen := int(binary.LittleEndian.Uint32(valid[len(valid)-8:]))
+	meta := valid[len(valid)-8-metaLen : len(valid)-8]
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"too small", []byte("PAR1PAR1")},
+		{"bad magic", append(append([]byte{}, valid[:len(valid)-4]...), "PAR2"...)},
+		{"length beyond file", append(append([]byte{}, valid[:len(valid)-8]...), 0xff, 0xff, 0, 0, 'P', 'A', 'R', '1')},
+		{"truncated metadata", withFooter(meta[:len(meta)/2])},
+		{"garbage metadata", withFooter(bytes.Repeat([]byte{0xff}, 64))},
+		{"no schema", withFooter([]byte{0})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := readParquet(bytes.NewReader(tt.data), int64(len(tt.data)), 1)
+			if !errors.Is(err, errBadParquet) {
+				t.Errorf("err = %v, want errBadParquet", err)
+			}
+		})
+	}
+}
+
+func TestReadParquetMetadataTooLarge(t *testing.T) {
+	data := make([]byte, 64)
+	binary.LittleEndian.PutUint32(data[len(data)-8:], maxParquetMetadata+1)
+	copy(data[len(data)-4:], parquetMagic)
+	_, _, err := readParquet(bytes.NewReader(data), int64(len(data)), 1)
+	if err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,531 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"webdav/dao/model"
+	"webdav/response"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/webdav"
+)
+
+const (
+	PreviewText    = "text"
+	PreviewCSV     = "csv"
+	PreviewTSV     = "tsv"
+	PreviewJSONL   = "jsonl"
+	PreviewJSON    = "json"
+	PreviewParquet = "parquet"
+	PreviewImage   = "image"
+)
+
+const (
+	defaultPreviewLines = 100
+	maxPreviewLines     = 1000
+	// 文本类预览最多读取的字节数，JSON 格式化后的输出也不超过这个大小
+	maxPreviewBytes = 1 << 20
+	maxPreviewLine  = 64 << 10
+	// JSON 格式化时最多读取的字节数
+	maxJSONScanBytes = 16 << 20
+	defaultThumbSize = 256
+	maxThumbSize     = 1024
+	maxImageBytes    = 64 << 20
+	// 解码后每个像素最多占 8 字节，单张图片不超过约 128MB
+	maxImagePixels = 16 << 20
+	// 同时解码的图片数
+	maxImageDecodes = 2
+	// 缩小图片时每个像素在每个方向上最多取的样本数
+	maxThumbSamples = 4
+)
+
+var previewTypes = map[string]string{
+	".txt":     PreviewText,
+	".log":     PreviewText,
+	".md":      PreviewText,
+	".csv":     PreviewCSV,
+	".tsv":     PreviewTSV,
+	".jsonl":   PreviewJSONL,
+	".ndjson":  PreviewJSONL,
+	".json":    PreviewJSON,
+	".parquet": PreviewParquet,
+	".png":     PreviewImage,
+	".jpg":     PreviewImage,
+	".jpeg":    PreviewImage,
+}
+
+var imageDecodeSlots = make(chan struct{}, maxImageDecodes)
+
+var errNotPreviewable = errors.New("this file type can't be previewed")
+
+type PreviewReq struct {
+	Type  string `form:"type"`  // 强制使用的预览类型，默认按扩展名和内容判断
+	Lines int    `form:"lines"` // 文本、CSV、JSONL、Parquet 返回的行数，默认 100
+	Size  int    `form:"size"`  // 缩略图的最大边长，默认 256
+}
+
+type PreviewResp struct {
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	Size      int64  `json:"size"`
+	Truncated bool   `json:"truncated"` // 只返回了文件的一部分
+
+	Lines   []string      `json:"lines,omitempty"`   // text、jsonl
+	Columns []string      `json:"columns,omitempty"` // csv、tsv 的第一行
+	Rows    [][]string    `json:"rows,omitempty"`    // csv、tsv
+	Content string        `json:"content,omitempty"` // 格式化后的 json
+	Parquet *ParquetInfo  `json:"parquet,omitempty"`
+	Image   *ImagePreview `json:"image,omitempty"`
+}
+
+type ImagePreview struct {
+	Format string `json:"format"` // png 或 jpeg
+	Width  int    `json:"width"`  // 原图尺寸
+	Height int    `json:"height"`
+	Data   string `json:"data"` // base64 编码的缩略图，格式与原图相同
+}
+
+// 按扩展名判断预览类型，未知的扩展名读取开头的内容判断是否为文本或图片
+func detectPreviewType(name string, f io.ReadSeeker) (string, error) {
+	if t, ok := previewTypes[strings.ToLower(path.Ext(name))]; ok {
+		return t, nil
+	}
+	buf := make([]byte, mimeSniffLen)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	switch t := http.DetectContentType(buf[:n]); {
+	case t == "image/png" || t == "image/jpeg":
+		return PreviewImage, nil
+	case strings.HasPrefix(t, "text/"):
+		return PreviewText, nil
+	}
+	return "", errNotPreviewable
+}
+
+// 限制读取的字节数，记录是否因达到上限而停止
+type cappedReader struct {
+	r   io.Reader
+	n   int64
+	hit bool
+}
+
+func (r *cappedReader) Read(p []byte) (int, error) {
+	if r.n <= 0 {
+		r.hit = true
+		return 0, io.EOF
+	}
+	if int64(len(p)) > r.n {
+		p = p[:r.n]
+	}
+	n, err := r.r.Read(p)
+	r.n -= int64(n)
+	return n, err
+}
+
+// 读取前 n 行，过长的行被截断
+func previewLines(f io.Reader, n int, resp *PreviewResp) error {
+	cr := &cappedReader{r: f, n: maxPreviewBytes}
+	br := bufio.NewReaderSize(cr, maxPreviewLine)
+	resp.Lines = []string{}
+	for len(resp.Lines) < n {
+		line, err := br.ReadSlice('\n')
+		text := strings.ToValidUTF8(strings.TrimRight(string(line), "\r\n"), "\uFFFD")
+		// 跳过过长的行的剩余部分
+		for errors.Is(err, bufio.ErrBufferFull) {
+			resp.Truncated = true
+			_, err = br.ReadSlice('\n')
+		}
+		if errors.Is(err, io.EOF) {
+			// 达到读取上限时最后一行可能不完整，丢弃
+			if cr.hit {
+				resp.Truncated = true
+			} else if len(line) > 0 {
+				resp.Lines = append(resp.Lines, text)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		resp.Lines = append(resp.Lines, text)
+	}
+	_, err := br.Peek(1)
+	resp.Truncated = resp.Truncated || err == nil
+	return nil
+}
+
+// 读取第一行作为列名和其后的 n 行
+func previewCSV(f io.Reader, comma rune, n int, resp *PreviewResp) error {
+	cr := &cappedReader{r: f, n: maxPreviewBytes}
+	r := csv.NewReader(cr)
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	resp.Rows = [][]string{}
+	for len(resp.Rows) < n {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			resp.Truncated = cr.hit
+			return nil
+		}
+		// 达到读取上限时最后一行可能不完整，丢弃
+		if cr.hit && r.InputOffset() >= maxPreviewBytes {
+			resp.Truncated = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for i := range record {
+			record[i] = strings.ToValidUTF8(record[i], "\uFFFD")
+		}
+		if resp.Columns == nil {
+			resp.Columns = record
+			continue
+		}
+		resp.Rows = append(resp.Rows, record)
+	}
+	_, err := r.Read()
+	resp.Truncated = resp.Truncated || err == nil
+	return nil
+}
+
+type jsonFrame struct {
+	delim byte
+	n     int
+	key   bool // 对象中下一个 token 是键
+}
+
+// 逐个 token 输出缩进后的 JSON，超出大小时在完整的值之后停止并补全括号，结果仍是合法的 JSON
+func previewJSON(f io.Reader, resp *PreviewResp) error {
+	cr := &cappedReader{r: f, n: maxJSONScanBytes}
+	dec := json.NewDecoder(cr)
+	dec.UseNumber()
+	var (
+		b     bytes.Buffer
+		stack []*jsonFrame
+		// 最后一个键之前的输出长度，截断时去掉没有值的键
+		keyStart int
+	)
+	newline := func() {
+		b.WriteByte('\n')
+		b.WriteString(strings.Repeat("  ", len(stack)))
+	}
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			// 读取上限截断了 JSON
+			if cr.hit && len(stack) > 0 {
+				if top := stack[len(stack)-1]; top.delim == '{' && !top.key {
+					b.Truncate(keyStart)
+					top.n--
+					top.key = true
+				}
+				resp.Truncated = true
+				break
+			}
+			return err
+		}
+		var top *jsonFrame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		if d, ok := tok.(json.Delim); ok && (d == '}' || d == ']') {
+			stack = stack[:len(stack)-1]
+			if top.n > 0 {
+				newline()
+			}
+			b.WriteByte(byte(d))
+		} else {
+			isKey := top != nil && top.delim == '{' && top.key
+			if isKey {
+				keyStart = b.Len()
+			}
+			switch {
+			case top == nil:
+			case top.delim == '{' && !top.key:
+				b.WriteString(": ")
+			default:
+				if top.n > 0 {
+					b.WriteByte(',')
+				}
+				newline()
+				top.n++
+			}
+			if top != nil && top.delim == '{' {
+				top.key = !top.key
+			}
+			if d, ok := tok.(json.Delim); ok {
+				b.WriteByte(byte(d))
+				stack = append(stack, &jsonFrame{delim: byte(d), key: d == '{'})
+				continue
+			}
+			// 过长的字符串只保留开头
+			if str, ok := tok.(string); ok && !isKey && len(str) > maxPreviewLine {
+				tok = strings.ToValidUTF8(str[:maxPreviewLine], "") + "…"
+				resp.Truncated = true
+			}
+			data, _ := json.Marshal(tok)
+			b.Write(data)
+			if isKey {
+				continue
+			}
+		}
+		if len(stack) == 0 {
+			break
+		}
+		if b.Len() >= maxPreviewBytes {
+			resp.Truncated = true
+			break
+		}
+	}
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if top.n > 0 {
+			newline()
+		}
+		if top.delim == '{' {
+			b.WriteByte('}')
+		} else {
+			b.WriteByte(']')
+		}
+	}
+	resp.Content = b.String()
+	return nil
+}
+
+func previewImage(ctx context.Context, f io.ReadSeeker, size int64, maxSide int, resp *PreviewResp) error {
+	if size > maxImageBytes {
+		return errors.New("image is too large to preview")
+	}
+	cfg, format, err := image.DecodeConfig(f)
+	if err != nil {
+		return err
+	}
+	if format != "png" && format != "jpeg" {
+		return errNotPreviewable
+	}
+	// 解码前检查尺寸，避免很小的文件解码出巨大的图片
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return errors.New("image is too large to preview")
+	}
+	select {
+	case imageDecodeSlots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-imageDecodeSlots }()
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return err
+	}
+	thumb := thumbnail(img, maxSide)
+	var b bytes.Buffer
+	if format == "png" {
+		err = png.Encode(&b, thumb)
+	} else {
+		err = jpeg.Encode(&b, thumb, &jpeg.Options{Quality: 80})
+	}
+	if err != nil {
+		return err
+	}
+	resp.Image = &ImagePreview{
+		Format: format,
+		Width:  cfg.Width,
+		Height: cfg.Height,
+		Data:   base64.StdEncoding.EncodeToString(b.Bytes()),
+	}
+	return nil
+}
+
+// 等比缩小到最长边不超过 maxSide，每个像素取所覆盖区域内若干样本的平均值
+func thumbnail(src image.Image, maxSide int) image.Image {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= maxSide && h <= maxSide {
+		return src
+	}
+	tw, th := maxSide, h*maxSide/w
+	if h > w {
+		tw, th = w*maxSide/h, maxSide
+	}
+	tw, th = max(tw, 1), max(th, 1)
+	samples := min((w+tw-1)/tw, maxThumbSamples)
+	dst := image.NewNRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		for x := 0; x < tw; x++ {
+			var r, g, b, a uint32
+			for sy := 0; sy < samples; sy++ {
+				for sx := 0; sx < samples; sx++ {
+					px := bounds.Min.X + (x*samples+sx)*w/(tw*samples)
+					py := bounds.Min.Y + (y*samples+sy)*h/(th*samples)
+					pr, pg, pb, pa := src.At(px, py).RGBA()
+					r, g, b, a = r+pr, g+pg, b+pb, a+pa
+				}
+			}
+			n := uint32(samples * samples)
+			// RGBA 返回预乘 alpha 的 16 位分量
+			dst.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+	return dst
+}
+
+// 检查参数并生成预览，出错时已写入响应
+func respondPreview(c *gin.Context, realPath string) {
+	var req PreviewReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequestError(c, err.Error())
+		return
+	}
+	if req.Type != "" && !containsString([]string{PreviewText, PreviewCSV, PreviewTSV, PreviewJSONL, PreviewJSON, PreviewParquet, PreviewImage}, req.Type) {
+		response.BadRequestError(c, "type must be text, csv, tsv, jsonl, json, parquet or image")
+		return
+	}
+	if req.Lines <= 0 || req.Lines > maxPreviewLines {
+		req.Lines = defaultPreviewLines
+	}
+	if req.Size <= 0 || req.Size > maxThumbSize {
+		req.Size = defaultThumbSize
+	}
+	ctx := c.Request.Context()
+	f, err := fs.FileSystem.OpenFile(ctx, realPath, os.O_RDONLY, 0)
+	if os.IsNotExist(err) {
+		response.HTTPError(c, http.StatusNotFound, "can't find file", response.NotSpecified)
+		return
+	}
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	defer f.Close()
+	resp, err := preview(ctx, f, &req)
+	if errors.Is(err, errNotPreviewable) {
+		response.HTTPError(c, http.StatusUnsupportedMediaType, err.Error(), response.NotSpecified)
+		return
+	}
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	response.Success(c, resp)
+}
+
+func preview(ctx context.Context, f webdav.File, req *PreviewReq) (*PreviewResp, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !fi.Mode().IsRegular() {
+		return nil, errors.New("can't preview a directory or special file")
+	}
+	resp := &PreviewResp{Type: req.Type, Name: fi.Name(), Size: fi.Size()}
+	if resp.Type == "" {
+		if resp.Type, err = detectPreviewType(fi.Name(), f); err != nil {
+			return nil, err
+		}
+	}
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	switch resp.Type {
+	case PreviewText, PreviewJSONL:
+		err = previewLines(f, req.Lines, resp)
+	case PreviewCSV:
+		err = previewCSV(f, ',', req.Lines, resp)
+	case PreviewTSV:
+		err = previewCSV(f, '\t', req.Lines, resp)
+	case PreviewJSON:
+		err = previewJSON(f, resp)
+	case PreviewParquet:
+		resp.Parquet, resp.Truncated, err = readParquet(f, fi.Size(), req.Lines)
+	case PreviewImage:
+		err = previewImage(ctx, f, fi.Size(), req.Size, resp)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// 预览文件开头的内容，不需要下载整个文件
+func PreviewFile(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	param := strings.Trim(path.Clean(strings.TrimPrefix(c.Request.URL.Path, "/api/ss/preview")), "/")
+	if param == "" || param == "." {
+		response.BadRequestError(c, "path is required")
+		return
+	}
+	if !GetPermission(param, jwttoken, c).CanRead() {
+		permissionDenied(c, param, jwttoken, "Your permission is notAllowed", response.NotSpecified)
+		return
+	}
+	realPath, err := Redirect(c, param, jwttoken)
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	respondPreview(c, realPath)
+}
+
+// 预览数据集中的文件，路径与 /dataset/:id/*path 列出的 path 相同，第一级为数据集目录本身
+func PreviewDatasetFile(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	var datasetReq DatasetRequest
+	if err = c.ShouldBindUri(&datasetReq); err != nil {
+		response.HTTPError(c, http.StatusBadRequest, err.Error(), response.NotSpecified)
+		return
+	}
+	if GetDatasetPermission(c, datasetReq.ID, jwttoken) == model.NotAllowed {
+		response.Error(c, "This dataset does not exist or you do not have permission", response.NotSpecified)
+		return
+	}
+	URL, err := datasetRoot(c, datasetReq.ID)
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	param := path.Clean(c.Param("path"))
+	token := getFirstToken(param)
+	if token == "" {
+		response.BadRequestError(c, "path is required")
+		return
+	}
+	respondPreview(c, URL+"/"+strings.TrimPrefix(param, "/"+token))
+}
+
+func RegisterPreview(webdavGroup *gin.RouterGroup) {
+	webdavGroup.GET("/preview/*path", PreviewFile)
+	webdavGroup.GET("/datasets/:id/preview/*path", PreviewDatasetFile)
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func TestPreviewJSON(t *testing.T) {
+	var big strings.Builder
+	big.WriteString(`{"list":[`)
+	for i := 0; big.Len() < 2*maxPreviewBytes; i++ {
+		fmt.Fprintf(&big, `{"id":%d,"name":"item%d"},`, i, i)
+	}
+	big.WriteString(`{"id":-1}]}`)
+	tests := []struct {
+		name      string
+		input     string
+		want      string // 为空时只检查结果是合法的 JSON
+		truncated bool
+		wantErr   bool
+	}{
+		{"scalar", `42`, "42", false, false},
+		{"nested", `{"a":[1,{"b":null}],"c":"d","e":[],"f":{}}`,
+			"{\n  \"a\": [\n    1,\n    {\n      \"b\": null\n    }\n  ],\n  \"c\": \"d\",\n  \"e\": [],\n  \"f\": {}\n}", false, false},
+		{"numbers kept", `[1.50, 1e100, 12345678901234567890]`, "[\n  1.50,\n  1e100,\n  12345678901234567890\n]", false, false},
+		{"output cap", big.String(), "", true, false},
+		{"scan cap in value", `{"a":[1,2],"b":"` + strings.Repeat("q", maxJSONScanBytes) + `"}`, "{\n  \"a\": [\n    1,\n    2\n  ]\n}", true, false},
+		{"long string", `["` + strings.Repeat("s", maxPreviewLine+10) + `"]`, "", true, false},
+		{"invalid", `{"a":}`, "", false, true},
+		{"empty", ``, "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp PreviewResp
+			err := previewJSON(strings.NewReader(tt.input), &resp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v", err)
+			}
+			if err != nil {
+				return
+			}
+			if resp.Truncated != tt.truncated {
+				t.Errorf("truncated = %v", resp.Truncated)
+			}
+			if tt.want != "" && resp.Content != tt.want {
+				t.Errorf("content = %q, want %q", resp.Content, tt.want)
+			}
+			if !json.Valid([]byte(resp.Content)) {
+				t.Errorf("content is not valid JSON: %.200q", resp.Content)
+			}
+			if len(resp.Content) > maxPreviewBytes+maxPreviewLine {
+				t.Errorf("content has %d bytes", len(resp.Content))
+			}
+		})
+	}
+}
+
+func TestPreviewCSV(t *testing.T) {
+	long := "h1,h2\n" + strings.Repeat("1234567,abcdefg\n", maxPreviewBytes/16+10)
+	tests := []struct {
+		name      string
+		input     string
+		comma     rune
+		n         int
+		columns   []string
+		rows      [][]string
+		truncated bool
+	}{
+		{"basic", "a,b\n1,\"x,y\"\n2,z\n", ',', 10, []string{"a", "b"}, [][]string{{"1", "x,y"}, {"2", "z"}}, false},
+		{"limit", "a,b\n1,2\n3,4\n5,6\n", ',', 2, []string{"a", "b"}, [][]string{{"1", "2"}, {"3", "4"}}, true},
+		{"exact limit", "a,b\n1,2\n3,4\n", ',', 2, []string{"a", "b"}, [][]string{{"1", "2"}, {"3", "4"}}, false},
+		{"tsv ragged", "a\tb\tc\n1\t2\n", '\t', 10, []string{"a", "b", "c"}, [][]string{{"1", "2"}}, false},
+		{"lazy quotes", "a\nsay \"hi\"\n", ',', 10, []string{"a"}, [][]string{{`say "hi"`}}, false},
+		{"header only", "a,b\n", ',', 10, []string{"a", "b"}, [][]string{}, false},
+		{"invalid utf8", "a\n\xff\n", ',', 10, []string{"a"}, [][]string{{"�"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp PreviewResp
+			if err := previewCSV(strings.NewReader(tt.input), tt.comma, tt.n, &resp); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(resp.Columns, tt.columns) || !reflect.DeepEqual(resp.Rows, tt.rows) || resp.Truncated != tt.truncated {
+				t.Errorf("got columns=%q rows=%q truncated=%v", resp.Columns, resp.Rows, resp.Truncated)
+			}
+		})
+	}
+	t.Run("read cap", func(t *testing.T) {
+		var resp PreviewResp
+		if err := previewCSV(strings.NewReader(long), ',', maxPreviewLines*1000, &resp); err != nil {
+			t.Fatal(err)
+		}
+		if !resp.Truncated {
+			t.Error("not truncated")
+		}
+		// 读取上限处被截断的行不能返回
+		for _, row := range resp.Rows {
+			if !reflect.DeepEqual(row, []string{"1234567", "abcdefg"}) {
+				t.Fatalf("partial row %q", row)
+			}
+		}
+	})
+}
+
+func TestPreviewLines(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		n         int
+		lines     []string
+		truncated bool
+	}{
+		{"crlf", "a\r\nb\r\n", 10, []string{"a", "b"}, false},
+		{"no trailing newline", "a\nb", 10, []string{"a", "b"}, false},
+		{"limit", "a\nb\nc\n", 2, []string{"a", "b"}, true},
+		{"empty lines", "\n\nx\n", 10, []string{"", "", "x"}, false},
+		{"long line", strings.Repeat("x", maxPreviewLine+5) + "\nnext\n", 10, []string{strings.Repeat("x", maxPreviewLine), "next"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp PreviewResp
+			if err := previewLines(strings.NewReader(tt.input), tt.n, &resp); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(resp.Lines, tt.lines) || resp.Truncated != tt.truncated {
+				t.Errorf("got %d lines, truncated=%v", len(resp.Lines), resp.Truncated)
+			}
+		})
+	}
+}
+
+func TestPreviewDetect(t *testing.T) {
+	dir := useTempFS(t)
+	pq := writeParquetFixture(t, 3, &parquet.Snappy)
+	files := map[string][]byte{
+		"a.CSV":     []byte("x\n1\n"),
+		"notes":     []byte("hello\n"),
+		"blob":      {0, 1, 2, 3},
+		"d.parquet": pq,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(dir+"/"+name, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name    string
+		typ     string
+		want    string
+		wantErr bool
+	}{
+		{"a.CSV", "", PreviewCSV, false},
+		{"notes", "", PreviewText, false},
+		{"blob", "", "", true},
+		{"blob", PreviewText, PreviewText, false},
+		{"d.parquet", "", PreviewParquet, false},
+	}
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name+tt.typ, func(t *testing.T) {
+			f, err := fs.FileSystem.OpenFile(ctx, "/"+tt.name, os.O_RDONLY, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			resp, err := preview(ctx, f, &PreviewReq{Type: tt.typ, Lines: 2})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Type != tt.want {
+				t.Errorf("type = %s", resp.Type)
+			}
+			if tt.want == PreviewParquet && (len(resp.Parquet.Rows) != 2 || !resp.Truncated) {
+				t.Errorf("parquet rows = %v, truncated=%v", resp.Parquet.Rows, resp.Truncated)
+			}
+		})
+	}
+}
@@ -0,0 +1,392 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+	"webdav/config"
+	"webdav/dao/model"
+	"webdav/dao/query"
+	"webdav/logutils"
+	"webdav/response"
+	"webdav/util"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const defaultUsageScanMinutes = 30
+
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+// 空间还没有统计过用量，此时不限制写入
+var errUsageUnknown = errors.New("space usage has not been scanned yet")
+
+// 空间用量缓存，键为空间根目录的实际路径
+var (
+	usageMu    sync.Mutex
+	usageCache = make(map[string]*model.SpaceUsage)
+	// 正在后台首次统计的空间
+	usageSeeding sync.Map
+)
+
+func cleanRealPath(p string) string {
+	return path.Clean("/" + p)
+}
+
+// 实际路径所在空间的根目录，不属于任何用户、账户或公共空间时返回空字符串
+func spaceRootOf(realPath string) string {
+	p := cleanRealPath(realPath)
+	for _, prefix := range []string{config.GetConfig().UserSpacePrefix, config.GetConfig().AccountSpacePrefix} {
+		prefix = cleanRealPath(prefix)
+		if rest, ok := strings.CutPrefix(p, prefix+"/"); ok {
+			return prefix + "/" + strings.SplitN(rest, "/", 2)[0]
+		}
+	}
+	public := cleanRealPath(config.GetConfig().PublicSpacePrefix)
+	if p == public || strings.HasPrefix(p, public+"/") {
+		return public
+	}
+	return ""
+}
+
+// 空间的存储配额，小于 0 表示不限制
+func spaceQuota(ctx context.Context, root string) int64 {
+	if root == cleanRealPath(config.GetConfig().PublicSpacePrefix) {
+		a := query.Account
+		account, err := a.WithContext(ctx).Where(a.ID.Eq(model.DefaultAccountID)).First()
+		if err != nil {
+			return model.StorageQuotaInfinity
+		}
+		return account.StorageQuota
+	}
+	dir, space := path.Split(root)
+	if cleanRealPath(dir) == cleanRealPath(config.GetConfig().UserSpacePrefix) {
+		u := query.User
+		user, err := u.WithContext(ctx).Where(u.Space.In(space, "/"+space)).First()
+		if err != nil {
+			return model.StorageQuotaInfinity
+		}
+		return user.StorageQuota
+	}
+	a := query.Account
+	account, err := a.WithContext(ctx).Where(a.Space.In(space, "/"+space)).First()
+	if err != nil {
+		return model.StorageQuotaInfinity
+	}
+	return account.StorageQuota
+}
+
+// 统计文件树的字节数和文件数，目录不计入文件数
+func treeUsage(ctx context.Context, name string) (bytes, files int64, err error) {
+	fi, err := fs.FileSystem.Stat(ctx, name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, 0, nil
+		}
+		return 0, 0, err
+	}
+	err = walkFS(ctx, name, fi, func(_ string, fi os.FileInfo) error {
+		if !fi.IsDir() {
+			bytes += fi.Size()
+			files++
+		}
+		return nil
+	})
+	return bytes, files, err
+}
+
+// 空间当前用量。缓存和数据库中都没有时返回 errUsageUnknown，并在后台统计，
+// 不在请求中遍历整个空间
+func spaceUsageOf(ctx context.Context, root string) (model.SpaceUsage, error) {
+	usageMu.Lock()
+	if usage, ok := usageCache[root]; ok {
+		usageMu.Unlock()
+		return *usage, nil
+	}
+	usageMu.Unlock()
+	su := query.SpaceUsage
+	usage, err := su.WithContext(ctx).Where(su.Space.Eq(root)).First()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		seedSpaceUsage(root)
+		return model.SpaceUsage{Space: root}, errUsageUnknown
+	}
+	if err != nil {
+		return model.SpaceUsage{}, err
+	}
+	usageMu.Lock()
+	usageCache[root] = usage
+	usageMu.Unlock()
+	return *usage, nil
+}
+
+// 在后台统计还没有用量记录的空间，同一个空间同时只统计一次
+func seedSpaceUsage(root string) {
+	if _, running := usageSeeding.LoadOrStore(root, true); running {
+		return
+	}
+	go func() {
+		defer usageSeeding.Delete(root)
+		if _, err := refreshSpaceUsage(context.Background(), root); err != nil {
+			logutils.Log.Warnf("scan usage of %s: %v", root, err)
+		}
+	}()
+}
+
+// 重新统计空间用量并写回数据库。统计期间其他请求通过 addSpaceUsage 记录的增量
+// 不能被覆盖，因此按统计前后的差值原子地更新，而不是直接写入统计结果
+func refreshSpaceUsage(ctx context.Context, root string) (model.SpaceUsage, error) {
+	su := query.SpaceUsage
+	before, err := su.WithContext(ctx).Where(su.Space.Eq(root)).First()
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.SpaceUsage{}, err
+	}
+	bytes, files, err := treeUsage(ctx, root)
+	if err != nil {
+		return model.SpaceUsage{}, err
+	}
+	if before == nil {
+		// 其他实例可能同时创建了记录，以先创建的为准
+		err = su.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&model.SpaceUsage{Space: root, Bytes: bytes, Files: files})
+	} else {
+		_, err = su.WithContext(ctx).Where(su.ID.Eq(before.ID)).
+			UpdateSimple(su.Bytes.Add(bytes-before.Bytes), su.Files.Add(files-before.Files))
+	}
+	if err != nil {
+		return model.SpaceUsage{}, err
+	}
+	usage, err := su.WithContext(ctx).Where(su.Space.Eq(root)).First()
+	if err != nil {
+		return model.SpaceUsage{}, err
+	}
+	usageMu.Lock()
+	usageCache[root] = usage
+	usageMu.Unlock()
+	return *usage, nil
+}
+
+// 增量更新空间用量
+func addSpaceUsage(ctx context.Context, root string, bytes, files int64) {
+	if root == "" || (bytes == 0 && files == 0) {
+		return
+	}
+	// 还没有统计过的空间由后台统计时一并计入
+	if _, err := spaceUsageOf(ctx, root); err != nil {
+		if !errors.Is(err, errUsageUnknown) {
+			logutils.Log.Errorf("space usage %s: %v", root, err)
+		}
+		return
+	}
+	usageMu.Lock()
+	if usage, ok := usageCache[root]; ok {
+		usage.Bytes += bytes
+		usage.Files += files
+	}
+	usageMu.Unlock()
+	su := query.SpaceUsage
+	if _, err := su.WithContext(ctx).Where(su.Space.Eq(root)).UpdateSimple(su.Bytes.Add(bytes), su.Files.Add(files)); err != nil {
+		logutils.Log.Errorf("space usage %s: %v", root, err)
+	}
+}
+
+// 检查向 realPath 所在空间再写入 bytes 字节后是否超出配额
+func checkQuota(ctx context.Context, realPath string, bytes int64) error {
+	root := spaceRootOf(realPath)
+	if root == "" || bytes <= 0 {
+		return nil
+	}
+	quota := spaceQuota(ctx, root)
+	if quota < 0 {
+		return nil
+	}
+	usage, err := spaceUsageOf(ctx, root)
+	if errors.Is(err, errUsageUnknown) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if usage.Bytes+bytes > quota {
+		return fmt.Errorf("%w: %d of %d bytes used", ErrQuotaExceeded, usage.Bytes, quota)
+	}
+	return nil
+}
+
+// 空间剩余可用的字节数，不限制配额时返回 -1
+func remainingQuota(ctx context.Context, realPath string) (int64, error) {
+	root := spaceRootOf(realPath)
+	if root == "" {
+		return -1, nil
+	}
+	quota := spaceQuota(ctx, root)
+	if quota < 0 {
+		return -1, nil
+	}
+	usage, err := spaceUsageOf(ctx, root)
+	if errors.Is(err, errUsageUnknown) {
+		return -1, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return max(quota-usage.Bytes, 0), nil
+}
+
+// 长度未知的请求体，读取超过 n 字节时返回 ErrQuotaExceeded
+type quotaReader struct {
+	io.ReadCloser
+	n        int64
+	exceeded bool
+}
+
+func (r *quotaReader) Read(p []byte) (int, error) {
+	if r.n <= 0 {
+		// 正好用完配额时还要确认后面没有数据
+		var b [1]byte
+		n, err := r.ReadCloser.Read(b[:])
+		if n > 0 {
+			r.exceeded = true
+			return 0, ErrQuotaExceeded
+		}
+		return 0, err
+	}
+	if int64(len(p)) > r.n {
+		p = p[:r.n]
+	}
+	n, err := r.ReadCloser.Read(p)
+	r.n -= int64(n)
+	return n, err
+}
+
+func quotaError(c *gin.Context, err error) {
+	if errors.Is(err, ErrQuotaExceeded) {
+		response.HTTPError(c, http.StatusInsufficientStorage, err.Error(), response.QuotaExceeded)
+		return
+	}
+	response.Error(c, err.Error(), response.NotSpecified)
+}
+
+// 写操作前记录目标的原有用量，完成后据此增量更新空间用量
+type usageSnapshot struct {
+	name  string
+	bytes int64
+	files int64
+}
+
+func snapshotUsage(ctx context.Context, name string) usageSnapshot {
+	bytes, files, err := treeUsage(ctx, name)
+	if err != nil {
+		logutils.Log.Warnf("usage of %s: %v", name, err)
+	}
+	return usageSnapshot{name: name, bytes: bytes, files: files}
+}
+
+func (s usageSnapshot) commit(ctx context.Context) {
+	invalidateDU(s.name)
+	bytes, files, err := treeUsage(ctx, s.name)
+	if err != nil {
+		logutils.Log.Warnf("usage of %s: %v", s.name, err)
+	}
+	addSpaceUsage(ctx, spaceRootOf(s.name), bytes-s.bytes, files-s.files)
+}
+
+type QuotaResp struct {
+	Name  string `json:"name"`
+	Used  int64  `json:"used"`
+	Files int64  `json:"files"`
+	Quota int64  `json:"quota"`
+}
+
+// 用户查看自己可访问的各空间的用量和配额
+func GetQuota(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	roots := []string{model.UserPath, model.PublicPath}
+	if jwttoken.AccountID != util.QueueIDNull && jwttoken.AccountID != model.DefaultAccountID {
+		roots = append(roots, model.AccountPath)
+	}
+	var data []QuotaResp
+	for _, name := range roots {
+		realPath, err := Redirect(c, name, jwttoken)
+		if err != nil {
+			continue
+		}
+		root := spaceRootOf(realPath)
+		usage, err := spaceUsageOf(c, root)
+		if err != nil && !errors.Is(err, errUsageUnknown) {
+			response.Error(c, err.Error(), response.NotSpecified)
+			return
+		}
+		data = append(data, QuotaResp{Name: name, Used: usage.Bytes, Files: usage.Files, Quota: spaceQuota(c, root)})
+	}
+	response.Success(c, data)
+}
+
+// 所有用户、账户和公共空间的根目录
+func spaceRoots(ctx context.Context) ([]string, error) {
+	roots := []string{cleanRealPath(config.GetConfig().PublicSpacePrefix)}
+	u := query.User
+	users, err := u.WithContext(ctx).Where(u.ID.IsNotNull()).Find()
+	if err != nil {
+		return nil, fmt.Errorf("can't get user")
+	}
+	for _, us := range users {
+		if us.Space != "" {
+			roots = append(roots, spaceRootOf(config.GetConfig().UserSpacePrefix+"/"+us.Space))
+		}
+	}
+	a := query.Account
+	accounts, err := a.WithContext(ctx).Where(a.ID.IsNotNull(), a.ID.Neq(model.DefaultAccountID)).Find()
+	if err != nil {
+		return nil, fmt.Errorf("can't get account")
+	}
+	for _, acc := range accounts {
+		if acc.Space != "" {
+			roots = append(roots, spaceRootOf(config.GetConfig().AccountSpacePrefix+"/"+acc.Space))
+		}
+	}
+	return roots, nil
+}
+
+// 定期重新统计所有空间的用量，校正绕过本服务写入的文件造成的偏差
+func scanUsage() {
+	ctx := context.Background()
+	roots, err := spaceRoots(ctx)
+	if err != nil {
+		logutils.Log.Warnf("scan space usage: %v", err)
+		return
+	}
+	for _, root := range roots {
+		if _, err := refreshSpaceUsage(ctx, root); err != nil {
+			logutils.Log.Warnf("scan usage of %s: %v", root, err)
+		}
+	}
+}
+
+func StartScanUsage() {
+	checkfs()
+	minutes := config.GetConfig().Quota.ScanIntervalMinutes
+	if minutes <= 0 {
+		minutes = defaultUsageScanMinutes
+	}
+	for {
+		scanUsage()
+		time.Sleep(time.Duration(minutes) * time.Minute)
+	}
+}
+
+func RegisterQuota(webdavGroup *gin.RouterGroup) {
+	webdavGroup.GET("/quota", GetQuota)
+}
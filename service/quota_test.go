@@ -0,0 +1,44 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestQuotaReader(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		n        int64
+		exceeded bool
+	}{
+		{"empty body", "", 0, false},
+		{"under quota", "abc", 10, false},
+		{"exactly quota", "abcdefghij", 10, false},
+		{"over quota", "abcdefghijk", 10, true},
+		{"no quota left", "a", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &quotaReader{ReadCloser: io.NopCloser(strings.NewReader(tt.body)), n: tt.n}
+			data, err := io.ReadAll(r)
+			if tt.exceeded {
+				if !errors.Is(err, ErrQuotaExceeded) || !r.exceeded {
+					t.Fatalf("err = %v, exceeded = %v, want quota exceeded", err, r.exceeded)
+				}
+				if int64(len(data)) != tt.n {
+					t.Errorf("read %d bytes before failing, want %d", len(data), tt.n)
+				}
+				return
+			}
+			if err != nil || r.exceeded {
+				t.Fatalf("err = %v, exceeded = %v", err, r.exceeded)
+			}
+			if string(data) != tt.body {
+				t.Errorf("read %q, want %q", data, tt.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package service
+
+import (
+	"errors"
+	"os"
+)
+
+func reflink(_, _ *os.File) error {
+	return errors.ErrUnsupported
+}
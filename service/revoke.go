@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+	"webdav/dao/model"
+	"webdav/dao/query"
+	"webdav/logutils"
+	"webdav/response"
+	"webdav/util"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	revocationSyncInterval = 30 * time.Second
+	userStatusTTL          = 30 * time.Second
+)
+
+var (
+	ErrTokenRevoked  = errors.New("token has been revoked")
+	ErrUserNotActive = errors.New("user is not active")
+)
+
+// 撤销记录的内存缓存，定期从数据库同步，使其他实例上的撤销也能生效
+var revocations = struct {
+	sync.RWMutex
+	loaded bool
+	tokens map[string]time.Time // jti -> 令牌过期时间
+	users  map[uint]time.Time   // 用户 -> 在此之前签发的令牌全部失效
+}{}
+
+type userStatusEntry struct {
+	status    model.Status
+	checkedAt time.Time
+}
+
+// 用户状态缓存，避免每个请求都查询数据库
+var userStatusCache sync.Map
+
+func loadRevocations(ctx context.Context) error {
+	r := query.RevokedToken
+	rows, err := r.WithContext(ctx).Where(r.ExpiresAt.Gt(time.Now())).Find()
+	if err != nil {
+		return err
+	}
+	tokens := make(map[string]time.Time)
+	users := make(map[uint]time.Time)
+	for _, row := range rows {
+		if row.JTI != "" {
+			tokens[row.JTI] = row.ExpiresAt
+		} else if row.CreatedAt.After(users[row.UserID]) {
+			users[row.UserID] = row.CreatedAt
+		}
+	}
+	revocations.Lock()
+	revocations.tokens, revocations.users, revocations.loaded = tokens, users, true
+	revocations.Unlock()
+	return nil
+}
+
+func isTokenRevoked(ctx context.Context, token util.JWTMessage) (bool, error) {
+	revocations.RLock()
+	loaded := revocations.loaded
+	revocations.RUnlock()
+	if !loaded {
+		if err := loadRevocations(ctx); err != nil {
+			return false, err
+		}
+	}
+	revocations.RLock()
+	defer revocations.RUnlock()
+	if _, ok := revocations.tokens[token.TokenID]; ok && token.TokenID != "" {
+		return true, nil
+	}
+	cutoff, ok := revocations.users[token.UserID]
+	// iat 只精确到秒，同一秒内签发的令牌也视为已撤销
+	return ok && !token.IssuedAt.After(cutoff.Truncate(time.Second)), nil
+}
+
+func userStatus(ctx context.Context, userID uint) (model.Status, error) {
+	if v, ok := userStatusCache.Load(userID); ok {
+		entry := v.(userStatusEntry)
+		if time.Since(entry.checkedAt) < userStatusTTL {
+			return entry.status, nil
+		}
+	}
+	u := query.User
+	user, err := u.WithContext(ctx).Where(u.ID.Eq(userID)).First()
+	if err != nil {
+		return 0, err
+	}
+	userStatusCache.Store(userID, userStatusEntry{status: user.Status, checkedAt: time.Now()})
+	return user.Status, nil
+}
+
+// 检查 JWT 是否被撤销，以及所属用户是否仍处于激活状态
+func checkTokenActive(ctx context.Context, token util.JWTMessage) error {
+	revoked, err := isTokenRevoked(ctx, token)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return ErrTokenRevoked
+	}
+	status, err := userStatus(ctx, token.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrUserNotActive
+	}
+	if err != nil {
+		return err
+	}
+	if status != model.StatusActive {
+		return ErrUserNotActive
+	}
+	return nil
+}
+
+func revokeToken(ctx context.Context, token util.JWTMessage) error {
+	if token.TokenID == "" {
+		return nil
+	}
+	row := &model.RevokedToken{JTI: token.TokenID, UserID: token.UserID, ExpiresAt: token.ExpiresAt}
+	if err := query.RevokedToken.WithContext(ctx).Create(row); err != nil {
+		return err
+	}
+	revocations.Lock()
+	if revocations.tokens != nil {
+		revocations.tokens[row.JTI] = row.ExpiresAt
+	}
+	revocations.Unlock()
+	return nil
+}
+
+// 撤销用户已签发的所有 JWT、个人访问令牌和分享链接
+func revokeUserTokens(ctx context.Context, userID uint) error {
+	row := &model.RevokedToken{UserID: userID, ExpiresAt: time.Now().Add(util.GetTokenMgr().MaxTokenTTL())}
+	err := query.Q.Transaction(func(tx *query.Query) error {
+		if err := tx.RevokedToken.WithContext(ctx).Create(row); err != nil {
+			return err
+		}
+		if _, err := tx.APIKey.WithContext(ctx).Where(tx.APIKey.UserID.Eq(userID)).Delete(); err != nil {
+			return err
+		}
+		_, err := tx.ShareLink.WithContext(ctx).Where(tx.ShareLink.UserID.Eq(userID)).Delete()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	revocations.Lock()
+	if revocations.users != nil {
+		revocations.users[userID] = row.CreatedAt
+	}
+	revocations.Unlock()
+	userStatusCache.Delete(userID)
+	return nil
+}
+
+type RevokeUserRequest struct {
+	UserID uint `uri:"userID" binding:"required"`
+}
+
+type LogoutReq struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// 管理员撤销用户的所有令牌，用户需要重新登录
+func RevokeUserTokens(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	if jwttoken.RolePlatform != model.RoleAdmin {
+		response.HTTPError(c, http.StatusUnauthorized, "Your RolePlatform is not RoleAdmin", response.NotSpecified)
+		return
+	}
+	var req RevokeUserRequest
+	if err = c.ShouldBindUri(&req); err != nil {
+		response.HTTPError(c, http.StatusBadRequest, err.Error(), response.NotSpecified)
+		return
+	}
+	u := query.User
+	if _, err = u.WithContext(c).Where(u.ID.Eq(req.UserID)).First(); err != nil {
+		response.Error(c, "user does not exist", response.UserNotFound)
+		return
+	}
+	if err = revokeUserTokens(c, req.UserID); err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	response.Success(c, "revoke tokens successfully")
+}
+
+// 撤销当前的访问令牌，请求体中带上刷新令牌时一并撤销
+func Logout(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	if jwttoken.Scope != nil {
+		response.BadRequestError(c, "personal access tokens are revoked through /keys")
+		return
+	}
+	var req LogoutReq
+	if c.Request.ContentLength > 0 {
+		if err = c.ShouldBindJSON(&req); err != nil {
+			response.BadRequestError(c, err.Error())
+			return
+		}
+	}
+	if err = revokeToken(c, jwttoken); err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	if req.RefreshToken != "" {
+		refresh, rerr := util.GetTokenMgr().CheckRefreshToken(req.RefreshToken)
+		if rerr == nil && refresh.UserID == jwttoken.UserID {
+			if err = revokeToken(c, refresh); err != nil {
+				response.Error(c, err.Error(), response.NotSpecified)
+				return
+			}
+		}
+	}
+	response.Success(c, "logout successfully")
+}
+
+func syncRevocations() {
+	ctx := context.Background()
+	if err := loadRevocations(ctx); err != nil {
+		logutils.Log.Errorf("load revoked tokens: %v", err)
+	}
+	r := query.RevokedToken
+	if _, err := r.WithContext(ctx).Where(r.ExpiresAt.Lte(time.Now())).Delete(); err != nil {
+		logutils.Log.Errorf("purge revoked tokens: %v", err)
+	}
+}
+
+func StartSyncRevocations() {
+	for {
+		syncRevocations()
+		time.Sleep(revocationSyncInterval)
+	}
+}
+
+func RegisterRevoke(webdavGroup *gin.RouterGroup) {
+	webdavGroup.POST("/auth/logout", Logout)
+	webdavGroup.POST("/admin/users/:userID/revoke", RevokeUserTokens)
+}
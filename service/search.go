@@ -0,0 +1,357 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"webdav/config"
+	"webdav/dao/model"
+	"webdav/dao/query"
+	"webdav/logutils"
+	"webdav/response"
+	"webdav/util"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gen"
+	"gorm.io/gen/field"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultIndexScanMinutes = 60
+	indexBatchSize          = 500
+)
+
+var errSearchDenied = errors.New("you have no permission to search this path")
+
+type indexedFile struct {
+	id      uint
+	isDir   bool
+	size    int64
+	modTime time.Time
+}
+
+func saveIndex(ctx context.Context, rows []*model.FileIndex) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	return query.FileIndex.WithContext(ctx).Save(rows...)
+}
+
+// 扫描一个空间，只写入新增或有变化的条目，并删除已经不存在的条目
+func indexSpace(ctx context.Context, root string) error {
+	f := query.FileIndex
+	existing := make(map[string]indexedFile)
+	var rows []*model.FileIndex
+	err := f.WithContext(ctx).Select(f.ID, f.Path, f.IsDir, f.Size, f.ModTime).Where(f.Space.Eq(root)).
+		FindInBatches(&rows, indexBatchSize*10, func(gen.Dao, int) error {
+			for _, row := range rows {
+				existing[row.Path] = indexedFile{id: row.ID, isDir: row.IsDir, size: row.Size, modTime: row.ModTime}
+			}
+			return nil
+		})
+	if err != nil {
+		return err
+	}
+	fi, err := fs.FileSystem.Stat(ctx, root)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		trash := trashDirOf(root)
+		var pending []*model.FileIndex
+		err = walkFS(ctx, root, fi, func(p string, fi os.FileInfo) error {
+			if p == root {
+				return nil
+			}
+			if p == trash {
+				return filepath.SkipDir
+			}
+			// 数据库中的时间只精确到微秒
+			modTime := fi.ModTime().Truncate(time.Microsecond)
+			old, ok := existing[p]
+			delete(existing, p)
+			if ok && old.isDir == fi.IsDir() && old.size == fi.Size() && old.modTime.Equal(modTime) {
+				return nil
+			}
+			pending = append(pending, &model.FileIndex{
+				ID:      old.id,
+				Space:   root,
+				Path:    p,
+				Name:    fi.Name(),
+				IsDir:   fi.IsDir(),
+				Size:    fi.Size(),
+				ModTime: modTime,
+			})
+			if len(pending) < indexBatchSize {
+				return nil
+			}
+			serr := saveIndex(ctx, pending)
+			pending = pending[:0]
+			return serr
+		})
+		if serr := saveIndex(ctx, pending); err == nil {
+			err = serr
+		}
+		// 没有扫描完整时不能确定哪些文件已被删除
+		if err != nil {
+			return err
+		}
+	}
+	ids := make([]uint, 0, len(existing))
+	for _, old := range existing {
+		ids = append(ids, old.id)
+	}
+	for len(ids) > 0 {
+		n := min(len(ids), indexBatchSize)
+		if _, err = f.WithContext(ctx).Where(f.ID.In(ids[:n]...)).Delete(); err != nil {
+			return err
+		}
+		ids = ids[n:]
+	}
+	return nil
+}
+
+func indexFiles() {
+	ctx := context.Background()
+	roots, err := spaceRoots(ctx)
+	if err != nil {
+		logutils.Log.Errorf("index files: %v", err)
+		return
+	}
+	for _, root := range roots {
+		if err = indexSpace(ctx, root); err != nil {
+			logutils.Log.Warnf("index files of %s: %v", root, err)
+		}
+	}
+	// 清理已删除的用户和账户的索引
+	f := query.FileIndex
+	if _, err = f.WithContext(ctx).Where(f.Space.NotIn(roots...)).Delete(); err != nil {
+		logutils.Log.Warnf("purge file index: %v", err)
+	}
+}
+
+func StartIndexFiles() {
+	checkfs()
+	minutes := config.GetConfig().Index.ScanIntervalMinutes
+	if minutes <= 0 {
+		minutes = defaultIndexScanMinutes
+	}
+	for {
+		indexFiles()
+		time.Sleep(time.Duration(minutes) * time.Minute)
+	}
+}
+
+type SearchReq struct {
+	Q             string    `form:"q" binding:"required"` // 文件名的通配符，regex 为真时为正则表达式
+	Regex         bool      `form:"regex"`
+	IgnoreCase    bool      `form:"ignoreCase"`
+	Root          string    `form:"root"` // 只搜索这个虚拟路径下的文件，默认搜索所有可读的空间
+	Type          string    `form:"type"` // file 或 dir
+	MinSize       int64     `form:"minSize"`
+	ModifiedAfter time.Time `form:"modifiedAfter"`
+	Page          int       `form:"page"`
+	Size          int       `form:"size"`
+}
+
+type SearchResult struct {
+	Path    string    `json:"path"` // 虚拟路径
+	Name    string    `json:"name"`
+	IsDir   bool      `json:"isdir"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modifytime"`
+}
+
+type SearchResp struct {
+	Items []SearchResult `json:"items"`
+	Total int64          `json:"total"`
+}
+
+type searchRoot struct {
+	virtual  string
+	realPath string
+}
+
+// 将通配符转换为匹配整个文件名的正则表达式
+func globToRegexp(glob string) (string, error) {
+	if _, err := path.Match(glob, ""); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch ch := glob[i]; ch {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+			}
+		case '[':
+			// 字符类的语法与正则表达式相同，path.Match 已检查过括号是成对的
+			end := i + 1
+			for glob[end] != ']' {
+				if glob[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			b.WriteString(glob[i : end+1])
+			i = end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	b.WriteString("$")
+	return b.String(), nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// 要搜索的虚拟根目录及其实际路径，显式指定的 root 不可读时返回错误
+func searchRoots(c *gin.Context, req *SearchReq, jwttoken util.JWTMessage) ([]searchRoot, error) {
+	names := []string{model.UserPath, model.PublicPath, model.AccountPath}
+	if req.Root != "" {
+		names = []string{req.Root}
+	} else if jwttoken.Scope != nil && keyScopeLimited(jwttoken.Scope) {
+		names = jwttoken.Scope.Roots
+	}
+	var roots []searchRoot
+	for _, name := range names {
+		name = strings.Trim(path.Clean("/"+name), "/")
+		if name == "" || !GetPermission(name, jwttoken, c).CanRead() {
+			if req.Root != "" {
+				return nil, errSearchDenied
+			}
+			continue
+		}
+		realPath, rerr := Redirect(c, name, jwttoken)
+		if rerr != nil {
+			if req.Root != "" {
+				return nil, rerr
+			}
+			continue
+		}
+		roots = append(roots, searchRoot{virtual: name, realPath: cleanRealPath(realPath)})
+	}
+	return roots, nil
+}
+
+// 按文件名搜索可读空间中的文件，结果来自后台维护的索引，可能略晚于实际的文件变化
+func SearchFiles(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	var req SearchReq
+	if err = c.ShouldBindQuery(&req); err != nil {
+		response.BadRequestError(c, err.Error())
+		return
+	}
+	if req.Type != "" && req.Type != "file" && req.Type != "dir" {
+		response.BadRequestError(c, "type must be file or dir")
+		return
+	}
+	pattern := req.Q
+	if req.Regex {
+		if _, err = regexp.Compile(pattern); err != nil {
+			response.BadRequestError(c, "invalid regex: "+err.Error())
+			return
+		}
+	} else if pattern, err = globToRegexp(pattern); err != nil {
+		response.BadRequestError(c, "invalid pattern: "+err.Error())
+		return
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.Size <= 0 || req.Size > maxPageSize {
+		req.Size = defaultPageSize
+	}
+	roots, err := searchRoots(c, &req, jwttoken)
+	if errors.Is(err, errSearchDenied) {
+		permissionDenied(c, req.Root, jwttoken, err.Error(), response.NotSpecified)
+		return
+	}
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	data := SearchResp{Items: []SearchResult{}}
+	if len(roots) == 0 {
+		response.Success(c, data)
+		return
+	}
+
+	f := query.FileIndex
+	conds := make([]field.Expr, 0, len(roots))
+	for _, root := range roots {
+		space := spaceRootOf(root.realPath)
+		switch {
+		case space == root.realPath:
+			conds = append(conds, f.Space.Eq(space))
+		case space != "":
+			conds = append(conds, field.And(f.Space.Eq(space), f.Path.Like(escapeLike(root.realPath)+"/%")))
+		default:
+			// 管理员的根目录包含所有用户或账户的空间
+			conds = append(conds, f.Path.Like(escapeLike(root.realPath)+"/%"))
+		}
+	}
+	op := "~"
+	if req.IgnoreCase {
+		op = "~*"
+	}
+	q := f.WithContext(c).Where(field.Or(conds...)).
+		Where(gen.Cond(clause.Expr{SQL: "? " + op + " ?", Vars: []any{clause.Column{Name: "name"}, pattern}})...)
+	if req.Type != "" {
+		q = q.Where(f.IsDir.Is(req.Type == "dir"))
+	}
+	if req.MinSize > 0 {
+		q = q.Where(f.Size.Gte(req.MinSize))
+	}
+	if !req.ModifiedAfter.IsZero() {
+		q = q.Where(f.ModTime.Gt(req.ModifiedAfter))
+	}
+	rows, total, err := q.Order(f.Path).FindByPage((req.Page-1)*req.Size, req.Size)
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	data.Total = total
+	for _, row := range rows {
+		data.Items = append(data.Items, SearchResult{
+			Path:    virtualPathOf(roots, row.Path),
+			Name:    row.Name,
+			IsDir:   row.IsDir,
+			Size:    row.Size,
+			ModTime: row.ModTime,
+		})
+	}
+	response.Success(c, data)
+}
+
+// 将实际路径转换为搜索根目录下的虚拟路径，不向用户暴露实际路径
+func virtualPathOf(roots []searchRoot, realPath string) string {
+	for _, root := range roots {
+		if rest, ok := strings.CutPrefix(realPath, root.realPath+"/"); ok {
+			return root.virtual + "/" + rest
+		}
+	}
+	return ""
+}
+
+func RegisterSearch(webdavGroup *gin.RouterGroup) {
+	webdavGroup.GET("/search", SearchFiles)
+}
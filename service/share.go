@@ -0,0 +1,440 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+	"webdav/config"
+	"webdav/dao/model"
+	"webdav/dao/query"
+	"webdav/response"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	shareTokenBytes      = 16
+	sharePasswordHeader  = "X-Share-Password"
+	shareUploadFormField = "file"
+)
+
+type CreateShareReq struct {
+	Path         string          `json:"path" binding:"required"`
+	Mode         model.ShareMode `json:"mode"`
+	Password     string          `json:"password"`
+	ExpiresAt    *time.Time      `json:"expiresAt"`
+	MaxDownloads int64           `json:"maxDownloads" binding:"min=0"`
+}
+
+type ShareResp struct {
+	ID           uint            `json:"id"`
+	Token        string          `json:"token"`
+	URL          string          `json:"url"`
+	Path         string          `json:"path"`
+	IsDir        bool            `json:"isdir"`
+	Mode         model.ShareMode `json:"mode"`
+	HasPassword  bool            `json:"hasPassword"`
+	ExpiresAt    *time.Time      `json:"expiresAt"`
+	MaxDownloads int64           `json:"maxDownloads"`
+	Downloads    int64           `json:"downloads"`
+	CreatedAt    time.Time       `json:"createdAt"`
+}
+
+// 分享链接中的条目，不返回实际路径等服务端信息
+type ShareEntryResp struct {
+	Name       string    `json:"name"`
+	Size       int64     `json:"size"`
+	IsDir      bool      `json:"isdir"`
+	ModifyTime time.Time `json:"modifytime"`
+}
+
+type ShareRequest struct {
+	ID uint `uri:"id" binding:"required"`
+}
+
+func toShareResp(link *model.ShareLink) ShareResp {
+	return ShareResp{
+		ID:           link.ID,
+		Token:        link.Token,
+		URL:          "/api/ss/s/" + link.Token,
+		Path:         link.Path,
+		IsDir:        link.IsDir,
+		Mode:         link.Mode,
+		HasPassword:  link.PasswordHash != "",
+		ExpiresAt:    link.ExpiresAt,
+		MaxDownloads: link.MaxDownloads,
+		Downloads:    link.Downloads,
+		CreatedAt:    link.CreatedAt,
+	}
+}
+
+// 创建分享链接，只读分享需要读权限，仅上传分享需要目录的读写权限
+func CreateShare(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	var req CreateShareReq
+	if err = c.ShouldBindJSON(&req); err != nil {
+		response.BadRequestError(c, err.Error())
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = model.ShareReadOnly
+	}
+	if req.Mode != model.ShareReadOnly && req.Mode != model.ShareUploadOnly {
+		response.BadRequestError(c, "mode must be readonly or uploadonly")
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		response.BadRequestError(c, "expiresAt must be in the future")
+		return
+	}
+	virtualPath := strings.TrimPrefix(path.Clean("/"+req.Path), "/")
+	if getFirstToken("/"+virtualPath) == "" || !strings.Contains(virtualPath, "/") {
+		response.BadRequestError(c, "can't share a whole space")
+		return
+	}
+	permission := GetPermission(virtualPath, jwttoken, c)
+	// 通过分享链接上传不会覆盖已有文件，只能追加的空间也可以创建
+	if !permission.CanRead() || (req.Mode == model.ShareUploadOnly && !permission.CanCreate()) {
+		permissionDenied(c, virtualPath, jwttoken, "Your permission is not enough to share this path", response.NotSpecified)
+		return
+	}
+	realPath, err := Redirect(c, virtualPath, jwttoken)
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	fi, err := fs.FileSystem.Stat(c, realPath)
+	if err != nil {
+		response.BadRequestError(c, "can't find file")
+		return
+	}
+	if req.Mode == model.ShareUploadOnly && !fi.IsDir() {
+		response.BadRequestError(c, "uploadonly links must share a directory")
+		return
+	}
+	token, err := newRandomID(shareTokenBytes)
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	link := &model.ShareLink{
+		Token:        token,
+		UserID:       jwttoken.UserID,
+		AccountID:    jwttoken.AccountID,
+		Path:         virtualPath,
+		RealPath:     cleanRealPath(realPath),
+		IsDir:        fi.IsDir(),
+		Mode:         req.Mode,
+		ExpiresAt:    req.ExpiresAt,
+		MaxDownloads: req.MaxDownloads,
+	}
+	if link.ExpiresAt == nil {
+		if hours := config.GetConfig().Share.DefaultExpireHours; hours > 0 {
+			expires := time.Now().Add(time.Duration(hours) * time.Hour)
+			link.ExpiresAt = &expires
+		}
+	}
+	if req.Password != "" {
+		hash, herr := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if herr != nil {
+			response.BadRequestError(c, herr.Error())
+			return
+		}
+		link.PasswordHash = string(hash)
+	}
+	if err = query.ShareLink.WithContext(c).Create(link); err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	response.Success(c, toShareResp(link))
+}
+
+// 列出自己创建的分享链接，管理员可以通过 all=true 查看所有人的
+func ListShares(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	s := query.ShareLink
+	q := s.WithContext(c).Order(s.CreatedAt.Desc())
+	if c.Query("all") != "true" || jwttoken.RolePlatform != model.RoleAdmin {
+		q = q.Where(s.UserID.Eq(jwttoken.UserID))
+	}
+	links, err := q.Find()
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	data := make([]ShareResp, 0, len(links))
+	for _, link := range links {
+		data = append(data, toShareResp(link))
+	}
+	response.Success(c, data)
+}
+
+// 撤销分享链接
+func DeleteShare(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	var req ShareRequest
+	if err = c.ShouldBindUri(&req); err != nil {
+		response.HTTPError(c, http.StatusBadRequest, err.Error(), response.NotSpecified)
+		return
+	}
+	s := query.ShareLink
+	q := s.WithContext(c).Where(s.ID.Eq(req.ID))
+	if jwttoken.RolePlatform != model.RoleAdmin {
+		q = q.Where(s.UserID.Eq(jwttoken.UserID))
+	}
+	info, err := q.Delete()
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	if info.RowsAffected == 0 {
+		response.HTTPError(c, http.StatusNotFound, "share link does not exist", response.NotSpecified)
+		return
+	}
+	response.Success(c, "revoke share link successfully")
+}
+
+// 按令牌查找仍然有效的分享链接并校验密码，失败时已写入响应
+func openShareLink(c *gin.Context) (*model.ShareLink, bool) {
+	s := query.ShareLink
+	link, err := s.WithContext(c).Where(s.Token.Eq(c.Param("token"))).First()
+	if err != nil {
+		response.HTTPError(c, http.StatusNotFound, "share link does not exist", response.NotSpecified)
+		return nil, false
+	}
+	if link.ExpiresAt != nil && time.Now().After(*link.ExpiresAt) {
+		response.HTTPError(c, http.StatusGone, "share link has expired", response.NotSpecified)
+		return nil, false
+	}
+	// 密码只从请求头读取，放在查询参数中会被记录到访问日志
+	if link.PasswordHash != "" &&
+		bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(c.GetHeader(sharePasswordHeader))) != nil {
+		response.HTTPError(c, http.StatusUnauthorized, "wrong password", response.WrongPassword)
+		return nil, false
+	}
+	if err = checkShareCreator(c, link); err != nil {
+		if errors.Is(err, errShareNotPermitted) || errors.Is(err, ErrUserNotActive) || errors.Is(err, ErrTokenRevoked) {
+			response.HTTPError(c, http.StatusGone, "share link is no longer valid: "+err.Error(), response.NotSpecified)
+			return nil, false
+		}
+		response.Error(c, err.Error(), response.NotSpecified)
+		return nil, false
+	}
+	return link, true
+}
+
+var errShareNotPermitted = errors.New("the creator no longer has permission to share this path")
+
+// 每次访问时按创建者当前的状态检查：被禁用、撤销了所有令牌或失去了对分享路径的权限后，链接随之失效
+func checkShareCreator(c *gin.Context, link *model.ShareLink) error {
+	u := query.User
+	user, err := u.WithContext(c).Where(u.ID.Eq(link.UserID)).First()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrUserNotActive
+	}
+	if err != nil {
+		return err
+	}
+	creator := userClaims(c, user, link.AccountID)
+	creator.IssuedAt = link.CreatedAt
+	if err = checkTokenActive(c, creator); err != nil {
+		return err
+	}
+	permission := getPermission(link.Path, creator, c)
+	if !permission.CanRead() || (link.Mode == model.ShareUploadOnly && !permission.CanCreate()) {
+		return errShareNotPermitted
+	}
+	return nil
+}
+
+// 分享内的实际路径，path 参数相对于分享的目录，不能跳出分享的范围
+func shareTarget(link *model.ShareLink, sub string) string {
+	sub = strings.TrimPrefix(path.Clean("/"+sub), "/")
+	if sub == "" {
+		return link.RealPath
+	}
+	return link.RealPath + "/" + sub
+}
+
+// 记录一次下载，超过最大下载次数时返回 false
+func countShareDownload(ctx context.Context, link *model.ShareLink) (bool, error) {
+	s := query.ShareLink
+	q := s.WithContext(ctx).Where(s.ID.Eq(link.ID))
+	if link.MaxDownloads > 0 {
+		q = q.Where(s.Downloads.Lt(link.MaxDownloads))
+	}
+	info, err := q.UpdateSimple(s.Downloads.Add(1))
+	if err != nil {
+		return false, err
+	}
+	return info.RowsAffected != 0, nil
+}
+
+// 无需登录访问分享链接：目录返回条目列表，文件直接下载，path 参数指定分享目录中的子路径
+func OpenShare(c *gin.Context) {
+	link, ok := openShareLink(c)
+	if !ok {
+		return
+	}
+	if link.Mode == model.ShareUploadOnly {
+		// 仅上传的分享不暴露目录中已有的内容
+		response.Success(c, toShareEntry(link))
+		return
+	}
+	target := shareTarget(link, c.Query("path"))
+	f, err := fs.FileSystem.OpenFile(c.Request.Context(), target, os.O_RDONLY, 0)
+	if err != nil {
+		response.HTTPError(c, http.StatusNotFound, "can't find file", response.NotSpecified)
+		return
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	if fi.IsDir() {
+		data := []ShareEntryResp{}
+		for {
+			children, rerr := f.Readdir(walkBatchSize)
+			for _, child := range children {
+				if child.Name() == model.TrashDir {
+					continue
+				}
+				data = append(data, ShareEntryResp{Name: child.Name(), Size: child.Size(), IsDir: child.IsDir(), ModifyTime: child.ModTime()})
+			}
+			if errors.Is(rerr, io.EOF) || len(children) == 0 {
+				break
+			}
+			if rerr != nil {
+				response.Error(c, rerr.Error(), response.NotSpecified)
+				return
+			}
+		}
+		response.Success(c, data)
+		return
+	}
+	if c.Request.Method == http.MethodGet {
+		counted, cerr := countShareDownload(c, link)
+		if cerr != nil {
+			response.Error(c, cerr.Error(), response.NotSpecified)
+			return
+		}
+		if !counted {
+			response.HTTPError(c, http.StatusGone, "share link has reached its download limit", response.NotSpecified)
+			return
+		}
+	}
+	serveFile(c, f, fi)
+}
+
+func toShareEntry(link *model.ShareLink) ShareEntryResp {
+	return ShareEntryResp{Name: path.Base(link.RealPath), IsDir: link.IsDir, ModifyTime: link.CreatedAt}
+}
+
+// 通过仅上传的分享链接上传文件，已存在的文件不会被覆盖
+func UploadToShare(c *gin.Context) {
+	link, ok := openShareLink(c)
+	if !ok {
+		return
+	}
+	if link.Mode != model.ShareUploadOnly {
+		response.HTTPError(c, http.StatusForbidden, "this share link does not accept uploads", response.NotSpecified)
+		return
+	}
+	form, err := c.MultipartForm()
+	if err != nil {
+		response.BadRequestError(c, err.Error())
+		return
+	}
+	headers := form.File[shareUploadFormField]
+	if len(headers) == 0 {
+		response.BadRequestError(c, "no file uploaded")
+		return
+	}
+	ctx := c.Request.Context()
+	dir := shareTarget(link, c.Query("path"))
+	if fi, serr := fs.FileSystem.Stat(ctx, dir); serr != nil || !fi.IsDir() {
+		response.HTTPError(c, http.StatusNotFound, "can't find directory", response.NotSpecified)
+		return
+	}
+	var total int64
+	for _, h := range headers {
+		total += h.Size
+	}
+	if err = checkQuota(ctx, dir, total); err != nil {
+		quotaError(c, err)
+		return
+	}
+	var names []string
+	for _, h := range headers {
+		name := path.Base(path.Clean("/" + h.Filename))
+		if name == "/" || name == "." || name == model.TrashDir {
+			response.BadRequestError(c, "invalid file name: "+h.Filename)
+			return
+		}
+		if err = saveShareUpload(ctx, dir+"/"+name, h); err != nil {
+			if os.IsExist(err) {
+				response.HTTPError(c, http.StatusConflict, name+" already exists", response.NotSpecified)
+				return
+			}
+			response.Error(c, err.Error(), response.NotSpecified)
+			return
+		}
+		names = append(names, name)
+	}
+	response.Success(c, names)
+}
+
+func saveShareUpload(ctx context.Context, name string, h *multipart.FileHeader) error {
+	src, err := h.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	snapshot := snapshotUsage(ctx, name)
+	defer snapshot.commit(ctx)
+	dst, err := fs.FileSystem.OpenFile(ctx, name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, model.DefaultFilePerm)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = fs.FileSystem.RemoveAll(ctx, name)
+		return fmt.Errorf("save %s: %w", path.Base(name), err)
+	}
+	return nil
+}
+
+func RegisterShare(webdavGroup *gin.RouterGroup) {
+	webdavGroup.POST("/share", CreateShare)
+	webdavGroup.GET("/share", ListShares)
+	webdavGroup.DELETE("/share/:id", DeleteShare)
+	webdavGroup.GET("/s/:token", OpenShare)
+	webdavGroup.HEAD("/s/:token", OpenShare)
+	webdavGroup.POST("/s/:token", UploadToShare)
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package service
+
+import "errors"
+
+func freeBytes(_ string) (int64, error) {
+	return 0, errors.New("statfs is not supported on this platform")
+}
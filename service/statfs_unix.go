@@ -0,0 +1,14 @@
+//go:build unix
+
+package service
+
+import "syscall"
+
+// 路径所在文件系统对非特权用户可用的剩余字节数
+func freeBytes(name string) (int64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(name, &st); err != nil {
+		return 0, err
+	}
+	return int64(st.Bavail) * int64(st.Bsize), nil //nolint:unconvert // field types differ across platforms
+}
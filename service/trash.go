@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	"webdav/config"
+	"webdav/dao/model"
+	"webdav/dao/query"
+	"webdav/logutils"
+	"webdav/response"
+	"webdav/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultTrashRetentionDays = 30
+	trashIDBytes              = 8
+	purgeTrashInterval        = time.Hour
+)
+
+// 空间根目录下的回收站目录
+func trashDirOf(root string) string {
+	return root + "/" + model.TrashDir
+}
+
+// 实际路径是否位于某个空间的回收站中
+func isTrashPath(realPath string) bool {
+	root := spaceRootOf(realPath)
+	if root == "" {
+		return false
+	}
+	p, trash := cleanRealPath(realPath), trashDirOf(root)
+	return p == trash || strings.HasPrefix(p, trash+"/")
+}
+
+// 不属于任何空间或本身就是空间根目录的路径不能放入回收站
+func canMoveToTrash(realPath string) bool {
+	root := spaceRootOf(realPath)
+	return root != "" && root != cleanRealPath(realPath)
+}
+
+// 将文件移入所在空间的回收站，不属于任何空间或本身就是空间根目录时返回错误
+func moveToTrash(ctx context.Context, virtualPath, realPath string, userID uint) (*model.TrashItem, error) {
+	if !canMoveToTrash(realPath) {
+		return nil, fmt.Errorf("%s can't be moved to trash", virtualPath)
+	}
+	root := spaceRootOf(realPath)
+	p := cleanRealPath(realPath)
+	fi, err := fs.FileSystem.Stat(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	if err = fs.FileSystem.Mkdir(ctx, trashDirOf(root), model.DefaultFolderPerm); err != nil && !os.IsExist(err) {
+		return nil, err
+	}
+	id, err := newRandomID(trashIDBytes)
+	if err != nil {
+		return nil, err
+	}
+	bytes, _, err := treeUsage(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	item := &model.TrashItem{
+		Space:     root,
+		Path:      strings.Trim(virtualPath, "/"),
+		RealPath:  p,
+		TrashPath: trashDirOf(root) + "/" + id,
+		IsDir:     fi.IsDir(),
+		Size:      bytes,
+		UserID:    userID,
+	}
+	if err = fs.FileSystem.Rename(ctx, p, item.TrashPath); err != nil {
+		return nil, err
+	}
+	invalidateDU(p)
+	if err = query.TrashItem.WithContext(ctx).Create(item); err != nil {
+		// 记录写入失败时把文件放回原处，避免文件留在回收站中无人可见
+		if rerr := fs.FileSystem.Rename(ctx, item.TrashPath, p); rerr != nil {
+			logutils.Log.Errorf("restore %s from trash: %v", p, rerr)
+		}
+		return nil, err
+	}
+	return item, nil
+}
+
+// 将回收站中的文件放回原处
+func restoreTrashItem(ctx context.Context, item *model.TrashItem) error {
+	if err := moveFiles(ctx, item.TrashPath, item.RealPath, false, nil); err != nil {
+		return err
+	}
+	t := query.TrashItem
+	_, err := t.WithContext(ctx).Unscoped().Where(t.ID.Eq(item.ID)).Delete()
+	return err
+}
+
+// 彻底删除回收站中的文件
+func purgeTrashItem(ctx context.Context, item *model.TrashItem) error {
+	snapshot := snapshotUsage(ctx, item.TrashPath)
+	err := fs.FileSystem.RemoveAll(ctx, item.TrashPath)
+	snapshot.commit(ctx)
+	if err != nil {
+		return err
+	}
+	t := query.TrashItem
+	_, err = t.WithContext(ctx).Unscoped().Where(t.ID.Eq(item.ID)).Delete()
+	return err
+}
+
+// 用户有读写权限的空间根目录，用于判断能否查看和操作回收站
+func writableSpaceRoots(c *gin.Context, token util.JWTMessage) []string {
+	var roots []string
+	names := []string{model.UserPath, model.PublicPath}
+	if token.AccountID != util.QueueIDNull && token.AccountID != model.DefaultAccountID {
+		names = append(names, model.AccountPath)
+	}
+	for _, name := range names {
+		if !GetPermission(name, token, c).CanModify() {
+			continue
+		}
+		realPath, err := Redirect(c, name, token)
+		if err != nil {
+			continue
+		}
+		if root := spaceRootOf(realPath); root != "" {
+			roots = append(roots, root)
+		}
+	}
+	return roots
+}
+
+type TrashRequest struct {
+	ID uint `uri:"id" binding:"required"`
+}
+
+// 查找当前用户可以操作的回收站条目
+func getTrashItem(c *gin.Context, token util.JWTMessage) (*model.TrashItem, error) {
+	var req TrashRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		return nil, err
+	}
+	t := query.TrashItem
+	item, err := t.WithContext(c).Where(t.ID.Eq(req.ID)).First()
+	if err != nil {
+		return nil, fmt.Errorf("trash item does not exist")
+	}
+	if token.RolePlatform == model.RoleAdmin || containsString(writableSpaceRoots(c, token), item.Space) {
+		return item, nil
+	}
+	return nil, fmt.Errorf("trash item does not exist")
+}
+
+type TrashItemResp struct {
+	ID        uint      `json:"id"`
+	Path      string    `json:"path"`
+	IsDir     bool      `json:"isdir"`
+	Size      int64     `json:"size"`
+	UserID    uint      `json:"userID"`
+	DeletedAt time.Time `json:"deletedAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// 列出回收站，普通用户只能看到自己有读写权限的空间中的条目
+func ListTrash(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	t := query.TrashItem
+	q := t.WithContext(c).Order(t.CreatedAt.Desc())
+	if jwttoken.RolePlatform != model.RoleAdmin {
+		roots := writableSpaceRoots(c, jwttoken)
+		if len(roots) == 0 {
+			response.Success(c, []TrashItemResp{})
+			return
+		}
+		q = q.Where(t.Space.In(roots...))
+	}
+	items, err := q.Find()
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	retention := trashRetention()
+	data := make([]TrashItemResp, 0, len(items))
+	for _, item := range items {
+		data = append(data, TrashItemResp{
+			ID:        item.ID,
+			Path:      item.Path,
+			IsDir:     item.IsDir,
+			Size:      item.Size,
+			UserID:    item.UserID,
+			DeletedAt: item.CreatedAt,
+			ExpiresAt: item.CreatedAt.Add(retention),
+		})
+	}
+	response.Success(c, data)
+}
+
+// 将回收站中的文件恢复到原位置
+func RestoreTrash(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	item, err := getTrashItem(c, jwttoken)
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	ctx := c.Request.Context()
+	if _, err = fs.FileSystem.Stat(ctx, item.RealPath); err == nil {
+		response.HTTPError(c, http.StatusConflict, item.Path+" already exists", response.NotSpecified)
+		return
+	}
+	if err = restoreTrashItem(ctx, item); err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	response.Success(c, "restore file successfully")
+}
+
+// 从回收站中彻底删除
+func DeleteTrash(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	item, err := getTrashItem(c, jwttoken)
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	if err = purgeTrashItem(c.Request.Context(), item); err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	response.Success(c, "delete file successfully")
+}
+
+func trashRetention() time.Duration {
+	days := config.GetConfig().Trash.RetentionDays
+	if days <= 0 {
+		days = defaultTrashRetentionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// 清理超过保留期限的回收站条目
+func purgeTrash() {
+	ctx := context.Background()
+	t := query.TrashItem
+	items, err := t.WithContext(ctx).Where(t.CreatedAt.Lt(time.Now().Add(-trashRetention()))).Find()
+	if err != nil {
+		logutils.Log.Errorf("get expired trash items: %v", err)
+		return
+	}
+	for _, item := range items {
+		logutils.Log.Infof("purge trash item %s", item.RealPath)
+		if err := purgeTrashItem(ctx, item); err != nil {
+			logutils.Log.Warnf("purge trash item %d: %v", item.ID, err)
+		}
+	}
+}
+
+func StartPurgeTrash() {
+	checkfs()
+	for {
+		purgeTrash()
+		time.Sleep(purgeTrashInterval)
+	}
+}
+
+func RegisterTrash(webdavGroup *gin.RouterGroup) {
+	webdavGroup.GET("/trash", ListTrash)
+	webdavGroup.POST("/trash/:id/restore", RestoreTrash)
+	webdavGroup.DELETE("/trash/:id", DeleteTrash)
+}
@@ -0,0 +1,562 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"webdav/config"
+	"webdav/dao/model"
+	"webdav/logutils"
+	"webdav/response"
+	"webdav/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tus 1.0 断点续传协议，参考 https://tus.io/protocols/resumable-upload
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,creation-with-upload,termination,expiration"
+	tusOffsetType = "application/offset+octet-stream"
+	uploadRoute   = "/api/ss/uploads"
+	uploadIDBytes = 16
+
+	defaultUploadExpireHours = 24
+	cleanUploadInterval      = 10 * time.Minute
+)
+
+// 未完成的上传以 <UploadPrefix>/<userID>/<uploadID> 存放数据，同名的 .info 文件存放上传信息
+type uploadInfo struct {
+	ID        string    `json:"id"`
+	UserID    uint      `json:"userID"`
+	Path      string    `json:"path"`     // 目标虚拟路径
+	RealPath  string    `json:"realPath"` // 目标实际路径
+	Length    int64     `json:"length"`
+	Metadata  string    `json:"metadata"` // 客户端提交的原始 Upload-Metadata
+	ExpiresAt time.Time `json:"expiresAt"`
+	NoClobber bool      `json:"noClobber"` // 目标位于只能追加的空间，完成时不能覆盖已有文件
+}
+
+// 同一个上传同时只允许一个请求写入
+var uploadLocks sync.Map
+
+func lockUpload(id string) (unlock func(), ok bool) {
+	v, _ := uploadLocks.LoadOrStore(id, &sync.Mutex{})
+	mu, _ := v.(*sync.Mutex)
+	if !mu.TryLock() {
+		return nil, false
+	}
+	return mu.Unlock, true
+}
+
+func uploadExpiry() time.Duration {
+	hours := config.GetConfig().Upload.ExpireHours
+	if hours <= 0 {
+		hours = defaultUploadExpireHours
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+func uploadDir(userID uint) string {
+	return model.UploadPrefix + "/" + strconv.FormatUint(uint64(userID), 10)
+}
+
+func uploadDataPath(userID uint, id string) string {
+	return uploadDir(userID) + "/" + id
+}
+
+func uploadInfoPath(userID uint, id string) string {
+	return uploadDataPath(userID, id) + ".info"
+}
+
+func readUploadInfo(ctx context.Context, userID uint, id string) (*uploadInfo, error) {
+	// id 来自 URL，只允许十六进制字符，避免拼出其他路径
+	if _, err := hex.DecodeString(id); err != nil || len(id) != 2*uploadIDBytes {
+		return nil, os.ErrNotExist
+	}
+	f, err := fs.FileSystem.OpenFile(ctx, uploadInfoPath(userID, id), os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var info uploadInfo
+	if err = json.NewDecoder(f).Decode(&info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func writeUploadInfo(ctx context.Context, info *uploadInfo) error {
+	f, err := fs.FileSystem.OpenFile(ctx, uploadInfoPath(info.UserID, info.ID), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, model.DefaultFilePerm)
+	if err != nil {
+		return err
+	}
+	if err = json.NewEncoder(f).Encode(info); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func removeUpload(ctx context.Context, userID uint, id string) error {
+	err := fs.FileSystem.RemoveAll(ctx, uploadDataPath(userID, id))
+	if rerr := fs.FileSystem.RemoveAll(ctx, uploadInfoPath(userID, id)); err == nil {
+		err = rerr
+	}
+	uploadLocks.Delete(id)
+	return err
+}
+
+func uploadOffset(ctx context.Context, info *uploadInfo) (int64, error) {
+	fi, err := fs.FileSystem.Stat(ctx, uploadDataPath(info.UserID, info.ID))
+	if err != nil {
+		return 0, err
+	}
+	return fi.Size(), nil
+}
+
+// Upload-Metadata 形如 "key1 base64value,key2 base64value"
+func parseUploadMetadata(header string) map[string]string {
+	meta := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), " ", 2)
+		if kv[0] == "" {
+			continue
+		}
+		if len(kv) == 1 {
+			meta[kv[0]] = ""
+			continue
+		}
+		v, err := base64.StdEncoding.DecodeString(kv[1])
+		if err != nil {
+			continue
+		}
+		meta[kv[0]] = string(v)
+	}
+	return meta
+}
+
+func checkTusResumable(c *gin.Context) bool {
+	c.Header("Tus-Resumable", tusVersion)
+	if c.GetHeader("Tus-Resumable") != tusVersion {
+		c.Header("Tus-Version", tusVersion)
+		response.HTTPError(c, http.StatusPreconditionFailed, "unsupported tus version", response.InvalidRequest)
+		return false
+	}
+	return true
+}
+
+func setUploadHeaders(c *gin.Context, info *uploadInfo, offset int64) {
+	c.Header("Upload-Offset", strconv.FormatInt(offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(info.Length, 10))
+	c.Header("Upload-Expires", info.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.Header("Cache-Control", "no-store")
+}
+
+// 将请求体追加到暂存文件，返回新的偏移量
+func appendUpload(c *gin.Context, info *uploadInfo, offset int64) (int64, error) {
+	f, err := fs.FileSystem.OpenFile(c.Request.Context(), uploadDataPath(info.UserID, info.ID), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return offset, err
+	}
+	n, err := io.CopyN(f, c.Request.Body, info.Length-offset)
+	if cerr := f.Close(); err == nil || errors.Is(err, io.EOF) {
+		err = cerr
+	}
+	return offset + n, err
+}
+
+// 上传完成后将暂存文件原子地移动到目标位置
+func finishUpload(ctx context.Context, info *uploadInfo) error {
+	errClobber := fmt.Errorf("%s already exists and can't be overwritten in an append-only space", info.Path)
+	if fi, err := fs.FileSystem.Stat(ctx, info.RealPath); err == nil {
+		if fi.IsDir() {
+			return fmt.Errorf("%s is a directory", info.Path)
+		}
+		if info.NoClobber {
+			return errClobber
+		}
+	}
+	snapshot := snapshotUsage(ctx, info.RealPath)
+	if err := checkQuota(ctx, info.RealPath, info.Length-snapshot.bytes); err != nil {
+		return err
+	}
+	// 暂存目录可能和目标不在同一个挂载点，先移动到目标所在的目录，再原子地替换目标
+	tmp := path.Join(path.Dir(info.RealPath), ".upload-"+info.ID)
+	if err := renameOrCopy(ctx, uploadDataPath(info.UserID, info.ID), tmp, nil); err != nil {
+		return err
+	}
+	var err error
+	if info.NoClobber {
+		// 与 appendOnlyFS 的 O_EXCL 一致：硬链接在目标已存在时失败，
+		// 不会像 Rename 那样替换检查之后才被创建的文件
+		if err = os.Link(osPath(tmp), osPath(info.RealPath)); err == nil {
+			if rerr := os.Remove(osPath(tmp)); rerr != nil {
+				logutils.Log.Warnf("remove %s: %v", tmp, rerr)
+			}
+		}
+	} else {
+		err = fs.FileSystem.Rename(ctx, tmp, info.RealPath)
+	}
+	if err != nil {
+		removeCopied(tmp)
+		if errors.Is(err, os.ErrExist) {
+			return errClobber
+		}
+		return err
+	}
+	snapshot.commit(ctx)
+	if err = os.Chmod(osPath(info.RealPath), model.RWXFolderPerm); err != nil {
+		logutils.Log.Warnf("chmod %s: %v", info.RealPath, err)
+	}
+	return removeUpload(ctx, info.UserID, info.ID)
+}
+
+// 创建上传后用户可能被降级、账户可能过期，每次写入前重新检查权限并解析实际路径。
+// 不能修改已有文件时完成上传不会覆盖目标
+func recheckUpload(c *gin.Context, token util.JWTMessage, info *uploadInfo) bool {
+	permission := GetPermission(info.Path, token, c)
+	if !permission.CanCreate() {
+		permissionDenied(c, info.Path, token, "You have no permission to upload files to this location", response.NotSpecified)
+		return false
+	}
+	realPath, err := Redirect(c, info.Path, token)
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return false
+	}
+	info.RealPath = realPath
+	info.NoClobber = !permission.CanModify()
+	return true
+}
+
+// 创建上传，*path 为目标目录，文件名由 Upload-Metadata 中的 filename 指定
+func CreateUpload(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	if !checkTusResumable(c) {
+		return
+	}
+	if c.GetHeader("Upload-Defer-Length") != "" {
+		response.BadRequestError(c, "Upload-Defer-Length is not supported")
+		return
+	}
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		response.BadRequestError(c, "invalid Upload-Length")
+		return
+	}
+	if maxSize := config.GetConfig().Upload.MaxSize; maxSize > 0 && length > maxSize {
+		response.HTTPError(c, http.StatusRequestEntityTooLarge, "upload exceeds the maximum size", response.InvalidRequest)
+		return
+	}
+	metadata := c.GetHeader("Upload-Metadata")
+	filename := parseUploadMetadata(metadata)["filename"]
+	if filename == "" || filename == "." || filename == ".." || strings.ContainsAny(filename, "/\\") {
+		response.BadRequestError(c, "invalid filename in Upload-Metadata")
+		return
+	}
+	dir := strings.Trim(strings.TrimPrefix(c.Request.URL.Path, uploadRoute), "/")
+	target := dir + "/" + filename
+	permission := GetPermission(target, jwttoken, c)
+	if !permission.CanCreate() {
+		permissionDenied(c, target, jwttoken, "You have no permission to upload files to this location", response.NotSpecified)
+		return
+	}
+	realPath, err := Redirect(c, target, jwttoken)
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	ctx := c.Request.Context()
+	if fi, ferr := fs.FileSystem.Stat(ctx, filepath.Dir(realPath)); ferr != nil || !fi.IsDir() {
+		response.HTTPError(c, http.StatusNotFound, "target directory does not exist", response.NotSpecified)
+		return
+	}
+	var existing int64
+	if fi, ferr := fs.FileSystem.Stat(ctx, realPath); ferr == nil {
+		if fi.IsDir() {
+			response.HTTPError(c, http.StatusConflict, "target is a directory", response.NotSpecified)
+			return
+		}
+		if !permission.CanModify() {
+			response.HTTPError(c, http.StatusForbidden, "You can't overwrite existing files in an append-only space", response.NotSpecified)
+			return
+		}
+		existing = fi.Size()
+	}
+	if err = checkQuota(ctx, realPath, length-existing); err != nil {
+		quotaError(c, err)
+		return
+	}
+
+	id, err := newRandomID(uploadIDBytes)
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	info := &uploadInfo{
+		ID:        id,
+		UserID:    jwttoken.UserID,
+		Path:      target,
+		RealPath:  realPath,
+		Length:    length,
+		Metadata:  metadata,
+		ExpiresAt: time.Now().Add(uploadExpiry()),
+		NoClobber: !permission.CanModify(),
+	}
+	if err = fs.FileSystem.Mkdir(ctx, uploadDir(info.UserID), model.DefaultFolderPerm); err != nil && !os.IsExist(err) {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	f, err := fs.FileSystem.OpenFile(ctx, uploadDataPath(info.UserID, id), os.O_WRONLY|os.O_CREATE|os.O_EXCL, model.DefaultFilePerm)
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	f.Close()
+	if err = writeUploadInfo(ctx, info); err != nil {
+		_ = removeUpload(ctx, info.UserID, id)
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+
+	var offset int64
+	if c.ContentType() == tusOffsetType {
+		offset, err = appendUpload(c, info, 0)
+		if err != nil {
+			logutils.Log.Warnf("upload %s: %v", id, err)
+		}
+	}
+	if offset == length {
+		if err = finishUpload(ctx, info); err != nil {
+			quotaError(c, err)
+			return
+		}
+	}
+	c.Header("Location", uploadRoute+"/"+id)
+	setUploadHeaders(c, info, offset)
+	c.Status(http.StatusCreated)
+}
+
+// 查询上传偏移量
+func HeadUpload(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	if !checkTusResumable(c) {
+		return
+	}
+	info, err := readUploadInfo(c.Request.Context(), jwttoken.UserID, c.Param("id"))
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	offset, err := uploadOffset(c.Request.Context(), info)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if info.Metadata != "" {
+		c.Header("Upload-Metadata", info.Metadata)
+	}
+	setUploadHeaders(c, info, offset)
+	c.Status(http.StatusOK)
+}
+
+// 追加上传数据，数据传完后移动到目标位置
+func PatchUpload(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	if !checkTusResumable(c) {
+		return
+	}
+	if c.ContentType() != tusOffsetType {
+		response.HTTPError(c, http.StatusUnsupportedMediaType, "Content-Type must be "+tusOffsetType, response.InvalidRequest)
+		return
+	}
+	id := c.Param("id")
+	unlock, ok := lockUpload(id)
+	if !ok {
+		response.HTTPError(c, http.StatusLocked, "upload is in use by another request", response.NotSpecified)
+		return
+	}
+	defer unlock()
+
+	ctx := c.Request.Context()
+	info, err := readUploadInfo(ctx, jwttoken.UserID, id)
+	if err != nil {
+		response.HTTPError(c, http.StatusNotFound, "upload does not exist", response.NotSpecified)
+		return
+	}
+	if time.Now().After(info.ExpiresAt) {
+		_ = removeUpload(ctx, info.UserID, info.ID)
+		response.HTTPError(c, http.StatusGone, "upload has expired", response.NotSpecified)
+		return
+	}
+	if !recheckUpload(c, jwttoken, info) {
+		return
+	}
+	offset, err := uploadOffset(ctx, info)
+	if err != nil {
+		response.HTTPError(c, http.StatusNotFound, "upload does not exist", response.NotSpecified)
+		return
+	}
+	reqOffset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || reqOffset != offset {
+		response.HTTPError(c, http.StatusConflict, "Upload-Offset does not match", response.InvalidRequest)
+		return
+	}
+
+	offset, err = appendUpload(c, info, offset)
+	if err != nil {
+		logutils.Log.Warnf("upload %s: %v", id, err)
+	}
+	info.ExpiresAt = time.Now().Add(uploadExpiry())
+	if offset == info.Length {
+		if err = finishUpload(ctx, info); err != nil {
+			quotaError(c, err)
+			return
+		}
+	} else if err = writeUploadInfo(ctx, info); err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	setUploadHeaders(c, info, offset)
+	c.Status(http.StatusNoContent)
+}
+
+// 终止上传并删除暂存文件
+func DeleteUpload(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	if !checkTusResumable(c) {
+		return
+	}
+	id := c.Param("id")
+	unlock, ok := lockUpload(id)
+	if !ok {
+		response.HTTPError(c, http.StatusLocked, "upload is in use by another request", response.NotSpecified)
+		return
+	}
+	defer unlock()
+	info, err := readUploadInfo(c.Request.Context(), jwttoken.UserID, id)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if err = removeUpload(c.Request.Context(), info.UserID, info.ID); err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// 预检请求，对 uploads 路径同时返回 tus 协议的能力声明
+func Options(c *gin.Context) {
+	if !strings.HasPrefix(c.Request.URL.Path, uploadRoute) {
+		return
+	}
+	c.Header("Tus-Resumable", tusVersion)
+	c.Header("Tus-Version", tusVersion)
+	c.Header("Tus-Extension", tusExtensions)
+	if maxSize := config.GetConfig().Upload.MaxSize; maxSize > 0 {
+		c.Header("Tus-Max-Size", strconv.FormatInt(maxSize, 10))
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// 清理过期的上传，没有 .info 的残留数据按修改时间判断
+func cleanExpiredUploads() {
+	ctx := context.Background()
+	root, err := fs.FileSystem.OpenFile(ctx, model.UploadPrefix, os.O_RDONLY, 0)
+	if err != nil {
+		return
+	}
+	users, err := root.Readdir(-1)
+	root.Close()
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	for _, u := range users {
+		if !u.IsDir() {
+			continue
+		}
+		dir := model.UploadPrefix + "/" + u.Name()
+		f, err := fs.FileSystem.OpenFile(ctx, dir, os.O_RDONLY, 0)
+		if err != nil {
+			continue
+		}
+		entries, err := f.Readdir(-1)
+		f.Close()
+		if err != nil {
+			continue
+		}
+		infos := make(map[string]bool)
+		for _, e := range entries {
+			if id, ok := strings.CutSuffix(e.Name(), ".info"); ok {
+				infos[id] = true
+			}
+		}
+		for _, e := range entries {
+			name := e.Name()
+			if id, ok := strings.CutSuffix(name, ".info"); ok {
+				var info uploadInfo
+				data, rerr := os.ReadFile(osPath(dir + "/" + name))
+				if rerr == nil && json.Unmarshal(data, &info) == nil && now.Before(info.ExpiresAt) {
+					continue
+				}
+				unlock, locked := lockUpload(id)
+				if !locked {
+					continue
+				}
+				logutils.Log.Infof("remove expired upload %s", dir+"/"+id)
+				_ = fs.FileSystem.RemoveAll(ctx, dir+"/"+id)
+				_ = fs.FileSystem.RemoveAll(ctx, dir+"/"+name)
+				unlock()
+				uploadLocks.Delete(id)
+			} else if !infos[name] && now.Sub(e.ModTime()) > uploadExpiry() {
+				_ = fs.FileSystem.RemoveAll(ctx, dir+"/"+name)
+			}
+		}
+	}
+}
+
+func StartCleanUploads() {
+	checkfs()
+	for {
+		cleanExpiredUploads()
+		time.Sleep(cleanUploadInterval)
+	}
+}
+
+func RegisterUpload(webdavGroup *gin.RouterGroup) {
+	webdavGroup.POST("/uploads/*path", CreateUpload)
+	webdavGroup.HEAD("/uploads/:id", HeadUpload)
+	webdavGroup.PATCH("/uploads/:id", PatchUpload)
+	webdavGroup.DELETE("/uploads/:id", DeleteUpload)
+}
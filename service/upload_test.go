@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFinishUpload(t *testing.T) {
+	tests := []struct {
+		name      string
+		noClobber bool
+		existing  bool
+		err       string // 错误应包含的内容，为空表示成功
+	}{
+		{"new file", false, false, ""},
+		{"overwrite", false, true, ""},
+		{"append-only new file", true, false, ""},
+		{"append-only existing file", true, true, "already exists"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := useTempFS(t)
+			info := &uploadInfo{ID: strings.Repeat("ab", uploadIDBytes), UserID: 1, Path: "data/a.txt", RealPath: "/data/a.txt", Length: 3, NoClobber: tt.noClobber}
+			for _, d := range []string{"data", uploadDir(info.UserID)} {
+				if err := os.MkdirAll(filepath.Join(dir, d), 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := os.WriteFile(osPath(uploadDataPath(info.UserID, info.ID)), []byte("new"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if tt.existing {
+				if err := os.WriteFile(filepath.Join(dir, "data/a.txt"), []byte("old"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := finishUpload(context.Background(), info)
+			want := "new"
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want %q", err, tt.err)
+				}
+				want = "old"
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if data, _ := os.ReadFile(filepath.Join(dir, "data/a.txt")); string(data) != want {
+				t.Errorf("target = %q, want %q", data, want)
+			}
+			// 不留下中转的临时文件
+			if matches, _ := filepath.Glob(filepath.Join(dir, "data/.upload-*")); len(matches) > 0 {
+				t.Errorf("temporary files left: %v", matches)
+			}
+		})
+	}
+}
@@ -0,0 +1,477 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"webdav/config"
+	"webdav/dao/model"
+	"webdav/dao/query"
+	"webdav/logutils"
+	"webdav/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 快照清单每批写入数据库的条目数
+const versionManifestBatch = 500
+
+type VersionRequest struct {
+	ID      uint `uri:"id" binding:"required"`
+	Version uint `uri:"version" binding:"required"`
+}
+
+type CreateVersionReq struct {
+	Describe string `json:"describe"`
+}
+
+type VersionResp struct {
+	ID        uint                `json:"id"`
+	DatasetID uint                `json:"datasetID"`
+	Version   uint                `json:"version"`
+	Describe  string              `json:"describe"`
+	Status    model.VersionStatus `json:"status"`
+	Message   string              `json:"message,omitempty"`
+	Files     int64               `json:"files"`
+	Bytes     int64               `json:"bytes"`
+	UserID    uint                `json:"userID"`
+	CreatedAt time.Time           `json:"createdAt"`
+}
+
+type ChecksumResp struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+func toVersionResp(v *model.DatasetVersion) VersionResp {
+	return VersionResp{
+		ID:        v.ID,
+		DatasetID: v.DatasetID,
+		Version:   v.Version,
+		Describe:  v.Describe,
+		Status:    v.Status,
+		Message:   v.Message,
+		Files:     v.Files,
+		Bytes:     v.Bytes,
+		UserID:    v.UserID,
+		CreatedAt: v.CreatedAt,
+	}
+}
+
+func getDatasetVersion(ctx context.Context, datasetID, version uint) (*model.DatasetVersion, error) {
+	dv := query.DatasetVersion
+	v, err := dv.WithContext(ctx).Where(dv.DatasetID.Eq(datasetID), dv.Version.Eq(version)).First()
+	if err != nil {
+		return nil, fmt.Errorf("version %d of dataset %d does not exist", version, datasetID)
+	}
+	return v, nil
+}
+
+// 数据集的根目录，带 version 参数时为对应快照的目录
+func datasetRoot(c *gin.Context, datasetID uint) (string, error) {
+	URL, err := GetDatasetURLByID(c, datasetID)
+	if err != nil {
+		return "", err
+	}
+	param := c.Query("version")
+	if param == "" {
+		return URL, nil
+	}
+	n, err := strconv.ParseUint(param, 10, 32)
+	if err != nil {
+		return "", fmt.Errorf("invalid version %q", param)
+	}
+	v, err := getDatasetVersion(c, datasetID, uint(n))
+	if err != nil {
+		return "", err
+	}
+	if v.Status != model.VersionReady {
+		return "", fmt.Errorf("version %d is %s", v.Version, v.Status)
+	}
+	return v.Path, nil
+}
+
+// 将 src 的文件树复制到快照目录，同时写入文件清单。
+// 只复制目录和普通文件，符号链接可能指向数据集之外，不纳入快照。
+func snapshotTree(ctx context.Context, v *model.DatasetVersion, src string, r *runningJob) (files, bytes int64, err error) {
+	fi, err := fs.FileSystem.Stat(ctx, src)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err = os.MkdirAll(osPath(path.Dir(v.Path)), model.RWXFolderPerm); err != nil {
+		return 0, 0, err
+	}
+	hardlink := config.GetConfig().DatasetVersion.Hardlink
+	dvf := query.DatasetVersionFile
+	var batch []*model.DatasetVersionFile
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		ferr := dvf.WithContext(ctx).CreateInBatches(batch, versionManifestBatch)
+		batch = batch[:0]
+		return ferr
+	}
+	err = walkFS(ctx, src, fi, func(p string, fi os.FileInfo) error {
+		rel := strings.TrimPrefix(strings.TrimPrefix(p, src), "/")
+		dst := path.Join(v.Path, rel)
+		if fi.IsDir() {
+			return fs.FileSystem.Mkdir(ctx, dst, model.RWXFolderPerm)
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		var sum string
+		var cerr error
+		if hardlink {
+			if cerr = os.Link(osPath(p), osPath(dst)); cerr == nil {
+				sum, cerr = hashFile(ctx, dst)
+			}
+		} else {
+			sum, cerr = copyFile(ctx, p, dst, fi)
+		}
+		if cerr != nil {
+			return cerr
+		}
+		if rel == "" {
+			rel = fi.Name()
+		}
+		files++
+		bytes += fi.Size()
+		r.doneFiles.Add(1)
+		r.doneBytes.Add(fi.Size())
+		batch = append(batch, &model.DatasetVersionFile{VersionID: v.ID, Path: rel, Size: fi.Size(), SHA256: sum})
+		if len(batch) >= versionManifestBatch {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	return files, bytes, err
+}
+
+// 去掉快照中所有的写权限。硬链接与原文件共享权限位，因此硬链接模式下只处理目录
+func freezeTree(ctx context.Context, root string) error {
+	fi, err := fs.FileSystem.Stat(ctx, root)
+	if err != nil {
+		return err
+	}
+	hardlink := config.GetConfig().DatasetVersion.Hardlink
+	return walkFS(ctx, root, fi, func(p string, fi os.FileInfo) error {
+		if fi.IsDir() {
+			return os.Chmod(osPath(p), fi.Mode().Perm()&^0222)
+		}
+		if hardlink || !fi.Mode().IsRegular() {
+			return nil
+		}
+		return os.Chmod(osPath(p), fi.Mode().Perm()&^0222)
+	})
+}
+
+// 数据集所有快照所在的目录
+func datasetVersionDir(datasetID uint) string {
+	return fmt.Sprintf("%s/%d", model.DatasetVersionPrefix, datasetID)
+}
+
+// 删除快照目录
+func removeVersionTree(ctx context.Context, v *model.DatasetVersion) error {
+	return removeSnapshotDir(ctx, path.Dir(v.Path))
+}
+
+// 删除快照所在的目录，先恢复目录的写权限，否则无法删除其中的文件
+func removeSnapshotDir(ctx context.Context, dir string) error {
+	if fi, err := fs.FileSystem.Stat(ctx, dir); err == nil {
+		if err = walkFS(ctx, dir, fi, func(p string, fi os.FileInfo) error {
+			if fi.IsDir() {
+				return os.Chmod(osPath(p), model.RWXFolderPerm)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return fs.FileSystem.RemoveAll(ctx, dir)
+}
+
+// 删除快照的文件和清单
+func clearVersion(ctx context.Context, v *model.DatasetVersion) error {
+	if err := removeVersionTree(ctx, v); err != nil {
+		return err
+	}
+	dvf := query.DatasetVersionFile
+	_, err := dvf.WithContext(ctx).Where(dvf.VersionID.Eq(v.ID)).Delete()
+	return err
+}
+
+// 生成中的快照标记为失败并清理残留的文件
+func failVersion(ctx context.Context, versionID uint, message string) {
+	dv := query.DatasetVersion
+	v, err := dv.WithContext(ctx).Where(dv.ID.Eq(versionID), dv.Status.Eq(string(model.VersionCreating))).First()
+	if err != nil {
+		return
+	}
+	if err = clearVersion(ctx, v); err != nil {
+		logutils.Log.Errorf("remove snapshot %s: %v", v.Path, err)
+	}
+	if _, err = dv.WithContext(ctx).Where(dv.ID.Eq(v.ID), dv.Status.Eq(string(model.VersionCreating))).
+		UpdateSimple(dv.Status.Value(string(model.VersionFailed)), dv.Message.Value(message)); err != nil {
+		logutils.Log.Errorf("update version %d: %v", v.ID, err)
+	}
+}
+
+// 在任务中生成快照，由任务框架保证同一时间只有一个实例执行，并在实例退出后重置。
+// 失败时清理已生成的部分，任务不再重试时由 abandonJob 将快照标记为失败
+func runVersionJob(ctx context.Context, r *runningJob) error {
+	params := r.job.Params.Data()
+	dv := query.DatasetVersion
+	v, err := dv.WithContext(ctx).Where(dv.ID.Eq(params.VersionID)).First()
+	if err != nil {
+		return err
+	}
+	if v.Status != model.VersionCreating {
+		return fmt.Errorf("version %d of dataset %d is %s", v.Version, v.DatasetID, v.Status)
+	}
+	// 重试时清理上次留下的文件和清单
+	if err = clearVersion(ctx, v); err != nil {
+		return err
+	}
+	src := cleanRealPath(params.RealSrc)
+	if bytes, files, uerr := treeUsage(ctx, src); uerr == nil {
+		r.setTotal(bytes, files)
+	}
+	files, bytes, err := snapshotTree(ctx, v, src, r)
+	if err == nil {
+		err = freezeTree(ctx, v.Path)
+	}
+	if err != nil {
+		if cerr := clearVersion(context.WithoutCancel(ctx), v); cerr != nil {
+			logutils.Log.Errorf("remove snapshot %s: %v", v.Path, cerr)
+		}
+		return err
+	}
+	_, err = dv.WithContext(context.WithoutCancel(ctx)).Where(dv.ID.Eq(v.ID), dv.Status.Eq(string(model.VersionCreating))).
+		UpdateSimple(dv.Status.Value(string(model.VersionReady)), dv.Files.Value(files), dv.Bytes.Value(bytes))
+	return err
+}
+
+// 为数据集创建只读快照，快照在后台任务中生成，可通过查询版本或 /jobs 获取进度
+func CreateDatasetVersion(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	var datasetReq DatasetRequest
+	if err = c.ShouldBindUri(&datasetReq); err != nil {
+		response.HTTPError(c, http.StatusBadRequest, err.Error(), response.NotSpecified)
+		return
+	}
+	var req CreateVersionReq
+	if c.Request.ContentLength != 0 {
+		if err = c.ShouldBindJSON(&req); err != nil {
+			response.BadRequestError(c, err.Error())
+			return
+		}
+	}
+	if GetDatasetPermission(c, datasetReq.ID, jwttoken) != model.ReadWrite {
+		response.HTTPError(c, http.StatusUnauthorized, "Only the owner or admin can create dataset versions", response.InvalidRole)
+		return
+	}
+	URL, err := GetDatasetURLByID(c, datasetReq.ID)
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	if _, err = fs.FileSystem.Stat(c, URL); err != nil {
+		response.Error(c, "The dataset's URL does not exist. ", response.NotSpecified)
+		return
+	}
+	version := &model.DatasetVersion{
+		DatasetID: datasetReq.ID,
+		Describe:  req.Describe,
+		Status:    model.VersionCreating,
+		UserID:    jwttoken.UserID,
+	}
+	err = query.Q.Transaction(func(tx *query.Query) error {
+		dv := tx.DatasetVersion
+		// 已删除的版本号不再复用
+		last, lerr := dv.WithContext(c).Unscoped().Where(dv.DatasetID.Eq(datasetReq.ID)).Order(dv.Version.Desc()).First()
+		if lerr != nil && !errors.Is(lerr, gorm.ErrRecordNotFound) {
+			return lerr
+		}
+		version.Version = 1
+		if last != nil {
+			version.Version = last.Version + 1
+		}
+		version.Path = fmt.Sprintf("%s/%d/%s", datasetVersionDir(datasetReq.ID), version.Version, path.Base(cleanRealPath(URL)))
+		return dv.WithContext(c).Create(version)
+	})
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	if _, err = submitJob(c, jwttoken.UserID, model.FileJobVersion, model.FileJobParams{
+		RealSrc:   URL,
+		DatasetID: datasetReq.ID,
+		VersionID: version.ID,
+	}, 0, 0); err != nil {
+		failVersion(c, version.ID, err.Error())
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	response.Success(c, toVersionResp(version))
+}
+
+// 列出数据集的所有版本
+func ListDatasetVersions(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	var datasetReq DatasetRequest
+	if err = c.ShouldBindUri(&datasetReq); err != nil {
+		response.HTTPError(c, http.StatusBadRequest, err.Error(), response.NotSpecified)
+		return
+	}
+	if GetDatasetPermission(c, datasetReq.ID, jwttoken) == model.NotAllowed {
+		response.Error(c, "This dataset does not exist or you do not have permission", response.NotSpecified)
+		return
+	}
+	dv := query.DatasetVersion
+	versions, err := dv.WithContext(c).Where(dv.DatasetID.Eq(datasetReq.ID)).Order(dv.Version.Desc()).Find()
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	data := make([]VersionResp, 0, len(versions))
+	for _, v := range versions {
+		data = append(data, toVersionResp(v))
+	}
+	response.Success(c, data)
+}
+
+// 查询数据集的某个版本
+func GetDatasetVersion(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	var req VersionRequest
+	if err = c.ShouldBindUri(&req); err != nil {
+		response.HTTPError(c, http.StatusBadRequest, err.Error(), response.NotSpecified)
+		return
+	}
+	if GetDatasetPermission(c, req.ID, jwttoken) == model.NotAllowed {
+		response.Error(c, "This dataset does not exist or you do not have permission", response.NotSpecified)
+		return
+	}
+	v, err := getDatasetVersion(c, req.ID, req.Version)
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	response.Success(c, toVersionResp(v))
+}
+
+// 获取快照的文件清单
+func GetDatasetVersionManifest(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	var req VersionRequest
+	if err = c.ShouldBindUri(&req); err != nil {
+		response.HTTPError(c, http.StatusBadRequest, err.Error(), response.NotSpecified)
+		return
+	}
+	if GetDatasetPermission(c, req.ID, jwttoken) == model.NotAllowed {
+		response.Error(c, "This dataset does not exist or you do not have permission", response.NotSpecified)
+		return
+	}
+	v, err := getDatasetVersion(c, req.ID, req.Version)
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	if v.Status != model.VersionReady {
+		response.HTTPError(c, http.StatusConflict, fmt.Sprintf("version %d is %s", v.Version, v.Status), response.NotSpecified)
+		return
+	}
+	dvf := query.DatasetVersionFile
+	entries, err := dvf.WithContext(c).Where(dvf.VersionID.Eq(v.ID)).Order(dvf.Path).Find()
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	data := make([]ChecksumResp, 0, len(entries))
+	for _, e := range entries {
+		data = append(data, ChecksumResp{Path: e.Path, Size: e.Size, SHA256: e.SHA256})
+	}
+	response.Success(c, data)
+}
+
+// 删除数据集的某个版本，只有创建者和管理员可以删除
+func DeleteDatasetVersion(c *gin.Context) {
+	jwttoken, err := CheckJWTToken(c)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	var req VersionRequest
+	if err = c.ShouldBindUri(&req); err != nil {
+		response.HTTPError(c, http.StatusBadRequest, err.Error(), response.NotSpecified)
+		return
+	}
+	if GetDatasetPermission(c, req.ID, jwttoken) != model.ReadWrite {
+		response.HTTPError(c, http.StatusUnauthorized, "Only the owner or admin can delete dataset versions", response.InvalidRole)
+		return
+	}
+	v, err := getDatasetVersion(c, req.ID, req.Version)
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	if v.Status == model.VersionCreating {
+		response.HTTPError(c, http.StatusConflict, fmt.Sprintf("version %d is still being created", v.Version), response.NotSpecified)
+		return
+	}
+	if err = removeVersionTree(c.Request.Context(), v); err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	err = query.Q.Transaction(func(tx *query.Query) error {
+		dvf := tx.DatasetVersionFile
+		if _, derr := dvf.WithContext(c).Where(dvf.VersionID.Eq(v.ID)).Delete(); derr != nil {
+			return derr
+		}
+		dv := tx.DatasetVersion
+		_, derr := dv.WithContext(c).Where(dv.ID.Eq(v.ID)).Delete()
+		return derr
+	})
+	if err != nil {
+		response.Error(c, err.Error(), response.NotSpecified)
+		return
+	}
+	response.Success(c, "delete dataset version successfully")
+}
+
+func RegisterVersion(webdavGroup *gin.RouterGroup) {
+	webdavGroup.POST("/datasets/:id/versions", CreateDatasetVersion)
+	webdavGroup.GET("/datasets/:id/versions", ListDatasetVersions)
+	webdavGroup.GET("/datasets/:id/versions/:version", GetDatasetVersion)
+	webdavGroup.GET("/datasets/:id/versions/:version/manifest", GetDatasetVersionManifest)
+	webdavGroup.DELETE("/datasets/:id/versions/:version", DeleteDatasetVersion)
+}
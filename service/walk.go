@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// 每次从目录中读取的条目数，避免一次性读入超大目录
+const walkBatchSize = 1024
+
+type walkFunc func(name string, fi os.FileInfo) error
+
+// 遍历 fs 中以 name 为根的文件树，fn 对目录返回 filepath.SkipDir 时跳过该目录。
+// 目录条目分批读取，不会整体加载进内存；符号链接不会被跟随。
+func walkFS(ctx context.Context, name string, fi os.FileInfo, fn walkFunc) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := fn(name, fi); err != nil {
+		if fi.IsDir() && errors.Is(err, filepath.SkipDir) {
+			return nil
+		}
+		return err
+	}
+	if !fi.IsDir() {
+		return nil
+	}
+	f, err := fs.FileSystem.OpenFile(ctx, name, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	for {
+		children, err := f.Readdir(walkBatchSize)
+		for _, child := range children {
+			if werr := walkFS(ctx, path.Join(name, child.Name()), child, fn); werr != nil {
+				return werr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(children) == 0 {
+			return nil
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package util
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"sync"
 	"time"
 	"webdav/config"
@@ -29,6 +32,16 @@ func NewTokenConf() *TokenConf {
 	}
 }
 
+// 令牌类型，两种令牌使用不同的密钥签名，且不能互相替代
+type TokenType string
+
+const (
+	AccessToken  TokenType = "access"
+	RefreshToken TokenType = "refresh"
+)
+
+var ErrTokenType = errors.New("wrong token type")
+
 type (
 	JWTClaims struct {
 		UserID           uint             `json:"ui"`
@@ -39,6 +52,7 @@ type (
 		RolePlatform     model.Role       `json:"rp"`
 		AccessMode       model.AccessMode `json:"am"`
 		PublicAccessMode model.AccessMode `json:"pa"`
+		TokenType        TokenType        `json:"tt,omitempty"`
 		jwt.RegisteredClaims
 	}
 	JWTMessage struct {
@@ -50,6 +64,16 @@ type (
 		AccountAccessMode model.AccessMode `json:"accessMode"`       // AccessMode in queue
 		PublicAccessMode  model.AccessMode `json:"publicaccessmode"` // Public Accessmode
 		RolePlatform      model.Role       `json:"rolePlatform"`     // Role in platform (e.g. guest, user, admin)
+		Scope             *KeyScope        `json:"-"`                // Set when authenticated by a personal access token
+		TokenID           string           `json:"-"`                // jti, empty for tokens issued before revocation existed
+		IssuedAt          time.Time        `json:"-"`                // Zero for tokens issued before revocation existed
+		ExpiresAt         time.Time        `json:"-"`
+	}
+	// KeyScope limits what a personal access token can reach
+	KeyScope struct {
+		ReadOnly   bool
+		Roots      []string // Virtual paths such as "user" or "public/data", empty means no limit unless DatasetIDs is set
+		DatasetIDs []uint
 	}
 )
 
@@ -60,9 +84,10 @@ const (
 )
 
 type TokenManager struct {
-	secretKey       string
-	accessTokenTTL  int
-	refreshTokenTTL int
+	secretKey        string
+	refreshSecretKey string
+	accessTokenTTL   int
+	refreshTokenTTL  int
 }
 
 var (
@@ -73,7 +98,14 @@ var (
 func GetTokenMgr() *TokenManager {
 	once.Do(func() {
 		tokenConfig := NewTokenConf()
+		refreshSecret := tokenConfig.RefreshTokenSecret
+		if refreshSecret == "" {
+			// 未配置时退回到访问令牌的密钥，两种令牌仍通过类型区分
+			logutils.Log.Warn("refreshTokenSecret is not set, refresh tokens are signed with accessTokenSecret")
+			refreshSecret = tokenConfig.AccessTokenSecret
+		}
 		tokenMgr = newTokenManager(tokenConfig.AccessTokenSecret,
+			refreshSecret,
 			tokenConfig.AccessTokenExpiryHour,
 			tokenConfig.RefreshTokenExpiryHour,
 		)
@@ -81,15 +113,42 @@ func GetTokenMgr() *TokenManager {
 	return tokenMgr
 }
 
-func newTokenManager(secretKey string, accessTokenTTL, refreshTokenTTL int) *TokenManager {
+func newTokenManager(secretKey, refreshSecretKey string, accessTokenTTL, refreshTokenTTL int) *TokenManager {
 	return &TokenManager{
 		secretKey,
+		refreshSecretKey,
 		accessTokenTTL,
 		refreshTokenTTL,
 	}
 }
-func (tm *TokenManager) createToken(msg *JWTMessage, ttl int) (string, error) {
-	expiresAt := time.Now().Add(time.Hour * time.Duration(ttl))
+
+func (tm *TokenManager) secretOf(tokenType TokenType) string {
+	if tokenType == RefreshToken {
+		return tm.refreshSecretKey
+	}
+	return tm.secretKey
+}
+
+// MaxTokenTTL is the lifetime of the longest-lived token, after which a revocation record is useless
+func (tm *TokenManager) MaxTokenTTL() time.Duration {
+	return time.Hour * time.Duration(max(tm.accessTokenTTL, tm.refreshTokenTTL))
+}
+
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (tm *TokenManager) createToken(msg *JWTMessage, ttl int, tokenType TokenType) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	expiresAt := now.Add(time.Hour * time.Duration(ttl))
 
 	claims := &JWTClaims{
 		UserID:           msg.UserID,
@@ -100,24 +159,27 @@ func (tm *TokenManager) createToken(msg *JWTMessage, ttl int) (string, error) {
 		RolePlatform:     msg.RolePlatform,
 		AccessMode:       msg.AccountAccessMode,
 		PublicAccessMode: msg.PublicAccessMode,
+		TokenType:        tokenType,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(tm.secretKey))
+	return token.SignedString([]byte(tm.secretOf(tokenType)))
 }
 
 // CreateTokens creates a new access token and a new refresh token
 func (tm *TokenManager) CreateTokens(msg *JWTMessage) (
 	accessToken string, refreshToken string, err error) {
-	accessToken, err = tm.createToken(msg, tm.accessTokenTTL)
+	accessToken, err = tm.createToken(msg, tm.accessTokenTTL, AccessToken)
 	if err != nil {
 		logutils.Log.Error(err)
 		return "", "", err
 	}
-	refreshToken, err = tm.createToken(msg, tm.refreshTokenTTL)
+	refreshToken, err = tm.createToken(msg, tm.refreshTokenTTL, RefreshToken)
 	if err != nil {
 		logutils.Log.Error(err)
 		return "", "", err
@@ -125,12 +187,51 @@ func (tm *TokenManager) CreateTokens(msg *JWTMessage) (
 	return accessToken, refreshToken, nil
 }
 
+// CreateAccessToken creates a new access token, used when refreshing
+func (tm *TokenManager) CreateAccessToken(msg *JWTMessage) (string, error) {
+	return tm.createToken(msg, tm.accessTokenTTL, AccessToken)
+}
+
+// CheckToken validates an access token. Tokens without a type claim were
+// issued before token types existed, when refresh tokens were signed with the
+// access secret too. They are accepted as access tokens only if they expire
+// within the access token TTL, so old refresh tokens can't be used instead.
 func (tm *TokenManager) CheckToken(requestToken string) (JWTMessage, error) {
+	claims, err := tm.parseToken(requestToken, AccessToken)
+	if err == nil {
+		switch claims.TokenType {
+		case AccessToken:
+		case "":
+			maxExpiry := time.Now().Add(time.Hour * time.Duration(tm.accessTokenTTL))
+			if claims.ExpiresAt == nil || claims.ExpiresAt.After(maxExpiry) {
+				err = ErrTokenType
+			}
+		default:
+			err = ErrTokenType
+		}
+	}
+	return claims.message(), err
+}
+
+// CheckRefreshToken validates a refresh token
+func (tm *TokenManager) CheckRefreshToken(requestToken string) (JWTMessage, error) {
+	claims, err := tm.parseToken(requestToken, RefreshToken)
+	if err == nil && claims.TokenType != RefreshToken {
+		err = ErrTokenType
+	}
+	return claims.message(), err
+}
+
+func (tm *TokenManager) parseToken(requestToken string, tokenType TokenType) (JWTClaims, error) {
 	claims := JWTClaims{}
 	_, err := jwt.ParseWithClaims(requestToken, &claims, func(_ *jwt.Token) (any, error) {
-		return []byte(tm.secretKey), nil
-	})
-	return JWTMessage{
+		return []byte(tm.secretOf(tokenType)), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	return claims, err
+}
+
+func (claims *JWTClaims) message() JWTMessage {
+	msg := JWTMessage{
 		UserID:            claims.UserID,
 		AccountID:         claims.QueueID,
 		Username:          claims.Username,
@@ -139,5 +240,13 @@ func (tm *TokenManager) CheckToken(requestToken string) (JWTMessage, error) {
 		RolePlatform:      claims.RolePlatform,
 		AccountAccessMode: claims.AccessMode,
 		PublicAccessMode:  claims.PublicAccessMode,
-	}, err
+		TokenID:           claims.ID,
+	}
+	if claims.IssuedAt != nil {
+		msg.IssuedAt = claims.IssuedAt.Time
+	}
+	if claims.ExpiresAt != nil {
+		msg.ExpiresAt = claims.ExpiresAt.Time
+	}
+	return msg
 }
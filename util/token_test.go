@@ -0,0 +1,136 @@
+package util
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"webdav/dao/model"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+)
+
+func TestTokenTypes(t *testing.T) {
+	tm := newTokenManager("access-secret", "refresh-secret", 1, 168)
+	msg := &JWTMessage{UserID: 7, Username: "alice", AccountID: 3, RolePlatform: model.RoleUser}
+	access, refresh, err := tm.CreateTokens(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 未带类型的旧令牌，有效期不超过访问令牌时按访问令牌处理。
+	// 旧的刷新令牌同样用访问令牌的密钥签名，只能通过有效期区分
+	legacyToken := func(ttl time.Duration, withExpiry bool) string {
+		claims := &JWTClaims{UserID: 7}
+		if withExpiry {
+			claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(ttl))
+		}
+		s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("access-secret"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	legacy := legacyToken(time.Hour, true)
+	legacyRefresh := legacyToken(168*time.Hour, true)
+	legacyNoExpiry := legacyToken(0, false)
+	expired, err := tm.createToken(msg, -1, AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 相同的密钥下只能靠类型区分
+	sameSecret := newTokenManager("secret", "secret", 1, 168)
+	sameAccess, sameRefresh, err := sameSecret.CreateTokens(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(tm *TokenManager, refresh bool) func(string) (JWTMessage, error) {
+		if refresh {
+			return tm.CheckRefreshToken
+		}
+		return tm.CheckToken
+	}
+	tests := []struct {
+		name    string
+		check   func(string) (JWTMessage, error)
+		token   string
+		wantErr error // 为空表示应当通过
+		invalid bool  // 只要求返回错误
+	}{
+		{"access as access", check(tm, false), access, nil, false},
+		{"refresh as refresh", check(tm, true), refresh, nil, false},
+		{"refresh as access", check(tm, false), refresh, nil, true},
+		{"access as refresh", check(tm, true), access, nil, true},
+		{"legacy as access", check(tm, false), legacy, nil, false},
+		{"legacy as refresh", check(tm, true), legacy, nil, true},
+		{"legacy refresh as access", check(tm, false), legacyRefresh, ErrTokenType, false},
+		{"legacy without expiry as access", check(tm, false), legacyNoExpiry, ErrTokenType, false},
+		{"expired", check(tm, false), expired, jwt.ErrTokenExpired, false},
+		{"garbage", check(tm, false), "not.a.token", nil, true},
+		{"same secret access", check(sameSecret, false), sameAccess, nil, false},
+		{"same secret refresh as access", check(sameSecret, false), sameRefresh, ErrTokenType, false},
+		{"same secret access as refresh", check(sameSecret, true), sameAccess, ErrTokenType, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.check(tt.token)
+			switch {
+			case tt.invalid:
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Fatalf("unexpected error %v", err)
+			default:
+				if got.UserID != 7 {
+					t.Errorf("UserID = %d, want 7", got.UserID)
+				}
+				if tt.token != legacy && (got.TokenID == "" || got.IssuedAt.IsZero() || got.ExpiresAt.IsZero()) {
+					t.Errorf("registered claims missing: %+v", got)
+				}
+			}
+		})
+	}
+}
+
+func TestRefreshKeepsIdentity(t *testing.T) {
+	tm := newTokenManager("access-secret", "refresh-secret", 1, 168)
+	msg := &JWTMessage{
+		UserID:            7,
+		AccountID:         3,
+		Username:          "alice",
+		AccountName:       "team",
+		RoleAccount:       model.RoleAdmin,
+		RolePlatform:      model.RoleUser,
+		AccountAccessMode: model.AccessModeRW,
+		PublicAccessMode:  model.AccessModeRO,
+	}
+	_, refresh, err := tm.CreateTokens(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := tm.CheckRefreshToken(refresh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	access, err := tm.CreateAccessToken(&parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := tm.CheckToken(access)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.TokenID == parsed.TokenID {
+		t.Error("refreshed access token reuses the refresh token ID")
+	}
+	if ttl := time.Until(got.ExpiresAt); ttl > time.Hour {
+		t.Errorf("access token lives %v, longer than its TTL", ttl)
+	}
+	got.TokenID, got.IssuedAt, got.ExpiresAt = "", time.Time{}, time.Time{}
+	if got != *msg {
+		t.Errorf("got %+v, want %+v", got, *msg)
+	}
+}